@@ -0,0 +1,119 @@
+// Сборка офлайн-фильтра утечек из списка SHA-1 паролей в формате Have I Been
+// Pwned (HASH:COUNT по строке, как его выгружает haveibeenpwned-downloader).
+//
+//	breachfilter -input pwnedpasswords.txt -output breached.bloom
+//	breachfilter -input pwnedpasswords.txt -output breached.bloom -min-count 10 -fp-rate 0.0001
+//	zcat pwnedpasswords.txt.gz | breachfilter -input - -expected 950000000 -output breached.bloom
+//
+// Файл сначала читается целиком, чтобы посчитать хеши и выбрать размер фильтра,
+// затем второй раз для заполнения; для stdin число хешей задается -expected.
+// Фильтр пишется во временный файл рядом с -output и подменяет его только
+// после успешной записи, поэтому обновлять можно на месте. Сервер читает
+// фильтр при старте (breach.filter_file).
+package main
+
+import (
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"LOIL-auth-server/internal/breach"
+)
+
+func main() {
+	input := flag.String("input", "", "HIBP SHA-1 hash list, - for stdin")
+	output := flag.String("output", "", "filter file to create or replace")
+	falsePositiveRate := flag.Float64("fp-rate", 0.001, "false positive rate of the filter")
+	minCount := flag.Int("min-count", 1, "skip hashes seen in breaches fewer times than this")
+	expected := flag.Uint64("expected", 0, "number of hashes to size the filter for (default: count the input first)")
+	flag.Parse()
+
+	if err := run(*input, *output, *falsePositiveRate, *minCount, *expected); err != nil {
+		fmt.Fprintln(os.Stderr, "breachfilter:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input, output string, falsePositiveRate float64, minCount int, expected uint64) error {
+	if input == "" || output == "" {
+		return fmt.Errorf("-input and -output are required")
+	}
+
+	var source io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		source = file
+
+		if expected == 0 {
+			if expected, err = countHashes(file, minCount); err != nil {
+				return fmt.Errorf("%s: %w", input, err)
+			}
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+	} else if expected == 0 {
+		return fmt.Errorf("-expected is required when reading from stdin")
+	}
+
+	filter, err := breach.NewFilter(expected, falsePositiveRate)
+	if err != nil {
+		return err
+	}
+	err = breach.ReadHashList(source, minCount, func(digest [sha1.Size]byte) error {
+		filter.AddHash(digest)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", input, err)
+	}
+
+	if err := writeFile(output, filter); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d hashes, %.1f MiB, expected false positive rate %.2g\n",
+		output, filter.Keys(), float64(filter.Size())/(1<<20), filter.FalsePositiveRate())
+	if filter.Keys() > expected {
+		fmt.Fprintf(os.Stderr, "breachfilter: warning: %d hashes added to a filter sized for %d, rebuild with a larger -expected\n", filter.Keys(), expected)
+	}
+	return nil
+}
+
+func countHashes(r io.Reader, minCount int) (uint64, error) {
+	var n uint64
+	err := breach.ReadHashList(r, minCount, func([sha1.Size]byte) error {
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// writeFile заменяет path атомарно: запущенный сервер или прерванная сборка
+// не увидят наполовину записанный фильтр
+func writeFile(path string, filter *breach.Filter) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := filter.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
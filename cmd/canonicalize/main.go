@@ -0,0 +1,89 @@
+// Пересчет канонических логинов, email и игровых фамилий, по которым ищутся
+// пользователи.
+//
+//	canonicalize -config config.yaml check   # найти аккаунты, совпадающие после приведения
+//	canonicalize -config config.yaml apply   # записать ключи по текущим правилам
+//
+// Сервер при старте сам дополняет строки без ключей; команда нужна после
+// изменения database.canonical или включения шифрования, а check - чтобы
+// заранее увидеть, какие аккаунты совпадут. Совпавший аккаунт остается без
+// ключа и находится только по точному логину, email или фамилии, пока одному из
+// пользователей не сменят его вручную. Отчет печатается в stdout в формате JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	batchSize := flag.Int("batch", 500, "rows to read per batch")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: canonicalize [-config path] [-batch n] check | apply")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*configPath, *batchSize, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "canonicalize:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, batchSize int, args []string) error {
+	if len(args) != 1 {
+		flag.Usage()
+		return fmt.Errorf("expected one command")
+	}
+
+	opts := database.CanonicalOptions{All: true, BatchSize: batchSize}
+	switch args[0] {
+	case "check":
+		opts.DryRun = true
+	case "apply":
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.RunMigrations(os.DirFS(filepath.Join("migrations", cfg.Database.Driver()))); err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+
+	report, canonicalErr := db.CanonicalizeUsers(ctx, opts)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if canonicalErr != nil {
+		return fmt.Errorf("stopped after %d rows: %w", report.Scanned, canonicalErr)
+	}
+	if len(report.Collisions) > 0 {
+		return fmt.Errorf("%d logins, emails or game surnames collide with other users after canonicalization, resolve them manually", len(report.Collisions))
+	}
+	return nil
+}
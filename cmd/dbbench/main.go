@@ -0,0 +1,182 @@
+// Нагрузочный замер хранилища SQLite: регистрация и вход параллельными воркерами
+// с настройками драйвера по умолчанию (before) и с настройками из конфигурации (after).
+//
+//	dbbench -workers 32 -duration 5s
+//	dbbench -config config.yaml -mode after
+//
+// Хеширование паролей не входит в замер: пароль сохраняется готовым хешем,
+// чтобы результат отражал только работу с базой.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+)
+
+// Хеш bcrypt для пароля "password", чтобы строки были реального размера
+const passwordHash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
+
+type scenario struct {
+	name string
+	// writeRatio - доля регистраций среди операций, остальное - вход
+	writeRatio float64
+}
+
+var scenarios = []scenario{
+	{"register", 1},
+	{"login", 0},
+	{"mixed 20% register", 0.2},
+}
+
+type result struct {
+	ops    int64
+	errors int64
+	busy   int64
+	// firstErr - первая неожиданная ошибка, для диагностики
+	firstErr error
+}
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file for the \"after\" settings")
+	migrationsDir := flag.String("migrations", "migrations/sqlite", "SQLite migrations directory")
+	mode := flag.String("mode", "both", "before, after or both")
+	workers := flag.Int("workers", 16, "concurrent workers")
+	duration := flag.Duration("duration", 3*time.Second, "duration of each scenario")
+	seed := flag.Int("seed", 1000, "users created before the login scenarios")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "dbbench:", err)
+		os.Exit(1)
+	}
+
+	settings := map[string]database.Options{
+		// Так хранилище открывалось до появления настроек SQLite
+		"before": {QueryTimeout: cfg.Database.QueryTimeout},
+		"after":  database.OptionsFrom(cfg.Database),
+	}
+
+	var modes []string
+	switch *mode {
+	case "both":
+		modes = []string{"before", "after"}
+	case "before", "after":
+		modes = []string{*mode}
+	default:
+		fmt.Fprintf(os.Stderr, "dbbench: unknown mode %q\n", *mode)
+		os.Exit(2)
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(out, "mode\tscenario\tops/s\terrors\tbusy\t")
+	for _, m := range modes {
+		for _, sc := range scenarios {
+			res, err := runScenario(settings[m], os.DirFS(*migrationsDir), sc, *workers, *duration, *seed)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "dbbench: %s/%s: %v\n", m, sc.name, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(out, "%s\t%s\t%.0f\t%d\t%d\t\n", m, sc.name, float64(res.ops)/duration.Seconds(), res.errors, res.busy)
+			if res.firstErr != nil {
+				defer fmt.Fprintf(os.Stderr, "%s/%s first error: %v\n", m, sc.name, res.firstErr)
+			}
+		}
+	}
+	out.Flush()
+}
+
+// runScenario замеряет один сценарий на свежей базе во временном каталоге
+func runScenario(opts database.Options, migrationFS fs.FS, sc scenario, workers int, duration time.Duration, seed int) (result, error) {
+	dir, err := os.MkdirTemp("", "dbbench")
+	if err != nil {
+		return result{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := database.NewSQLiteDB(filepath.Join(dir, "bench.db"), opts)
+	if err != nil {
+		return result{}, err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.RunMigrations(migrationFS); err != nil {
+		return result{}, err
+	}
+	if err := db.Prepare(ctx); err != nil {
+		return result{}, err
+	}
+
+	for i := 0; i < seed; i++ {
+		if err := db.CreateUser(ctx, newUser("seed", i)); err != nil {
+			return result{}, fmt.Errorf("seed user %d: %w", i, err)
+		}
+	}
+
+	var (
+		res      result
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+		deadline = time.Now().Add(duration)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for n := 0; time.Now().Before(deadline); n++ {
+				var err error
+				if rng.Float64() < sc.writeRatio {
+					err = db.CreateUser(ctx, newUser(fmt.Sprintf("w%d_", w), n))
+				} else {
+					_, err = db.GetUserByLogin(ctx, fmt.Sprintf("seed%d", rng.Intn(seed)))
+				}
+
+				if err == nil {
+					atomic.AddInt64(&res.ops, 1)
+					continue
+				}
+				atomic.AddInt64(&res.errors, 1)
+				if isBusy(err) {
+					atomic.AddInt64(&res.busy, 1)
+				}
+				errOnce.Do(func() { res.firstErr = err })
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	return res, nil
+}
+
+func newUser(prefix string, n int) *models.User {
+	return &models.User{
+		Login:       fmt.Sprintf("%s%d", prefix, n),
+		GameSurname: fmt.Sprintf("Surname%s%d", prefix, n),
+		Email:       fmt.Sprintf("%s%d@example.com", prefix, n),
+		Password:    passwordHash,
+		Locale:      "ru",
+	}
+}
+
+// isBusy распознает SQLITE_BUSY и SQLITE_LOCKED по тексту: код ошибки
+// оборачивается по-разному в зависимости от места, где он возник
+func isBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "SQLITE_BUSY") || strings.Contains(msg, "SQLITE_LOCKED")
+}
@@ -0,0 +1,104 @@
+// Импорт пользователей старого форума и прежнего игрового сервера из CSV или JSON.
+//
+//	importusers -config config.yaml -file forum_users.csv -dry-run
+//	importusers -config config.yaml -file game_users.json -map-ids > id_map.json
+//
+// Хеши паролей сохраняются с пометкой алгоритма (md5, md5_double, sha1, phpass,
+// bcrypt, argon2id) и пересчитываются текущим алгоритмом при первом входе.
+// Отчет печатается в stdout в формате JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/userimport"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	file := flag.String("file", "", "CSV or JSON file with user records")
+	format := flag.String("format", "", "csv or json (default: by file extension)")
+	mapIDs := flag.Bool("map-ids", false, "assign new IDs instead of keeping the old ones and report the mapping")
+	dryRun := flag.Bool("dry-run", false, "validate records without saving them")
+	flag.Parse()
+
+	if err := run(*configPath, *file, *format, userimport.Options{MapIDs: *mapIDs, DryRun: *dryRun}); err != nil {
+		fmt.Fprintln(os.Stderr, "importusers:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath, file, format string, opts userimport.Options) error {
+	if file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	opts.GameSurnames = gamesurname.PolicyFrom(cfg.GameSurname)
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var records []userimport.Record
+	switch format {
+	case "csv":
+		records, err = userimport.ParseCSV(f)
+	case "json":
+		records, err = userimport.ParseJSON(f)
+	default:
+		return fmt.Errorf("unknown format %q, use -format csv or json", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.RunMigrations(os.DirFS(filepath.Join("migrations", cfg.Database.Driver()))); err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	if err := db.Prepare(ctx); err != nil {
+		return err
+	}
+
+	report, importErr := userimport.Import(ctx, db, records, opts)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if importErr != nil {
+		return fmt.Errorf("interrupted after %d imported users: %w", report.Imported, importErr)
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d of %d records were not imported", len(report.Errors), report.Total)
+	}
+	return nil
+}
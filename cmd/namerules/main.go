@@ -0,0 +1,146 @@
+// Правила администраторов для фильтра логинов и игровых фамилий.
+//
+//	namerules -config config.yaml list
+//	namerules -config config.yaml add [-action block|allow] [-note текст] шаблон
+//	namerules -config config.yaml delete id
+//	namerules -config config.yaml check имя
+//
+// Те же операции, что у /internal/admin/name-rules на admin-слушателе, но
+// напрямую через базу: admin-слушатель есть только при собственном TLS
+// сервера, а за прокси (tls.behind_proxy) правила меняются этой командой.
+// Работающие серверы подхватывают изменения через names.refresh_interval.
+// Шаблон: "слово", "слово*", "*слово" или "*слово*".
+// Результат печатается в stdout в формате JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/namepolicy"
+	"LOIL-auth-server/pkg/logger"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: namerules [-config path] list | add [-action block|allow] [-note text] pattern | delete id | check name")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*configPath, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "namerules:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("expected a command")
+	}
+	command, args := args[0], args[1:]
+
+	// Аргументы проверяются до подключения к базе
+	var rule models.NameRule
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			return fmt.Errorf("list takes no arguments")
+		}
+	case "add":
+		addFlags := flag.NewFlagSet("add", flag.ContinueOnError)
+		action := addFlags.String("action", models.NameRuleBlock, "block or allow")
+		note := addFlags.String("note", "", "why the rule was added")
+		if err := addFlags.Parse(args); err != nil {
+			return err
+		}
+		if addFlags.NArg() != 1 {
+			return fmt.Errorf("add expects one pattern")
+		}
+		rule = models.NameRule{Pattern: strings.TrimSpace(addFlags.Arg(0)), Action: *action, Note: *note}
+		if rule.Action != models.NameRuleBlock && rule.Action != models.NameRuleAllow {
+			return fmt.Errorf("-action must be %s or %s", models.NameRuleBlock, models.NameRuleAllow)
+		}
+		if err := namepolicy.ValidatePattern(rule.Pattern); err != nil {
+			return fmt.Errorf("pattern %q: %w", rule.Pattern, err)
+		}
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("delete expects one rule ID")
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("rule ID %q: %w", args[0], err)
+		}
+		rule.ID = id
+	case "check":
+		if len(args) != 1 || args[0] == "" {
+			return fmt.Errorf("check expects one name")
+		}
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	cfg, err := config.NewManager(configPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.Get().Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.RunMigrations(os.DirFS(filepath.Join("migrations", cfg.Get().Database.Driver()))); err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	if err := db.Prepare(ctx); err != nil {
+		return err
+	}
+
+	var result any
+	switch command {
+	case "list":
+		rules, err := db.ListNameRules(ctx)
+		if err != nil {
+			return err
+		}
+		if rules == nil {
+			rules = []models.NameRule{}
+		}
+		result = rules
+	case "add":
+		if err := db.AddNameRule(ctx, &rule); err != nil {
+			return err
+		}
+		result = rule
+	case "delete":
+		if err := db.DeleteNameRule(ctx, rule.ID); err != nil {
+			return err
+		}
+		result = map[string]int{"deleted": rule.ID}
+	case "check":
+		// Как и эндпоинт check, проверяет и при выключенном names.enabled
+		result = namepolicy.NewFilter(db, cfg, logger.NewLogger()).Verdict(ctx, args[0])
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,98 @@
+// Шифрование персональных данных, уже сохраненных в базе, и смена ключей.
+//
+//	piicrypt -config config.yaml status    # сколько email открыто и какими ключами зашифровано
+//	piicrypt -config config.yaml encrypt   # зашифровать открытые email и заполнить слепой индекс
+//	piicrypt -config config.yaml rotate    # перешифровать email старых версий ключа текущей
+//
+// Ключи берутся из секции database.encryption. Команды можно запускать на
+// работающем сервере и прерывать: строки, измененные во время прохода,
+// пропускаются и подхватываются повторным запуском. Старую версию ключа
+// можно убрать из конфигурации, когда status не показывает ее в byKey.
+// Смена index_key меняет слепой индекс всех строк, поэтому rotate после нее
+// запускают при остановленном сервере: до конца прохода поиск по email не
+// находит еще не переиндексированные строки.
+// Отчет печатается в stdout в формате JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	batchSize := flag.Int("batch", 500, "rows to read per batch")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: piicrypt [-config path] [-batch n] status | encrypt | rotate")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*configPath, *batchSize, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "piicrypt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, batchSize int, args []string) error {
+	if len(args) != 1 {
+		flag.Usage()
+		return fmt.Errorf("expected one command")
+	}
+
+	opts := database.EmailEncryptionOptions{BatchSize: batchSize}
+	switch args[0] {
+	case "status":
+		opts.DryRun = true
+		opts.Rotate = true
+	case "encrypt":
+	case "rotate":
+		opts.Rotate = true
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if !cfg.Database.Encryption.Enabled() {
+		return fmt.Errorf("set database.encryption.current_key, keys and index_key first")
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := db.RunMigrations(os.DirFS(filepath.Join("migrations", cfg.Database.Driver()))); err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+
+	report, encryptErr := db.EncryptEmails(ctx, opts)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if encryptErr != nil {
+		return fmt.Errorf("stopped after %d rows: %w", report.Scanned, encryptErr)
+	}
+	if len(report.Conflicts) > 0 {
+		return fmt.Errorf("%d users share an email with another user, resolve them manually", len(report.Conflicts))
+	}
+	return nil
+}
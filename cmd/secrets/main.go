@@ -0,0 +1,109 @@
+// Утилита для управления зашифрованным файлом секретов.
+//
+//	SECRETS_MASTER_KEY=file:///run/secrets/master_key secrets -file secrets.enc set jwt_secret < jwt.txt
+//	secrets -file secrets.enc list
+//	secrets -file secrets.enc delete jwt_secret_old
+//
+// Значение для set читается из stdin, чтобы не попадать в историю shell и вывод ps.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"LOIL-auth-server/internal/secrets"
+)
+
+func main() {
+	file := flag.String("file", os.Getenv("SECRETS_FILE"), "path to encrypted secrets file")
+	masterKeyRef := flag.String("master-key", os.Getenv("SECRETS_MASTER_KEY"), "master key or file:// reference to it")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: secrets [-file path] [-master-key ref] set <name> | get <name> | delete <name> | list")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*file, *masterKeyRef, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "secrets:", err)
+		os.Exit(1)
+	}
+}
+
+func run(file, masterKeyRef string, args []string) error {
+	if file == "" {
+		return fmt.Errorf("-file or SECRETS_FILE is required")
+	}
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("command is required")
+	}
+
+	masterKey, err := secrets.Resolve(masterKeyRef, nil)
+	if err != nil {
+		return fmt.Errorf("master key: %w", err)
+	}
+
+	store, err := secrets.OpenStore(file, []byte(masterKey))
+	if err != nil {
+		return err
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+		return nil
+
+	case "get":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: get <name>")
+		}
+		value, err := store.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	case "set":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: set <name> (value is read from stdin)")
+		}
+		value, err := readValue(os.Stdin)
+		if err != nil {
+			return err
+		}
+		store.Set(args[0], value)
+		return store.Save()
+
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: delete <name>")
+		}
+		if _, err := store.Get(args[0]); err != nil {
+			return err
+		}
+		store.Delete(args[0])
+		return store.Save()
+	}
+
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func readValue(r io.Reader) (string, error) {
+	content, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return "", fmt.Errorf("read value from stdin: %w", err)
+	}
+	value := strings.TrimRight(string(content), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("value is empty")
+	}
+	return value, nil
+}
@@ -1,14 +1,32 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 
+	"LOIL-auth-server/internal/breach"
 	"LOIL-auth-server/internal/config"
 	"LOIL-auth-server/internal/database"
 	"LOIL-auth-server/internal/handlers"
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/mail"
 	"LOIL-auth-server/internal/middleware"
+	"LOIL-auth-server/internal/namepolicy"
+	"LOIL-auth-server/internal/passwordpolicy"
+	"LOIL-auth-server/internal/tlsutil"
 	"LOIL-auth-server/pkg/logger"
 )
 
@@ -16,51 +34,346 @@ func main() {
 	// Инициализация логгера
 	appLogger := logger.NewLogger()
 
-	// Загрузка конфигурации
-	cfg, err := config.Load()
+	// Загрузка конфигурации: файл задается флагом -config или CONFIG_PATH,
+	// переменные окружения переопределяют значения из файла
+	configPath := flag.String("config", os.Getenv("CONFIG_PATH"), "path to YAML config file")
+	flag.Parse()
+
+	cfgManager, err := config.NewManager(*configPath)
 	if err != nil {
 		appLogger.Fatal("Failed to load config:", err)
 	}
+	cfg := cfgManager.Get()
+	appLogger.Info("Environment: " + cfg.Environment)
+	if cfg.IsProduction() {
+		for _, warning := range cfg.ProductionWarnings() {
+			appLogger.Warn("Production check:", warning)
+		}
+	}
+
+	applyLogLevel(appLogger, cfg)
+	cfgManager.OnReload(func(cfg *config.Config) {
+		applyLogLevel(appLogger, cfg)
+	})
 
 	// Инициализация базы данных
-	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	db, err := database.Open(cfg.Database)
 	if err != nil {
 		appLogger.Fatal("Database connection failed:", err)
 	}
-	defer db.Close()
+	appLogger.Info("Database: " + cfg.Database.Driver())
 
-	// Запуск миграций
-	migrationFS := os.DirFS("migrations")
+	// Запуск миграций: у каждого хранилища свой набор в migrations/<driver>
+	migrationFS := os.DirFS(filepath.Join("migrations", cfg.Database.Driver()))
 	if err := db.RunMigrations(migrationFS); err != nil {
-		log.Fatal("Migrations failed:", err)
+		db.Close()
+		appLogger.Fatal("Migrations failed:", err)
+	}
+
+	// Запросы готовятся один раз, когда схема уже актуальна
+	if err := db.Prepare(context.Background()); err != nil {
+		db.Close()
+		appLogger.Fatal("Preparing statements failed:", err)
+	}
+
+	// Канонические логины и email вычисляются в Go, поэтому строки,
+	// добавленные до миграции или импортом в обход сервера, дополняются здесь
+	canonicalReport, err := db.CanonicalizeUsers(context.Background(), database.CanonicalOptions{})
+	if err != nil {
+		db.Close()
+		appLogger.Fatal("Canonicalizing users failed:", err)
+	}
+	if canonicalReport.Logins+canonicalReport.Emails+canonicalReport.GameSurnames > 0 {
+		appLogger.Info(fmt.Sprintf("Canonical keys filled: %d logins, %d emails, %d game surnames",
+			canonicalReport.Logins, canonicalReport.Emails, canonicalReport.GameSurnames))
+	}
+	for _, collision := range canonicalReport.Collisions {
+		appLogger.Warn(fmt.Sprintf("User %d has the same canonical %s as user %d and is found only by the exact value, see canonicalize check",
+			collision.UserID, collision.Field, collision.ExistingID))
+	}
+
+	// Хеширование паролей идет на отдельном пуле, чтобы всплеск входов
+	// не занимал все процессоры и не мешал остальным запросам
+	passwordHasher, err := newPasswordHasher(cfg.Hashing)
+	if err != nil {
+		db.Close()
+		appLogger.Fatal("Password hasher setup failed:", err)
+	}
+	if cfg.Hashing.Pepper.Current != "" {
+		appLogger.Info("Password pepper version: " + cfg.Hashing.Pepper.Current)
+	}
+	hasher := hashing.NewPool(passwordHasher, cfg.Hashing.Workers, cfg.Hashing.QueueSize)
+	expvar.Publish("password_hashing", expvar.Func(func() any { return hasher.Stats() }))
+
+	// База утечек целиком читается в память; проверка выключена, если файл не задан
+	var breaches passwordpolicy.BreachList
+	if cfg.Breach.Enabled() {
+		filter, err := breach.OpenFilter(cfg.Breach.FilterFile)
+		if err != nil {
+			db.Close()
+			appLogger.Fatal("Loading breach filter failed:", err)
+		}
+		appLogger.Info(fmt.Sprintf("Breach filter: %d hashes, %.1f MiB, false positive rate %.2g",
+			filter.Keys(), float64(filter.Size())/(1<<20), filter.FalsePositiveRate()))
+		breaches = filter
 	}
 
+	// Письма отправляются в фоне; без SMTP-сервера они пишутся в лог
+	var mailSender mail.Sender = mail.NewLogSender(appLogger)
+	if cfg.Mail.Enabled() {
+		mailSender = mail.NewSMTPSender(cfg.Mail.SMTPAddress, cfg.Mail.From, cfg.Mail.Username, cfg.Mail.Password)
+	}
+	mailQueue := mail.NewQueue(mailSender, 100, appLogger)
+
+	// Фильтр имен: встроенные списки и правила администраторов из базы
+	nameFilter := namepolicy.NewFilter(db, cfgManager, appLogger)
+
 	// Инициализация обработчиков
-	authHandler := handlers.NewAuthHandler(db, appLogger)
-	profileHandler := handlers.NewProfileHandler(db, appLogger)
+	authHandler := handlers.NewAuthHandler(db, hasher, breaches, nameFilter, mailQueue, cfgManager, appLogger)
+	profileHandler := handlers.NewProfileHandler(db, nameFilter, cfgManager, appLogger)
+	healthHandler := handlers.NewHealthHandler(db, migrationFS, appLogger)
+	importHandler := handlers.NewImportHandler(db, cfgManager, appLogger)
+	nameRulesHandler := handlers.NewNameRulesHandler(db, nameFilter, appLogger)
 
 	// Настройка маршрутов
 	router := http.NewServeMux()
 
 	// Публичные маршруты
-	router.HandleFunc("POST /api/auth/register", authHandler.Register)
-	router.HandleFunc("POST /api/auth/login", authHandler.Login)
+	rateLimiter := middleware.NewRateLimiter(cfgManager)
+	router.HandleFunc("POST /api/auth/register", rateLimiter.Limit(authHandler.Register))
+	router.HandleFunc("POST /api/auth/login", rateLimiter.Limit(authHandler.Login))
+	router.HandleFunc("GET /api/auth/validation-rules", authHandler.ValidationRules)
+	router.HandleFunc("POST /api/auth/password-strength", rateLimiter.Limit(authHandler.PasswordStrength))
 
 	// Защищенные маршруты
-	router.Handle("GET /api/auth/profile", middleware.AuthMiddleware(profileHandler.GetProfile))
-	router.Handle("PUT /api/auth/profile", middleware.AuthMiddleware(profileHandler.UpdateProfile))
+	router.Handle("GET /api/auth/profile", middleware.AuthMiddleware(cfgManager, profileHandler.GetProfile))
+	router.Handle("PUT /api/auth/profile", middleware.AuthMiddleware(cfgManager, profileHandler.UpdateProfile))
+	router.Handle("PUT /api/auth/password", middleware.AuthMiddleware(cfgManager, rateLimiter.Limit(authHandler.ChangePassword)))
 
-	// Health check
-	router.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status": "ok"}`))
-	})
+	// Health checks: /health оставлен для совместимости и работает как liveness
+	router.HandleFunc("GET /health", healthHandler.Live)
+	router.HandleFunc("GET /health/live", healthHandler.Live)
+	router.HandleFunc("GET /health/ready", healthHandler.Ready)
+
+	// Отладочные эндпоинты (запрещены в production)
+	if cfg.Debug.Pprof {
+		router.HandleFunc("GET /debug/pprof/", pprof.Index)
+		router.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+		router.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+		router.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+		router.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+		router.Handle("GET /debug/vars", expvar.Handler())
+		appLogger.Warn("Debug endpoints enabled on /debug/pprof/ and /debug/vars")
+	}
+
+	if cfg.TLS.BehindProxy && cfg.RateLimit.Enabled && len(cfg.RateLimit.TrustedProxies) == 0 {
+		appLogger.Warn("Rate limit: tls.behind_proxy is set but rate_limit.trusted_proxies is empty, all clients behind the proxy share one limit")
+	}
 
-	// Настройка CORS middleware
-	handler := middleware.CORS(router)
+	// Настройка CORS и выбора языка
+	handler := middleware.CORS(cfgManager, middleware.Locale(cfgManager, router))
 
-	appLogger.Info("Auth server starting on " + cfg.ServerAddress)
-	if err := http.ListenAndServe(cfg.ServerAddress, handler); err != nil {
+	mainServer := newHTTPServer(cfg, cfg.Server.Address, handler)
+	servers := []*http.Server{mainServer}
+
+	var certReloader *tlsutil.CertReloader
+	if cfg.TLS.Enabled() {
+		certReloader, err = tlsutil.NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, appLogger)
+		if err != nil {
+			db.Close()
+			appLogger.Fatal("TLS setup failed:", err)
+		}
+		certReloader.Watch(cfg.TLS.ReloadInterval)
+		defer certReloader.Stop()
+
+		mainServer.TLSConfig, err = tlsutil.ServerConfig(cfg.TLS.MinVersion, cfg.TLS.CipherSuites, certReloader)
+		if err != nil {
+			db.Close()
+			appLogger.Fatal("TLS setup failed:", err)
+		}
+
+		// HTTP-листенер только перенаправляет на HTTPS
+		if cfg.TLS.RedirectAddress != "" {
+			servers = append(servers, newHTTPServer(cfg, cfg.TLS.RedirectAddress, redirectToHTTPS(cfg.Server.Address)))
+		}
+
+		// Внутренние эндпоинты для игровых серверов доступны только с клиентским сертификатом
+		if cfg.TLS.MTLS.Enabled() {
+			internalRouter := http.NewServeMux()
+			internalRouter.HandleFunc("POST /internal/auth/verify", authHandler.VerifyToken)
+			internalRouter.HandleFunc("GET /health/live", healthHandler.Live)
+			internalRouter.Handle("GET /internal/metrics", expvar.Handler())
+
+			internalServer := newHTTPServer(cfg, cfg.TLS.MTLS.Address, middleware.RequireClient(cfg.TLS.MTLS.AllowedClients, internalRouter))
+			internalServer.TLSConfig, err = tlsutil.MutualConfig(mainServer.TLSConfig, cfg.TLS.MTLS.ClientCAFile)
+			if err != nil {
+				db.Close()
+				appLogger.Fatal("mTLS setup failed:", err)
+			}
+			servers = append(servers, internalServer)
+		}
+
+		// Административные эндпоинты меняют аккаунты и хеши паролей, поэтому
+		// живут на отдельном порту со своим CA: сертификат игрового сервера
+		// к ним не подходит
+		if cfg.TLS.Admin.Enabled() {
+			adminRouter := http.NewServeMux()
+			adminRouter.HandleFunc("GET /health/live", healthHandler.Live)
+			adminRouter.HandleFunc("POST /internal/admin/users/import", importHandler.ImportUsers)
+			adminRouter.HandleFunc("GET /internal/admin/name-rules", nameRulesHandler.ListNameRules)
+			adminRouter.HandleFunc("POST /internal/admin/name-rules", nameRulesHandler.AddNameRule)
+			adminRouter.HandleFunc("DELETE /internal/admin/name-rules/{id}", nameRulesHandler.DeleteNameRule)
+			adminRouter.HandleFunc("GET /internal/admin/name-rules/check", nameRulesHandler.CheckName)
+
+			adminServer := newHTTPServer(cfg, cfg.TLS.Admin.Address, middleware.RequireClient(cfg.TLS.Admin.AllowedClients, adminRouter))
+			adminServer.TLSConfig, err = tlsutil.MutualConfig(mainServer.TLSConfig, cfg.TLS.Admin.ClientCAFile)
+			if err != nil {
+				db.Close()
+				appLogger.Fatal("admin mTLS setup failed:", err)
+			}
+			servers = append(servers, adminServer)
+		}
+	}
+
+	// Останавливаемся по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// SIGHUP перечитывает конфигурацию без перезапуска
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			ignored, err := cfgManager.Reload()
+			if err != nil {
+				appLogger.Error("Config reload failed, keeping current settings:", err)
+				continue
+			}
+			if len(ignored) > 0 {
+				appLogger.Warn("Config reloaded; changes in these sections require restart:", strings.Join(ignored, ", "))
+			} else {
+				appLogger.Info("Config reloaded")
+			}
+		}
+	}()
+
+	serverErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			var err error
+			if server.TLSConfig != nil {
+				appLogger.Info("Auth server starting on " + server.Addr + " (HTTPS)")
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				appLogger.Info("Auth server starting on " + server.Addr)
+				err = server.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("%s: %w", server.Addr, err)
+			}
+		}(server)
+	}
+
+	select {
+	case err := <-serverErr:
+		db.Close()
 		appLogger.Fatal("Server failed:", err)
+	case <-ctx.Done():
+		stop()
+		appLogger.Info("Shutdown signal received, draining connections")
+	}
+
+	// Readiness сразу отвечает 503, новые соединения не принимаются,
+	// а текущие запросы получают ShutdownTimeout на завершение
+	healthHandler.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				appLogger.Error("Graceful shutdown failed on "+server.Addr+":", err)
+				server.Close()
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	hasher.Close()
+	mailQueue.Close()
+	if err := db.Close(); err != nil {
+		appLogger.Error("Database close failed:", err)
+	}
+
+	appLogger.Info("Auth server stopped")
+}
+
+func newHTTPServer(cfg *config.Config, addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+}
+
+// redirectToHTTPS перенаправляет запрос на тот же путь по HTTPS на адрес основного сервера
+func redirectToHTTPS(httpsAddress string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddress)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	})
+}
+
+// newPasswordHasher хеширует выбранным алгоритмом и проверяет хеши обоих,
+// а также импортированные хеши старого форума и игрового сервера.
+// Если настроен перец, пароль подписывается им перед хешированием
+func newPasswordHasher(cfg config.HashingConfig) (*hashing.Hasher, error) {
+	argon := hashing.Argon2id{
+		Memory:      cfg.Argon2id.MemoryKiB,
+		Iterations:  cfg.Argon2id.Iterations,
+		Parallelism: cfg.Argon2id.Parallelism,
+		SaltLength:  cfg.Argon2id.SaltLength,
+		KeyLength:   cfg.Argon2id.KeyLength,
+	}
+	bcrypt := hashing.Bcrypt{Cost: cfg.Bcrypt.Cost}
+
+	hasher := hashing.NewHasher(argon, append([]hashing.Algorithm{bcrypt}, hashing.LegacyAlgorithms()...)...)
+	if cfg.Algorithm == config.HashBcrypt {
+		hasher = hashing.NewHasher(bcrypt, append([]hashing.Algorithm{argon}, hashing.LegacyAlgorithms()...)...)
+	}
+
+	peppers := hashing.Peppers{
+		Current: cfg.Pepper.Current,
+		Keys:    make(map[string][]byte, len(cfg.Pepper.Keys)),
+	}
+	for version, key := range cfg.Pepper.Keys {
+		peppers.Keys[version] = []byte(key)
+	}
+	return hasher.WithPeppers(peppers)
+}
+
+func applyLogLevel(appLogger *logger.Logger, cfg *config.Config) {
+	level, err := logger.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		appLogger.Error("Invalid log level:", err)
+		return
 	}
+	appLogger.SetLevel(level)
 }
@@ -0,0 +1,252 @@
+// Проверка, что ответы API не выдают, зарегистрирован ли пользователь: ни
+// содержимым, ни временем.
+//
+//	timingcheck -url http://localhost:8081 -known player1
+//	timingcheck -url http://localhost:8081 -known player1 -mode register -samples 100
+//
+// login сравнивает входы с неверным паролем для существующего логина -known и
+// для случайных несуществующих. register сравнивает регистрации с занятым
+// логином -known и со свободным; она создает аккаунты, поэтому запускается
+// только на тестовой базе и имеет смысл с auth.hide_registration_conflicts.
+//
+// Запросы двух групп чередуются в случайном порядке, чтобы дрейф нагрузки
+// влиял на обе одинаково. Для каждой группы печатаются медиана, p90 и среднее,
+// а затем t-статистика Уэлча по замерам без выбросов (выше 95-го перцентиля
+// группы), как в dudect. |t| больше -threshold или разные статус и код ошибки
+// считаются утечкой, и команда завершается с кодом 1. Ограничение частоты
+// запросов (rate_limit) на проверяемом сервере нужно выключить.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// group - замеры запросов одного вида
+type group struct {
+	name      string
+	durations []time.Duration
+	// responses - сколько раз встретился каждый ответ "статус код_ошибки"
+	responses map[string]int
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8081", "server base URL")
+	mode := flag.String("mode", "login", "login or register")
+	known := flag.String("known", "", "registered login")
+	password := flag.String("password", "Timing-check-9f2#Lq", "password to send; for register it must pass the password policy")
+	samples := flag.Int("samples", 200, "requests per group")
+	warmup := flag.Int("warmup", 10, "requests per group to discard first")
+	threshold := flag.Float64("threshold", 4.5, "largest acceptable |t| of Welch's t-test")
+	flag.Parse()
+
+	if err := run(*baseURL, *mode, *known, *password, *samples, *warmup, *threshold); err != nil {
+		fmt.Fprintln(os.Stderr, "timingcheck:", err)
+		os.Exit(1)
+	}
+}
+
+func run(baseURL, mode, known, password string, samples, warmup int, threshold float64) error {
+	if known == "" {
+		return fmt.Errorf("-known is required")
+	}
+
+	var endpoint string
+	var knownBody, unknownBody func() any
+	switch mode {
+	case "login":
+		endpoint = "/api/auth/login"
+		knownBody = func() any { return map[string]string{"identifier": known, "password": password + "-wrong"} }
+		unknownBody = func() any {
+			return map[string]string{"identifier": randomName("tc", 12), "password": password + "-wrong"}
+		}
+	case "register":
+		endpoint = "/api/auth/register"
+		knownBody = func() any { return registration(known, password) }
+		unknownBody = func() any { return registration(randomName("tc", 12), password) }
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	url := strings.TrimSuffix(baseURL, "/") + endpoint
+	knownGroup := &group{name: "known " + known, responses: make(map[string]int)}
+	unknownGroup := &group{name: "unknown", responses: make(map[string]int)}
+
+	for i := 0; i < warmup+samples; i++ {
+		record := i >= warmup
+		pair := []struct {
+			g    *group
+			body func() any
+		}{{knownGroup, knownBody}, {unknownGroup, unknownBody}}
+		rand.Shuffle(len(pair), func(a, b int) { pair[a], pair[b] = pair[b], pair[a] })
+
+		for _, p := range pair {
+			elapsed, response, err := measure(client, url, p.body())
+			if err != nil {
+				return err
+			}
+			if record {
+				p.g.durations = append(p.g.durations, elapsed)
+				p.g.responses[response]++
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "group\tmedian\tp90\tmean\tresponses")
+	for _, g := range []*group{knownGroup, unknownGroup} {
+		sorted := sortedCopy(g.durations)
+		fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%v\n", g.name,
+			percentile(sorted, 0.5).Round(time.Microsecond), percentile(sorted, 0.9).Round(time.Microsecond),
+			mean(sorted).Round(time.Microsecond), g.responses)
+	}
+	tw.Flush()
+
+	t := welchT(cropped(knownGroup.durations), cropped(unknownGroup.durations))
+	fmt.Printf("welch t = %.2f (threshold %.1f)\n", t, threshold)
+
+	var leaks []string
+	if !sameResponses(knownGroup.responses, unknownGroup.responses) {
+		leaks = append(leaks, "responses differ between known and unknown users")
+	}
+	if math.Abs(t) > threshold {
+		leaks = append(leaks, "response time depends on whether the user exists")
+	}
+	if len(leaks) > 0 {
+		return fmt.Errorf("%s", strings.Join(leaks, "; "))
+	}
+	fmt.Println("no difference detected")
+	return nil
+}
+
+func registration(login, password string) map[string]string {
+	return map[string]string{
+		"login":           login,
+		"gameSurname":     randomLetters(12),
+		"email":           randomName("tc", 12) + "@example.com",
+		"password":        password,
+		"passwordConfirm": password,
+	}
+}
+
+// measure отправляет запрос и возвращает время до конца тела ответа и ответ
+// в виде "статус код_ошибки"
+func measure(client *http.Client, url string, body any) (time.Duration, string, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	start := time.Now()
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var decoded struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(data, &decoded)
+	return elapsed, fmt.Sprintf("%d %s", resp.StatusCode, decoded.Code), nil
+}
+
+func sameResponses(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for response := range a {
+		if _, ok := b[response]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// cropped отбрасывает замеры выше 95-го перцентиля: паузы GC и планировщика
+// шумят сильнее, чем различие, которое ищем
+func cropped(durations []time.Duration) []time.Duration {
+	sorted := sortedCopy(durations)
+	limit := percentile(sorted, 0.95)
+	n := sort.Search(len(sorted), func(i int) bool { return sorted[i] > limit })
+	return sorted[:n]
+}
+
+// welchT - t-статистика Уэлча для двух выборок с разной дисперсией
+func welchT(a, b []time.Duration) float64 {
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	se := math.Sqrt(varA/float64(len(a)) + varB/float64(len(b)))
+	if se == 0 {
+		return 0
+	}
+	return (meanA - meanB) / se
+}
+
+func meanVariance(durations []time.Duration) (mean, variance float64) {
+	if len(durations) < 2 {
+		return 0, 0
+	}
+	for _, d := range durations {
+		mean += float64(d)
+	}
+	mean /= float64(len(durations))
+	for _, d := range durations {
+		diff := float64(d) - mean
+		variance += diff * diff
+	}
+	return mean, variance / float64(len(durations)-1)
+}
+
+func sortedCopy(durations []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+func mean(durations []time.Duration) time.Duration {
+	m, _ := meanVariance(durations)
+	return time.Duration(m)
+}
+
+func randomName(prefix string, length int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := []byte(prefix)
+	for len(b) < length {
+		b = append(b, chars[rand.Intn(len(chars))])
+	}
+	return string(b)
+}
+
+func randomLetters(length int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
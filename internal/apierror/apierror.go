@@ -0,0 +1,243 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/validation"
+)
+
+// Error - ошибка API с HTTP-статусом и стабильным машиночитаемым кодом.
+// Код не меняется между версиями, поэтому лаунчер может локализовать сообщение по нему.
+// Message - английский текст по умолчанию; клиенту отдается перевод из каталога
+// error.<code> на языке запроса.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// RetryAfter в секундах для 429/503; 0 - заголовок не отправляется
+	RetryAfter int
+	// Fields - ошибки отдельных полей формы для validation_failed
+	Fields []validation.FieldError
+	// cause - исходная ошибка для логов, клиенту не отдается
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Code + ": " + e.cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is сравнивает ошибки API по коду, чтобы errors.Is работал и для копий с причиной
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap возвращает копию ошибки с исходной причиной
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.cause = cause
+	return &wrapped
+}
+
+// WithRetryAfter возвращает копию ошибки с заголовком Retry-After
+func (e *Error) WithRetryAfter(seconds int) *Error {
+	copied := *e
+	copied.RetryAfter = seconds
+	return &copied
+}
+
+// Коды ошибок API
+var (
+	ErrInvalidInput = New(http.StatusBadRequest, "invalid_input", "Invalid input")
+	ErrValidation   = New(http.StatusBadRequest, "validation_failed", "Validation failed")
+
+	ErrUnauthorized       = New(http.StatusUnauthorized, "unauthorized", "Authorization header required")
+	ErrInvalidAuthFormat  = New(http.StatusUnauthorized, "invalid_authorization", "Invalid authorization format")
+	ErrInvalidToken       = New(http.StatusUnauthorized, "invalid_token", "Invalid token")
+	ErrInvalidCredentials = New(http.StatusUnauthorized, "invalid_credentials", "Invalid login or password")
+
+	ErrForbidden = New(http.StatusForbidden, "forbidden", "Access denied")
+
+	ErrUserNotFound     = New(http.StatusNotFound, "user_not_found", "User not found")
+	ErrNameRuleNotFound = New(http.StatusNotFound, "name_rule_not_found", "Name rule not found")
+
+	ErrLoginTaken       = New(http.StatusConflict, "login_taken", "Login already exists")
+	ErrGameSurnameTaken = New(http.StatusConflict, "game_surname_taken", "Game surname already exists")
+	ErrEmailTaken       = New(http.StatusConflict, "email_taken", "Email already exists")
+	ErrNameRuleExists   = New(http.StatusConflict, "name_rule_exists", "Name rule already exists")
+
+	ErrRateLimited = New(http.StatusTooManyRequests, "rate_limited", "Too many requests")
+
+	ErrInternal    = New(http.StatusInternalServerError, "internal_error", "Server error")
+	ErrUnavailable = New(http.StatusServiceUnavailable, "service_unavailable", "Service temporarily unavailable")
+)
+
+// From приводит любую ошибку к ошибке API. Доменные ошибки хранилища получают
+// свои статусы, все неизвестные ошибки становятся 500 без раскрытия деталей.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	// Для совместимости со старыми клиентами в error попадает первое сообщение
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) && len(fieldErrs) > 0 {
+		validationErr := ErrValidation.Wrap(err)
+		validationErr.Message = fieldErrs[0].Message
+		validationErr.Fields = fieldErrs
+		return validationErr
+	}
+
+	switch {
+	case errors.Is(err, database.ErrUserNotFound):
+		return ErrUserNotFound.Wrap(err)
+	case errors.Is(err, database.ErrLoginTaken):
+		return ErrLoginTaken.Wrap(err)
+	case errors.Is(err, database.ErrGameSurnameTaken):
+		return ErrGameSurnameTaken.Wrap(err)
+	case errors.Is(err, database.ErrEmailTaken):
+		return ErrEmailTaken.Wrap(err)
+	case errors.Is(err, database.ErrNameRuleNotFound):
+		return ErrNameRuleNotFound.Wrap(err)
+	case errors.Is(err, database.ErrNameRuleExists):
+		return ErrNameRuleExists.Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// Запрос не уложился в таймаут или клиент отключился - повторить можно позже
+		return ErrUnavailable.Wrap(err)
+	case errors.Is(err, hashing.ErrOverloaded):
+		return ErrUnavailable.Wrap(err).WithRetryAfter(1)
+	}
+
+	return ErrInternal.Wrap(err)
+}
+
+// Response - тело ошибки в формате, который уже понимают клиенты
+type Response struct {
+	Success bool                    `json:"success"`
+	Error   string                  `json:"error"`
+	Code    string                  `json:"code"`
+	Fields  []validation.FieldError `json:"fields,omitempty"`
+}
+
+// Problem - тело ошибки по RFC 9457 (application/problem+json)
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Code   string `json:"code"`
+	// Instance - путь запроса, в котором произошла ошибка
+	Instance string                  `json:"instance,omitempty"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+}
+
+const ProblemContentType = "application/problem+json"
+
+// Write отправляет ошибку клиенту с правильным статусом. Если клиент указал
+// application/problem+json в Accept, ответ формируется по RFC 9457.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	locale := i18n.FromContext(r.Context())
+	message := apiErr.localizedMessage(locale)
+	fields := localizedFields(apiErr.Fields, locale)
+
+	if apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(apiErr.RetryAfter))
+	}
+
+	if wantsProblem(r) {
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(apiErr.Status)
+		json.NewEncoder(w).Encode(Problem{
+			Type:     "urn:loil:error:" + apiErr.Code,
+			Title:    message,
+			Status:   apiErr.Status,
+			Code:     apiErr.Code,
+			Instance: r.URL.Path,
+			Errors:   fields,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(Response{
+		Success: false,
+		Error:   message,
+		Code:    apiErr.Code,
+		Fields:  fields,
+	})
+}
+
+// localizedMessage переводит сообщение по коду ошибки. Для ошибок полей
+// основным сообщением служит первая ошибка поля.
+func (e *Error) localizedMessage(locale string) string {
+	if len(e.Fields) > 0 {
+		return e.Fields[0].Localized(locale)
+	}
+
+	key := "error." + e.Code
+	params := i18n.Params{}
+
+	// "попробуйте через N секунд/минут" с правильной формой множественного числа
+	if e.RetryAfter > 0 {
+		retryKey := key + ".seconds"
+		params["count"] = e.RetryAfter
+		if e.RetryAfter >= 60 {
+			retryKey = key + ".minutes"
+			params["count"] = (e.RetryAfter + 59) / 60
+		}
+		if i18n.Has(locale, retryKey) {
+			key = retryKey
+		}
+	}
+
+	if !i18n.Has(locale, key) {
+		return e.Message
+	}
+	return i18n.T(locale, key, params)
+}
+
+func localizedFields(fields []validation.FieldError, locale string) []validation.FieldError {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	localized := make([]validation.FieldError, len(fields))
+	for i, fieldErr := range fields {
+		localized[i] = fieldErr
+		localized[i].Message = fieldErr.Localized(locale)
+	}
+	return localized
+}
+
+func wantsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), ProblemContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"LOIL-auth-server/internal/hashing"
+)
+
+// Переполненная очередь хеширования - временная перегрузка: 503 с Retry-After,
+// чтобы лаунчер повторил вход, а не показал внутреннюю ошибку
+func TestWriteHashingOverloaded(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := fmt.Errorf("login: %w", hashing.ErrOverloaded)
+	Write(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login", nil), err)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	var body Response
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Success || body.Code != ErrUnavailable.Code {
+		t.Errorf("body = %+v, want code %q", body, ErrUnavailable.Code)
+	}
+}
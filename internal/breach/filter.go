@@ -0,0 +1,241 @@
+// Package breach проверяет пароли по базе известных утечек без обращения
+// к внешним сервисам. База хранится локально как фильтр Блума по SHA-1
+// паролей: на миллиард хешей при вероятности ложного срабатывания 0.1%
+// нужно около 1.7 ГБ вместо 20 ГБ исходного списка. Ложноположительные
+// ответы возможны, ложноотрицательные - нет.
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+)
+
+// Filter - фильтр Блума по SHA-1 паролей. Индексы k битов получаются двойным
+// хешированием из первых 16 байт дайджеста: SHA-1 и так распределен равномерно.
+// После сборки фильтр только читается и безопасен для параллельного использования.
+type Filter struct {
+	hashes uint32
+	keys   uint64
+	bits   []uint64
+}
+
+// maxHashes ограничивает число битов на ключ: больше не нужно ни при какой разумной вероятности
+const maxHashes = 32
+
+// NewFilter готовит пустой фильтр на expected ключей с вероятностью ложного
+// срабатывания falsePositiveRate (0 < rate < 1)
+func NewFilter(expected uint64, falsePositiveRate float64) (*Filter, error) {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %v", falsePositiveRate)
+	}
+	n := float64(max(expected, 1))
+
+	// Оптимальные размер и число хешей: m = -n ln p / ln²2, k = m/n ln 2
+	bits := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	words := uint64(math.Ceil(bits / 64))
+	hashes := uint32(math.Round(float64(words*64) / n * math.Ln2))
+	hashes = min(max(hashes, 1), maxHashes)
+
+	return &Filter{hashes: hashes, bits: make([]uint64, words)}, nil
+}
+
+// AddHash добавляет SHA-1 пароля
+func (f *Filter) AddHash(digest [sha1.Size]byte) {
+	h1, h2, m := f.indexes(digest)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.keys++
+}
+
+// ContainsHash сообщает, что SHA-1 пароля, вероятно, есть в фильтре
+func (f *Filter) ContainsHash(digest [sha1.Size]byte) bool {
+	h1, h2, m := f.indexes(digest)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains сообщает, что пароль, вероятно, встречался в утечках
+func (f *Filter) Contains(password string) bool {
+	return f.ContainsHash(sha1.Sum([]byte(password)))
+}
+
+func (f *Filter) indexes(digest [sha1.Size]byte) (h1, h2, m uint64) {
+	h1 = binary.LittleEndian.Uint64(digest[0:8])
+	// Нечетный шаг не зацикливается раньше времени при четном m
+	h2 = binary.LittleEndian.Uint64(digest[8:16]) | 1
+	return h1, h2, uint64(len(f.bits)) * 64
+}
+
+// Keys - число добавленных ключей
+func (f *Filter) Keys() uint64 {
+	return f.keys
+}
+
+// Size - размер битового массива в байтах
+func (f *Filter) Size() uint64 {
+	return uint64(len(f.bits)) * 8
+}
+
+// FalsePositiveRate - ожидаемая вероятность ложного срабатывания при текущем
+// числе ключей: (1 - e^(-kn/m))^k
+func (f *Filter) FalsePositiveRate() float64 {
+	k := float64(f.hashes)
+	fill := 1 - math.Exp(-k*float64(f.keys)/float64(len(f.bits)*64))
+	return math.Pow(fill, k)
+}
+
+// Формат файла: заголовок, биты little-endian по 64, CRC-32C битов.
+// Версия формата входит в магическую строку.
+var fileMagic = [8]byte{'L', 'O', 'I', 'L', 'B', 'F', '0', '1'}
+
+type fileHeader struct {
+	Magic  [8]byte
+	Hashes uint32
+	Flags  uint32 // зарезервировано
+	Keys   uint64
+	Words  uint64
+}
+
+// headerSize и trailerSize нужны, чтобы сверить длину файла до чтения
+const (
+	headerSize  = 32
+	trailerSize = 4
+)
+
+// maxFilterSize защищает от выделения памяти по поврежденному заголовку
+const maxFilterSize = 64 << 30
+
+var (
+	ErrInvalidFilter = errors.New("invalid breach filter file")
+	ErrChecksum      = errors.New("breach filter checksum mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// chunkWords - сколько 64-битных слов читается и пишется за раз
+const chunkWords = 8192
+
+// WriteTo записывает фильтр в w
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriterSize(w, chunkWords*8)
+	header := fileHeader{
+		Magic:  fileMagic,
+		Hashes: f.hashes,
+		Keys:   f.keys,
+		Words:  uint64(len(f.bits)),
+	}
+	if err := binary.Write(bw, binary.LittleEndian, header); err != nil {
+		return 0, err
+	}
+
+	written := int64(headerSize)
+	crc := uint32(0)
+	buf := make([]byte, chunkWords*8)
+	for start := 0; start < len(f.bits); start += chunkWords {
+		chunk := f.bits[start:min(start+chunkWords, len(f.bits))]
+		b := buf[:len(chunk)*8]
+		for i, word := range chunk {
+			binary.LittleEndian.PutUint64(b[i*8:], word)
+		}
+		crc = crc32.Update(crc, castagnoli, b)
+		n, err := bw.Write(b)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if err := binary.Write(bw, binary.LittleEndian, crc); err != nil {
+		return written, err
+	}
+	written += trailerSize
+	return written, bw.Flush()
+}
+
+// ReadFilter читает фильтр, записанный WriteTo, и проверяет контрольную сумму
+func ReadFilter(r io.Reader) (*Filter, error) {
+	br := bufio.NewReaderSize(r, chunkWords*8)
+
+	var header fileHeader
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if header.Magic != fileMagic {
+		return nil, fmt.Errorf("%w: unknown format", ErrInvalidFilter)
+	}
+	if header.Hashes == 0 || header.Hashes > maxHashes || header.Words == 0 || header.Words > maxFilterSize/8 {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidFilter)
+	}
+
+	f := &Filter{
+		hashes: header.Hashes,
+		keys:   header.Keys,
+		bits:   make([]uint64, header.Words),
+	}
+	crc := uint32(0)
+	buf := make([]byte, chunkWords*8)
+	for start := 0; start < len(f.bits); start += chunkWords {
+		chunk := f.bits[start:min(start+chunkWords, len(f.bits))]
+		b := buf[:len(chunk)*8]
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		crc = crc32.Update(crc, castagnoli, b)
+		for i := range chunk {
+			chunk[i] = binary.LittleEndian.Uint64(b[i*8:])
+		}
+	}
+
+	var stored uint32
+	if err := binary.Read(br, binary.LittleEndian, &stored); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	if stored != crc {
+		return nil, ErrChecksum
+	}
+	return f, nil
+}
+
+// OpenFilter читает фильтр из файла, заранее сверяя размер файла с заголовком
+func OpenFilter(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var header fileHeader
+	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", path, ErrInvalidFilter, err)
+	}
+	if header.Words > maxFilterSize/8 || info.Size() != int64(headerSize+header.Words*8+trailerSize) {
+		return nil, fmt.Errorf("%s: %w: file size does not match header", path, ErrInvalidFilter)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	f, err := ReadFilter(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
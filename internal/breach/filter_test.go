@@ -0,0 +1,200 @@
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// buildFilter собирает фильтр из списка в формате HIBP, как breachfilter
+func buildFilter(t *testing.T, list string, minCount int) *Filter {
+	t.Helper()
+	f, err := NewFilter(1000, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ReadHashList(strings.NewReader(list), minCount, func(digest [sha1.Size]byte) error {
+		f.AddHash(digest)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadHashList: %v", err)
+	}
+	return f
+}
+
+func writeFilter(t *testing.T, f *Filter) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	n, err := f.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+	}
+	return buf.Bytes()
+}
+
+func TestBuildWriteOpen(t *testing.T) {
+	list := sha1Hex("password") + ":9545824\n" +
+		strings.ToLower(sha1Hex("qwerty")) + ":3912816\n" +
+		"\n" +
+		sha1Hex("123456") + ":37359195\n" +
+		sha1Hex("rare-password") + ":1\n" +
+		sha1Hex("no-count") + "\n"
+	f := buildFilter(t, list, 2)
+	if f.Keys() != 3 {
+		t.Errorf("Keys = %d, want 3 (rare-password and no-count are below min count)", f.Keys())
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	if err := os.WriteFile(path, writeFilter(t, f), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := OpenFilter(path)
+	if err != nil {
+		t.Fatalf("OpenFilter: %v", err)
+	}
+
+	if loaded.Keys() != f.Keys() || loaded.Size() != f.Size() {
+		t.Errorf("loaded filter has %d keys and %d bytes, want %d and %d", loaded.Keys(), loaded.Size(), f.Keys(), f.Size())
+	}
+	for _, password := range []string{"password", "qwerty", "123456"} {
+		if !loaded.Contains(password) {
+			t.Errorf("Contains(%q) = false, want true", password)
+		}
+	}
+	for _, password := range []string{"rare-password", "no-count", "Password", "correct horse battery staple"} {
+		if loaded.Contains(password) {
+			t.Errorf("Contains(%q) = true, want false", password)
+		}
+	}
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	const keys = 10000
+	f, err := NewFilter(keys, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range keys {
+		f.AddHash(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i))))
+	}
+	for i := range keys {
+		if !f.Contains(fmt.Sprintf("breached-%d", i)) {
+			t.Fatalf("false negative for key %d", i)
+		}
+	}
+
+	falsePositives := 0
+	const probes = 20000
+	for i := range probes {
+		if f.Contains(fmt.Sprintf("clean-%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / probes; rate > 0.02 {
+		t.Errorf("false positive rate %.4f, want about 0.01", rate)
+	}
+	if rate := f.FalsePositiveRate(); rate < 0.005 || rate > 0.015 {
+		t.Errorf("FalsePositiveRate = %.4f, want about 0.01", rate)
+	}
+}
+
+func TestNewFilterRejectsBadRate(t *testing.T) {
+	for _, rate := range []float64{0, 1, -0.1, 1.5} {
+		if _, err := NewFilter(100, rate); err == nil {
+			t.Errorf("NewFilter accepted rate %v", rate)
+		}
+	}
+}
+
+func TestReadFilterCorrupted(t *testing.T) {
+	data := writeFilter(t, buildFilter(t, sha1Hex("password")+":10\n", 1))
+
+	flipped := bytes.Clone(data)
+	flipped[headerSize] ^= 0x01
+	if _, err := ReadFilter(bytes.NewReader(flipped)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("ReadFilter with a flipped bit = %v, want ErrChecksum", err)
+	}
+
+	badCRC := bytes.Clone(data)
+	badCRC[len(badCRC)-1] ^= 0xff
+	if _, err := ReadFilter(bytes.NewReader(badCRC)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("ReadFilter with a wrong checksum = %v, want ErrChecksum", err)
+	}
+
+	badMagic := bytes.Clone(data)
+	badMagic[7] = '9'
+	if _, err := ReadFilter(bytes.NewReader(badMagic)); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("ReadFilter with another format version = %v, want ErrInvalidFilter", err)
+	}
+
+	noHashes := bytes.Clone(data)
+	copy(noHashes[8:12], []byte{0, 0, 0, 0})
+	if _, err := ReadFilter(bytes.NewReader(noHashes)); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("ReadFilter with zero hashes = %v, want ErrInvalidFilter", err)
+	}
+
+	for _, size := range []int{0, headerSize - 1, headerSize + 3, len(data) - 1} {
+		if _, err := ReadFilter(bytes.NewReader(data[:size])); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ReadFilter of %d of %d bytes = %v, want ErrInvalidFilter", size, len(data), err)
+		}
+	}
+}
+
+func TestOpenFilterTruncated(t *testing.T) {
+	data := writeFilter(t, buildFilter(t, sha1Hex("password")+":10\n", 1))
+	dir := t.TempDir()
+
+	for name, content := range map[string][]byte{
+		"truncated": data[:len(data)-8],
+		"appended":  append(bytes.Clone(data), 0),
+		"header":    data[:headerSize-4],
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".bloom")
+			if err := os.WriteFile(path, content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := OpenFilter(path); !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("OpenFilter = %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
+
+func TestReadHashListErrors(t *testing.T) {
+	tests := map[string]string{
+		"short hash":     "5BAA61E4C9B93F3F0682250B6CF8331B7EE68F:3\n",
+		"not hex":        strings.Repeat("Z", 40) + ":3\n",
+		"bad count":      sha1Hex("password") + ":many\n",
+		"negative count": sha1Hex("password") + ":-1\n",
+	}
+	for name, list := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := ReadHashList(strings.NewReader(list), 0, func([sha1.Size]byte) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("ReadHashList = %v, want an error on line 1", err)
+			}
+		})
+	}
+
+	stop := errors.New("stop")
+	err := ReadHashList(strings.NewReader(sha1Hex("a")+"\n"+sha1Hex("b")+"\n"), 0, func([sha1.Size]byte) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("ReadHashList did not return the callback error: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ReadHashList разбирает список SHA-1 в формате Have I Been Pwned: по строке
+// "HASH:COUNT", где HASH - 40 hex-символов в любом регистре, а COUNT - сколько
+// раз пароль встречался в утечках. Строки без COUNT считаются встреченными
+// один раз. Для каждого хеша с COUNT не меньше minCount вызывается fn;
+// ошибка fn прерывает чтение.
+func ReadHashList(r io.Reader, minCount int, fn func(digest [sha1.Size]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		hash, countText, hasCount := bytes.Cut(text, []byte(":"))
+		count := 1
+		if hasCount {
+			n, err := strconv.Atoi(string(countText))
+			if err != nil || n < 0 {
+				return fmt.Errorf("line %d: invalid count %q", line, countText)
+			}
+			count = n
+		}
+		if count < minCount {
+			continue
+		}
+
+		var digest [sha1.Size]byte
+		if len(hash) != hex.EncodedLen(sha1.Size) {
+			return fmt.Errorf("line %d: expected %d hex characters of SHA-1, got %q", line, hex.EncodedLen(sha1.Size), hash)
+		}
+		if _, err := hex.Decode(digest[:], hash); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := fn(digest); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,95 @@
+// Package canonical приводит логины, email и игровые фамилии к каноническому
+// виду, по которому они ищутся и проверяются на уникальность: "Player" и
+// "player", "John@Mail.ru" и "john@mail.ru" - один и тот же аккаунт. Сами
+// значения хранятся так, как их ввел пользователь.
+package canonical
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Login возвращает канонический логин: без пробелов по краям, в форме NFKC
+// (полноширинные и составные символы заменены обычными) и без регистра
+func Login(login string) string {
+	return fold(login)
+}
+
+// EmailRules - особенности почтовых сервисов, которые учитываются в
+// каноническом email. Домены указываются в нижнем регистре, "*" - любой домен.
+// Нулевое значение сравнивает адреса только без учета регистра.
+type EmailRules struct {
+	// IgnoreDotsDomains - домены, где точки в имени ящика не различают адреса:
+	// j.o.h.n@gmail.com доставляется в john@gmail.com
+	IgnoreDotsDomains []string
+	// StripPlusDomains - домены, где часть имени после + - метка для
+	// фильтров: john+loil@gmail.com доставляется в john@gmail.com
+	StripPlusDomains []string
+}
+
+// Email возвращает канонический email. Имя ящика по RFC 5321 может
+// различаться регистром, но ни один распространенный сервис этим не
+// пользуется, поэтому регистр не учитывается во всем адресе.
+func (r EmailRules) Email(email string) string {
+	email = fold(email)
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	if matchDomain(r.StripPlusDomains, domain) {
+		if plus := strings.IndexByte(local, '+'); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if matchDomain(r.IgnoreDotsDomains, domain) {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+// Surname возвращает каноническую игровую фамилию: без регистра, а буквы
+// кириллицы, которые пишутся так же, как латинские, заменены латинскими.
+// "Ivanov" и "Ivаnov" с кириллической "а", "Coco" и "Сосо" выглядят в игре
+// одинаково, поэтому считаются одной фамилией. Сравниваются и строчные, и
+// заглавные формы: в фамилии первая буква заглавная ("Нот" и "Hot").
+func Surname(surname string) string {
+	return strings.Map(func(r rune) rune {
+		if latin, ok := homoglyphs[r]; ok {
+			return latin
+		}
+		return r
+	}, fold(surname))
+}
+
+// homoglyphs - буквы кириллицы после свертки регистра и латинские буквы,
+// от которых они неотличимы в строчном или заглавном виде
+var homoglyphs = map[rune]rune{
+	// ё не похожа на e, но вместо нее часто пишут е: "Ёлкин" и "Елкин" -
+	// одна фамилия
+	'ё': 'e',
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q', 'ԝ': 'w',
+}
+
+func matchDomain(domains []string, domain string) bool {
+	for _, d := range domains {
+		if d == "*" || d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// fold снимает регистр и нормализует. NFKC повторяется после свертки
+// регистра: она может дать ненормализованную последовательность.
+// cases.Caser хранит состояние, поэтому создается на каждый вызов.
+func fold(s string) string {
+	s = norm.NFKC.String(s)
+	s = cases.Fold().String(s)
+	return strings.TrimSpace(norm.NFKC.String(s))
+}
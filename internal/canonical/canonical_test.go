@@ -0,0 +1,111 @@
+package canonical
+
+import "testing"
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Player", "player"},
+		{"  PLAYER\t", "player"},
+		{"Игрок", "игрок"},
+		{"ЁЛКА", "ёлка"},
+		// Полноширинные буквы и цифры
+		{"Ｐｌａｙｅｒ１", "player1"},
+		// Лигатура и верхний индекс раскладываются NFKC
+		{"ﬁsh²", "fish2"},
+		// Составная й и готовая й - одна буква
+		{"Мой", "мой"},
+		// Свертка регистра, а не ToLower: ß и ss совпадают
+		{"Straße", "strasse"},
+		{"STRASSE", "strasse"},
+		// Гомоглифы в логинах не заменяются: это делает только Surname
+		{"Ivаnov", "ivаnov"},
+	}
+	for _, tt := range tests {
+		if got := Login(tt.in); got != tt.want {
+			t.Errorf("Login(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	gmail := EmailRules{
+		IgnoreDotsDomains: []string{"gmail.com", "googlemail.com"},
+		StripPlusDomains:  []string{"gmail.com"},
+	}
+	everywhere := EmailRules{StripPlusDomains: []string{"*"}}
+
+	tests := []struct {
+		name  string
+		rules EmailRules
+		in    string
+		want  string
+	}{
+		{"case", EmailRules{}, "John.Smith@Mail.RU", "john.smith@mail.ru"},
+		{"spaces", EmailRules{}, " john@mail.ru ", "john@mail.ru"},
+		{"trailing dot in domain", EmailRules{}, "john@mail.ru.", "john@mail.ru"},
+		{"fullwidth", EmailRules{}, "ｊｏｈｎ＠mail.ru", "john@mail.ru"},
+		{"no rules keep dots and plus", EmailRules{}, "j.o.h.n+loil@gmail.com", "j.o.h.n+loil@gmail.com"},
+		{"gmail dots", gmail, "J.o.h.n@Gmail.com", "john@gmail.com"},
+		{"gmail plus", gmail, "john+loil@gmail.com", "john@gmail.com"},
+		{"gmail dots and plus", gmail, "j.ohn+lo.il@gmail.com", "john@gmail.com"},
+		{"googlemail dots only", gmail, "j.ohn+loil@googlemail.com", "john+loil@googlemail.com"},
+		{"other domain untouched", gmail, "j.ohn+loil@mail.ru", "j.ohn+loil@mail.ru"},
+		{"leading plus kept", gmail, "+john@gmail.com", "+john@gmail.com"},
+		{"any domain", everywhere, "john+tag@mail.ru", "john@mail.ru"},
+		{"quoted at", EmailRules{}, `"a@b"@Mail.ru`, `"a@b"@mail.ru`},
+		{"not an email", gmail, "J.Ohn", "j.ohn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Email(tt.in); got != tt.want {
+				t.Errorf("Email(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSurname(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Ivanov", "IVANOV", true},
+		// Кириллическая а
+		{"Ivanov", "Ivаnov", true},
+		// Полностью кириллическое написание
+		{"Coco", "Сосо", true},
+		// Строчная н не похожа на h, но заглавные Н и H неотличимы
+		{"Hot", "Нот", true},
+		{"Ёлкин", "Елкин", true},
+		{"Ivanov", "Ivanova", false},
+		{"Petrov", "Петров", false},
+	}
+	for _, tt := range tests {
+		if same := Surname(tt.a) == Surname(tt.b); same != tt.same {
+			t.Errorf("Surname(%q) == Surname(%q) is %v, want %v (%q, %q)", tt.a, tt.b, same, tt.same, Surname(tt.a), Surname(tt.b))
+		}
+	}
+}
+
+// Канонический вид уже канонического значения не меняется: иначе ключи,
+// пересчитанные canonicalize apply, разошлись бы с ключами новых записей
+func TestIdempotent(t *testing.T) {
+	rules := EmailRules{IgnoreDotsDomains: []string{"*"}, StripPlusDomains: []string{"*"}}
+	inputs := []string{
+		"Player", " Ｐｌａｙｅｒ ", "Straße", "ﬁsh²", "Мой", "ǅemal", "ΣΊΣΥΦΟΣ",
+		"J.o.h.n+tag@Gmail.com.", "a+b+c@d.e", "Ivаnov", "Сосо", "Ёлкин",
+	}
+	for _, in := range inputs {
+		if once := Login(in); Login(once) != once {
+			t.Errorf("Login is not idempotent on %q: %q, then %q", in, once, Login(once))
+		}
+		if once := rules.Email(in); rules.Email(once) != once {
+			t.Errorf("Email is not idempotent on %q: %q, then %q", in, once, rules.Email(once))
+		}
+		if once := Surname(in); Surname(once) != once {
+			t.Errorf("Surname is not idempotent on %q: %q, then %q", in, once, Surname(once))
+		}
+	}
+}
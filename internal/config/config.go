@@ -1,29 +1,695 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/secrets"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
+// Режимы работы сервера
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// DefaultJWTSecret используется только для локальной разработки
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
 type Config struct {
-	ServerAddress string
-	DatabasePath  string
-	JWTSecret     string
+	// Environment: development (удобные значения по умолчанию) или production
+	// (сервер отказывается стартовать с небезопасными настройками)
+	Environment string `yaml:"environment"`
+
+	Server      ServerConfig      `yaml:"server"`
+	TLS         TLSConfig         `yaml:"tls"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Secrets     SecretsConfig     `yaml:"secrets"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	CORS        CORSConfig        `yaml:"cors"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Hashing     HashingConfig     `yaml:"hashing"`
+	Auth        AuthConfig        `yaml:"auth"`
+	GameSurname GameSurnameConfig `yaml:"game_surname"`
+	Names       NamesConfig       `yaml:"names"`
+	Password    PasswordConfig    `yaml:"password"`
+	Breach      BreachConfig      `yaml:"breach"`
+	Mail        MailConfig        `yaml:"mail"`
+	Log         LogConfig         `yaml:"log"`
+	I18n        I18nConfig        `yaml:"i18n"`
+	Debug       DebugConfig       `yaml:"debug"`
+}
+
+type ServerConfig struct {
+	Address           string        `yaml:"address"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// BehindProxy: TLS завершается на reverse proxy перед сервером
+	BehindProxy bool `yaml:"behind_proxy"`
+
+	// MinVersion: "1.2" или "1.3"
+	MinVersion string `yaml:"min_version"`
+	// CipherSuites - имена шифров из crypto/tls для TLS 1.2; пусто - набор Go по умолчанию
+	CipherSuites []string `yaml:"cipher_suites"`
+	// ReloadInterval - как часто проверять, не обновились ли файлы сертификата
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	// RedirectAddress - адрес HTTP-листенера, перенаправляющего на HTTPS; пусто - выключен
+	RedirectAddress string `yaml:"redirect_address"`
+
+	MTLS MTLSConfig `yaml:"mtls"`
+	// Admin - порт административных эндпоинтов (импорт, правила имен) со
+	// своим CA, чтобы сертификат игрового сервера не давал к ним доступа
+	Admin MTLSConfig `yaml:"admin"`
+}
+
+// MTLSConfig - отдельный порт с обязательным клиентским сертификатом
+// для внутренних эндпоинтов, которые вызывают игровые серверы
+type MTLSConfig struct {
+	Address      string `yaml:"address"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	// AllowedClients - CN или DNS-имена из SAN допустимых клиентских
+	// сертификатов; пусто - любой сертификат, подписанный client_ca_file
+	AllowedClients []string `yaml:"allowed_clients"`
+}
+
+// Enabled сообщает, что mTLS-листенер включен
+func (m MTLSConfig) Enabled() bool {
+	return m.Address != ""
+}
+
+// Enabled сообщает, что сервер сам обслуживает HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// DatabaseConfig выбирает хранилище: если задан DSN (postgres://...), используется
+// PostgreSQL, иначе SQLite-файл по пути Path
+type DatabaseConfig struct {
+	Path string `yaml:"path"`
+	DSN  string `yaml:"dsn"`
+	// QueryTimeout ограничивает время одного запроса к базе
+	QueryTimeout time.Duration    `yaml:"query_timeout"`
+	SQLite       SQLiteConfig     `yaml:"sqlite"`
+	Encryption   EncryptionConfig `yaml:"encryption"`
+	Canonical    CanonicalConfig  `yaml:"canonical"`
+}
+
+// CanonicalConfig - как email сводятся к каноническому виду для поиска и
+// проверки уникальности; регистр, пробелы по краям и NFKC не учитываются
+// всегда. Домены указываются в нижнем регистре, "*" - любой домен. После
+// изменения канонические значения пересчитывают командой canonicalize apply.
+type CanonicalConfig struct {
+	// IgnoreDotsDomains - домены, где точки в имени ящика не различают адреса (gmail.com)
+	IgnoreDotsDomains []string `yaml:"ignore_dots_domains"`
+	// StripPlusDomains - домены, где john+tag@ доставляется в john@
+	StripPlusDomains []string `yaml:"strip_plus_domains"`
+}
+
+// EncryptionConfig - шифрование персональных данных (email) в базе.
+// Ключ меняют так же, как перец: добавляют версию в Keys, делают ее
+// CurrentKey и перешифровывают записи командой piicrypt rotate.
+// Секреты принимают значение, file://путь или secret://имя.
+type EncryptionConfig struct {
+	// CurrentKey - версия ключа для новых записей; пусто - шифрование выключено
+	CurrentKey string            `yaml:"current_key"`
+	Keys       map[string]string `yaml:"keys"`
+	// IndexKey - секрет слепого индекса, по которому ищется email и
+	// проверяется его уникальность
+	IndexKey string `yaml:"index_key"`
+	// AllowUnencrypted разрешает production без шифрования - на время, пока
+	// ключи не заведены и piicrypt encrypt не прогнан по существующим записям
+	AllowUnencrypted bool `yaml:"allow_unencrypted"`
+}
+
+// Enabled сообщает, что персональные данные шифруются
+func (e EncryptionConfig) Enabled() bool {
+	return e.CurrentKey != ""
+}
+
+// SQLiteConfig - прагмы SQLite и размер пула чтения. Запись всегда идет через
+// одно соединение, если max_read_conns больше нуля.
+type SQLiteConfig struct {
+	JournalMode  string        `yaml:"journal_mode"`
+	Synchronous  string        `yaml:"synchronous"`
+	BusyTimeout  time.Duration `yaml:"busy_timeout"`
+	ForeignKeys  bool          `yaml:"foreign_keys"`
+	MaxReadConns int           `yaml:"max_read_conns"`
+}
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Driver возвращает имя выбранного хранилища
+func (d DatabaseConfig) Driver() string {
+	if d.DSN != "" {
+		return DriverPostgres
+	}
+	return DriverSQLite
+}
+
+// SecretsConfig описывает зашифрованный файл секретов, на записи которого
+// можно ссылаться как secret://имя
+type SecretsConfig struct {
+	File string `yaml:"file"`
+	// MasterKey обычно задается ссылкой file://, чтобы ключ не попадал в окружение
+	MasterKey string `yaml:"master_key"`
+}
+
+// JWTConfig можно менять без перезапуска (SIGHUP), чтобы ротировать секреты.
+// Secret и PreviousSecrets принимают значение, file://путь или secret://имя;
+// после загрузки в них лежат уже прочитанные значения.
+type JWTConfig struct {
+	// Secret подписывает новые токены
+	Secret string `yaml:"secret"`
+	// PreviousSecrets принимаются при проверке токенов на время ротации
+	PreviousSecrets []string      `yaml:"previous_secrets"`
+	TTL             time.Duration `yaml:"ttl"`
+}
+
+// VerificationKeys возвращает все секреты, которыми может быть подписан валидный токен
+func (j JWTConfig) VerificationKeys() []string {
+	return append([]string{j.Secret}, j.PreviousSecrets...)
+}
+
+// CORSConfig можно менять без перезапуска (SIGHUP)
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// RateLimitConfig ограничивает частоту запросов к публичным эндпоинтам с одного IP.
+// Можно менять без перезапуска (SIGHUP)
+type RateLimitConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	RequestsPerMinute int  `yaml:"requests_per_minute"`
+	Burst             int  `yaml:"burst"`
+	// TrustedProxies - адреса и подсети (CIDR) reverse proxy. Только от них
+	// принимаются X-Forwarded-For и X-Real-IP; без них все клиенты за proxy
+	// делят один лимит
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
-func Load() (*Config, error) {
-	// Значения по умолчанию
-	cfg := &Config{
-		ServerAddress: getEnv("SERVER_ADDRESS", ":8081"),
-		DatabasePath:  getEnv("DATABASE_PATH", "./auth.db"),
-		JWTSecret:     getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+// TrustedProxyPrefixes разбирает trusted_proxies; одиночный адрес становится
+// подсетью из одного адреса. Некорректные значения отсеивает Validate.
+func (c RateLimitConfig) TrustedProxyPrefixes() []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
 	}
-	
+	return prefixes
+}
+
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// HashingConfig задает алгоритм хеширования паролей и ограничивает параллельное
+// хеширование. Требует перезапуска
+type HashingConfig struct {
+	// Algorithm - алгоритм новых хешей: argon2id или bcrypt. Хеши другого
+	// алгоритма или с другими параметрами пересчитываются при входе
+	Algorithm string         `yaml:"algorithm"`
+	Argon2id  Argon2idConfig `yaml:"argon2id"`
+	Bcrypt    BcryptConfig   `yaml:"bcrypt"`
+	// Workers - число воркеров; 0 - по числу процессоров (GOMAXPROCS)
+	Workers int `yaml:"workers"`
+	// QueueSize - сколько запросов может ждать воркера, остальные получают 503;
+	// 0 - по одному на воркер
+	QueueSize int `yaml:"queue_size"`
+	// Pepper - секреты вне базы, которыми пароль подписывается перед хешированием
+	Pepper PepperConfig `yaml:"pepper"`
+}
+
+// PepperConfig - версии перца. Хеш помнит версию, поэтому перец меняют так:
+// добавляют новую версию в Keys и делают ее Current; хеши пересчитываются
+// при входе, а старую версию убирают, когда на нее не ссылается ни один хеш.
+type PepperConfig struct {
+	// Current - версия для новых хешей; пусто - новые хеши без перца
+	Current string `yaml:"current"`
+	// Keys - перцы по версиям: значение, file://путь или secret://имя;
+	// после загрузки в них лежат уже прочитанные значения
+	Keys map[string]string `yaml:"keys"`
+}
+
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+type Argon2idConfig struct {
+	// MemoryKiB - память на одно хеширование в КиБ
+	MemoryKiB   uint32 `yaml:"memory_kib"`
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
+}
+
+type BcryptConfig struct {
+	Cost int `yaml:"cost"`
+}
+
+// AuthConfig - как пользователи входят в аккаунт. Можно менять без перезапуска (SIGHUP)
+type AuthConfig struct {
+	// LoginByGameSurname разрешает входить по игровой фамилии, если
+	// идентификатор не совпал ни с одним логином
+	LoginByGameSurname bool `yaml:"login_by_game_surname"`
+	// HideRegistrationConflicts отвечает на регистрацию одинаково, создан
+	// аккаунт или логин, фамилия или email уже заняты, чтобы по ответу нельзя
+	// было проверить, зарегистрирован ли кто-то. Результат приходит письмом
+	// на указанный email; токен при регистрации не выдается.
+	HideRegistrationConflicts bool `yaml:"hide_registration_conflicts"`
+}
+
+// GameSurnameConfig - из каких букв может состоять новая игровая фамилия.
+// Можно менять без перезапуска (SIGHUP); уже занятые фамилии не проверяются
+type GameSurnameConfig struct {
+	// Alphabets - допустимые алфавиты: latin, cyrillic. Фамилия целиком
+	// пишется буквами одного из них
+	Alphabets []string `yaml:"alphabets"`
+}
+
+// Алфавиты игровых фамилий
+const (
+	AlphabetLatin    = "latin"
+	AlphabetCyrillic = "cyrillic"
+)
+
+// NamesConfig - проверка логинов и игровых фамилий по спискам служебных
+// имен и брани и правилам администраторов. Можно менять без перезапуска (SIGHUP);
+// уже занятые имена не проверяются
+type NamesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// RefreshInterval - как часто перечитывать правила администраторов из базы
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// PasswordConfig - требования к новым паролям при регистрации и смене пароля.
+// Можно менять без перезапуска (SIGHUP)
+type PasswordConfig struct {
+	MinLength int `yaml:"min_length"`
+	// MaxBytes ограничивает длину, чтобы хеширование огромных строк не стало способом DoS
+	MaxBytes int `yaml:"max_bytes"`
+	// MinClasses - сколько видов символов нужно: строчные, заглавные, цифры, прочие (0-4)
+	MinClasses int `yaml:"min_classes"`
+	// MaxRepeat - сколько раз подряд может повториться символ или фрагмент; 0 - без ограничения
+	MaxRepeat int `yaml:"max_repeat"`
+	// DisallowUserInputs запрещает логин, email и игровую фамилию внутри пароля
+	DisallowUserInputs bool `yaml:"disallow_user_inputs"`
+	// MinScore - минимальная оценка стойкости от 0 (подбирается мгновенно) до 4
+	MinScore int `yaml:"min_score"`
+}
+
+// BreachConfig - офлайн-проверка паролей по базе утечек. Фильтр собирается
+// командой breachfilter из списка Have I Been Pwned. Требует перезапуска
+type BreachConfig struct {
+	// FilterFile - путь к фильтру; пусто - проверка выключена. Пароли из
+	// фильтра отклоняются при регистрации и смене пароля
+	FilterFile string `yaml:"filter_file"`
+	// FlagOnLogin проверяет пароль при каждом входе и помечает аккаунт,
+	// чтобы клиент предложил сменить пароль
+	FlagOnLogin bool `yaml:"flag_on_login"`
+}
+
+// Enabled сообщает, что проверка по базе утечек включена
+func (b BreachConfig) Enabled() bool {
+	return b.FilterFile != ""
+}
+
+// MailConfig - отправка писем пользователям. Требует перезапуска
+type MailConfig struct {
+	// SMTPAddress - host:port почтового сервера; пусто - письма пишутся в лог
+	SMTPAddress string `yaml:"smtp_address"`
+	From        string `yaml:"from"`
+	Username    string `yaml:"username"`
+	// Password принимает значение, file://путь или secret://имя
+	Password string `yaml:"password"`
+}
+
+// Enabled сообщает, что письма отправляются, а не пишутся в лог
+func (m MailConfig) Enabled() bool {
+	return m.SMTPAddress != ""
+}
+
+// LogConfig можно менять без перезапуска (SIGHUP)
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+// I18nConfig можно менять без перезапуска (SIGHUP)
+type I18nConfig struct {
+	// DefaultLocale используется, если ни Accept-Language, ни настройки пользователя не подходят
+	DefaultLocale string `yaml:"default_locale"`
+}
+
+// DebugConfig включает отладочные эндпоинты, недопустимые в production
+type DebugConfig struct {
+	// Pprof публикует net/http/pprof на /debug/pprof/
+	Pprof bool `yaml:"pprof"`
+}
+
+// IsProduction сообщает, что сервер запущен в production-режиме
+func (c *Config) IsProduction() bool {
+	return strings.EqualFold(c.Environment, EnvProduction)
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Environment: EnvDevelopment,
+		Server: ServerConfig{
+			Address:           ":8081",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		TLS: TLSConfig{
+			MinVersion:     "1.2",
+			ReloadInterval: time.Minute,
+		},
+		Database: DatabaseConfig{
+			Path:         "./auth.db",
+			QueryTimeout: 5 * time.Second,
+			SQLite: SQLiteConfig{
+				JournalMode:  "wal",
+				Synchronous:  "normal",
+				BusyTimeout:  5 * time.Second,
+				ForeignKeys:  true,
+				MaxReadConns: 4,
+			},
+		},
+		JWT: JWTConfig{
+			Secret: DefaultJWTSecret,
+			TTL:    24 * time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			RequestsPerMinute: 30,
+			Burst:             10,
+		},
+		Hashing: HashingConfig{
+			Algorithm: HashArgon2id,
+			Argon2id: Argon2idConfig{
+				MemoryKiB:   64 * 1024,
+				Iterations:  3,
+				Parallelism: 2,
+				SaltLength:  16,
+				KeyLength:   32,
+			},
+			Bcrypt: BcryptConfig{
+				Cost: bcrypt.DefaultCost,
+			},
+			QueueSize: 64,
+		},
+		GameSurname: GameSurnameConfig{
+			Alphabets: []string{AlphabetLatin},
+		},
+		Names: NamesConfig{
+			Enabled:         true,
+			RefreshInterval: time.Minute,
+		},
+		Password: PasswordConfig{
+			MinLength:          8,
+			MaxBytes:           1024,
+			MaxRepeat:          3,
+			DisallowUserInputs: true,
+			MinScore:           2,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		I18n: I18nConfig{
+			DefaultLocale: i18n.Russian,
+		},
+	}
+}
+
+// Load собирает конфигурацию: значения по умолчанию, затем файл (если путь задан),
+// затем переменные окружения. Результат проверяется целиком.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ошибки разбора переменных окружения показываем вместе с остальными проблемами
+	problems := applyEnv(cfg)
+	problems = append(problems, resolveSecrets(cfg)...)
+	if err := cfg.Validate(); err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, err
+		}
+		problems = append(problems, validationErr.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	if cfg.IsProduction() {
+		if violations := cfg.ProductionViolations(); len(violations) > 0 {
+			return nil, &ProductionError{Violations: violations}
+		}
+	}
+
 	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	// Неизвестные ключи считаем ошибкой, чтобы опечатки не проходили незамеченными
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
 	}
-	return defaultValue
-}
\ No newline at end of file
+
+	return nil
+}
+
+// applyEnv переопределяет значения из переменных окружения
+func applyEnv(cfg *Config) []string {
+	var problems []string
+
+	setString := func(key string, dst *string) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			*dst = value
+		}
+	}
+	setDuration := func(key string, dst *time.Duration) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid duration %q", key, value))
+				return
+			}
+			*dst = d
+		}
+	}
+	setInt := func(key string, dst *int) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid integer %q", key, value))
+				return
+			}
+			*dst = n
+		}
+	}
+	setBool := func(key string, dst *bool) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid boolean %q", key, value))
+				return
+			}
+			*dst = b
+		}
+	}
+	setList := func(key string, dst *[]string) {
+		if value, ok := os.LookupEnv(key); ok && value != "" {
+			*dst = splitList(value)
+		}
+	}
+
+	setString("APP_ENV", &cfg.Environment)
+
+	setString("SERVER_ADDRESS", &cfg.Server.Address)
+	setDuration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	setDuration("SERVER_READ_HEADER_TIMEOUT", &cfg.Server.ReadHeaderTimeout)
+	setDuration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	setDuration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	setDuration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+
+	setString("TLS_CERT_FILE", &cfg.TLS.CertFile)
+	setString("TLS_KEY_FILE", &cfg.TLS.KeyFile)
+	setBool("TLS_BEHIND_PROXY", &cfg.TLS.BehindProxy)
+	setString("TLS_MIN_VERSION", &cfg.TLS.MinVersion)
+	setList("TLS_CIPHER_SUITES", &cfg.TLS.CipherSuites)
+	setDuration("TLS_RELOAD_INTERVAL", &cfg.TLS.ReloadInterval)
+	setString("TLS_REDIRECT_ADDRESS", &cfg.TLS.RedirectAddress)
+	setString("MTLS_ADDRESS", &cfg.TLS.MTLS.Address)
+	setString("MTLS_CLIENT_CA_FILE", &cfg.TLS.MTLS.ClientCAFile)
+	setList("MTLS_ALLOWED_CLIENTS", &cfg.TLS.MTLS.AllowedClients)
+	setString("ADMIN_ADDRESS", &cfg.TLS.Admin.Address)
+	setString("ADMIN_CLIENT_CA_FILE", &cfg.TLS.Admin.ClientCAFile)
+	setList("ADMIN_ALLOWED_CLIENTS", &cfg.TLS.Admin.AllowedClients)
+
+	setString("DATABASE_PATH", &cfg.Database.Path)
+	setString("DATABASE_DSN", &cfg.Database.DSN)
+	setDuration("DATABASE_QUERY_TIMEOUT", &cfg.Database.QueryTimeout)
+	setString("SQLITE_JOURNAL_MODE", &cfg.Database.SQLite.JournalMode)
+	setString("SQLITE_SYNCHRONOUS", &cfg.Database.SQLite.Synchronous)
+	setDuration("SQLITE_BUSY_TIMEOUT", &cfg.Database.SQLite.BusyTimeout)
+	setBool("SQLITE_FOREIGN_KEYS", &cfg.Database.SQLite.ForeignKeys)
+	setInt("SQLITE_MAX_READ_CONNS", &cfg.Database.SQLite.MaxReadConns)
+	setString("DATABASE_ENCRYPTION_CURRENT_KEY", &cfg.Database.Encryption.CurrentKey)
+	setString("DATABASE_ENCRYPTION_INDEX_KEY", &cfg.Database.Encryption.IndexKey)
+	setBool("DATABASE_ENCRYPTION_ALLOW_UNENCRYPTED", &cfg.Database.Encryption.AllowUnencrypted)
+
+	setString("SECRETS_FILE", &cfg.Secrets.File)
+	setString("SECRETS_MASTER_KEY", &cfg.Secrets.MasterKey)
+
+	setString("JWT_SECRET", &cfg.JWT.Secret)
+	setList("JWT_PREVIOUS_SECRETS", &cfg.JWT.PreviousSecrets)
+	setDuration("JWT_TTL", &cfg.JWT.TTL)
+
+	setList("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+
+	setBool("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	setList("RATE_LIMIT_TRUSTED_PROXIES", &cfg.RateLimit.TrustedProxies)
+	setString("HASHING_ALGORITHM", &cfg.Hashing.Algorithm)
+	setInt("HASHING_WORKERS", &cfg.Hashing.Workers)
+	setInt("HASHING_QUEUE_SIZE", &cfg.Hashing.QueueSize)
+	setString("HASHING_PEPPER_CURRENT", &cfg.Hashing.Pepper.Current)
+	setBool("AUTH_LOGIN_BY_GAME_SURNAME", &cfg.Auth.LoginByGameSurname)
+	setBool("AUTH_HIDE_REGISTRATION_CONFLICTS", &cfg.Auth.HideRegistrationConflicts)
+	setList("GAME_SURNAME_ALPHABETS", &cfg.GameSurname.Alphabets)
+	setBool("NAMES_ENABLED", &cfg.Names.Enabled)
+	setDuration("NAMES_REFRESH_INTERVAL", &cfg.Names.RefreshInterval)
+	setString("MAIL_SMTP_ADDRESS", &cfg.Mail.SMTPAddress)
+	setString("MAIL_FROM", &cfg.Mail.From)
+	setString("MAIL_USERNAME", &cfg.Mail.Username)
+	setString("MAIL_PASSWORD", &cfg.Mail.Password)
+	setInt("PASSWORD_MIN_LENGTH", &cfg.Password.MinLength)
+	setInt("PASSWORD_MIN_CLASSES", &cfg.Password.MinClasses)
+	setInt("PASSWORD_MIN_SCORE", &cfg.Password.MinScore)
+	setString("BREACH_FILTER_FILE", &cfg.Breach.FilterFile)
+	setBool("BREACH_FLAG_ON_LOGIN", &cfg.Breach.FlagOnLogin)
+	setInt("RATE_LIMIT_REQUESTS_PER_MINUTE", &cfg.RateLimit.RequestsPerMinute)
+	setInt("RATE_LIMIT_BURST", &cfg.RateLimit.Burst)
+
+	setString("LOG_LEVEL", &cfg.Log.Level)
+
+	setString("DEFAULT_LOCALE", &cfg.I18n.DefaultLocale)
+
+	setBool("DEBUG_PPROF", &cfg.Debug.Pprof)
+
+	return problems
+}
+
+// resolveSecrets заменяет ссылки file:// и secret:// на значения секретов
+func resolveSecrets(cfg *Config) []string {
+	var problems []string
+
+	var store *secrets.Store
+	if cfg.Secrets.File != "" {
+		masterKey, err := secrets.Resolve(cfg.Secrets.MasterKey, nil)
+		if err != nil {
+			return append(problems, fmt.Sprintf("secrets.master_key: %v", err))
+		}
+		if masterKey == "" {
+			return append(problems, "secrets.master_key: required when secrets.file is set")
+		}
+		store, err = secrets.OpenStore(cfg.Secrets.File, []byte(masterKey))
+		if err != nil {
+			return append(problems, fmt.Sprintf("secrets.file: %v", err))
+		}
+	}
+
+	resolve := func(field string, dst *string) {
+		value, err := secrets.Resolve(*dst, store)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+			return
+		}
+		*dst = value
+	}
+
+	resolve("database.dsn", &cfg.Database.DSN)
+	resolve("database.encryption.index_key", &cfg.Database.Encryption.IndexKey)
+	cfg.Database.Encryption.Keys = resolveMap("database.encryption.keys", cfg.Database.Encryption.Keys, resolve)
+	resolve("jwt.secret", &cfg.JWT.Secret)
+	resolve("mail.password", &cfg.Mail.Password)
+	cfg.Hashing.Pepper.Keys = resolveMap("hashing.pepper.keys", cfg.Hashing.Pepper.Keys, resolve)
+	// Копируем срез, чтобы не изменить значения по умолчанию или из другой конфигурации
+	cfg.JWT.PreviousSecrets = append([]string(nil), cfg.JWT.PreviousSecrets...)
+	for i := range cfg.JWT.PreviousSecrets {
+		resolve(fmt.Sprintf("jwt.previous_secrets[%d]", i), &cfg.JWT.PreviousSecrets[i])
+	}
+
+	return problems
+}
+
+// resolveMap разрешает ссылки в значениях карты. Возвращает копию, чтобы не
+// изменить значения по умолчанию или из другой конфигурации
+func resolveMap(field string, values map[string]string, resolve func(field string, dst *string)) map[string]string {
+	resolved := make(map[string]string, len(values))
+	for name, value := range values {
+		resolve(fmt.Sprintf("%s[%s]", field, name), &value)
+		resolved[name] = value
+	}
+	return resolved
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Manager хранит текущую конфигурацию и умеет перечитывать ее по SIGHUP.
+// При перезагрузке применяются только безопасные секции (cors, rate_limit, log, i18n,
+// а также jwt и secrets - для ротации секретов),
+// остальные изменения требуют перезапуска сервера. Новая конфигурация проходит
+// те же проверки, что и при старте, включая production-режим.
+type Manager struct {
+	path    string
+	current atomic.Pointer[Config]
+
+	mu        sync.Mutex
+	listeners []func(*Config)
+}
+
+// NewManager загружает конфигурацию из файла path (может быть пустым) и переменных окружения
+func NewManager(path string) (*Manager, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{path: path}
+	m.current.Store(cfg)
+	return m, nil
+}
+
+// Get возвращает текущую конфигурацию. Возвращаемое значение нельзя изменять.
+func (m *Manager) Get() *Config {
+	return m.current.Load()
+}
+
+// OnReload регистрирует функцию, которая вызывается после успешной перезагрузки
+func (m *Manager) OnReload(fn func(*Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Reload перечитывает конфигурацию. Если новая конфигурация невалидна,
+// текущая остается без изменений. Возвращает список секций, изменения в которых
+// проигнорированы до перезапуска.
+func (m *Manager) Reload() (ignored []string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loaded, err := Load(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("reload config: %w", err)
+	}
+
+	old := m.current.Load()
+	next := *old
+
+	// Безопасные для горячей замены секции
+	next.CORS = loaded.CORS
+	next.RateLimit = loaded.RateLimit
+	next.Log = loaded.Log
+	next.I18n = loaded.I18n
+	next.Auth = loaded.Auth
+	next.GameSurname = loaded.GameSurname
+	next.Names = loaded.Names
+	next.Password = loaded.Password
+	next.Secrets = loaded.Secrets
+	next.JWT = loaded.JWT
+
+	if loaded.Environment != old.Environment {
+		ignored = append(ignored, "environment")
+	}
+	if !reflect.DeepEqual(loaded.Server, old.Server) {
+		ignored = append(ignored, "server")
+	}
+	if !reflect.DeepEqual(loaded.Database, old.Database) {
+		ignored = append(ignored, "database")
+	}
+	if !reflect.DeepEqual(loaded.TLS, old.TLS) {
+		ignored = append(ignored, "tls")
+	}
+	if !reflect.DeepEqual(loaded.Hashing, old.Hashing) {
+		ignored = append(ignored, "hashing")
+	}
+	if loaded.Breach != old.Breach {
+		ignored = append(ignored, "breach")
+	}
+	if loaded.Mail != old.Mail {
+		ignored = append(ignored, "mail")
+	}
+	if !reflect.DeepEqual(loaded.Debug, old.Debug) {
+		ignored = append(ignored, "debug")
+	}
+
+	m.current.Store(&next)
+	for _, fn := range m.listeners {
+		fn(&next)
+	}
+
+	return ignored, nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strings"
+)
+
+// MinProductionSecretLength минимальная длина JWT-секрета в production (256 бит для HS256)
+const MinProductionSecretLength = 32
+
+// ProductionError перечисляет все нарушения, из-за которых production-режим не запускается
+type ProductionError struct {
+	Violations []string
+}
+
+func (e *ProductionError) Error() string {
+	var b strings.Builder
+	b.WriteString("production safety check failed, fix the following before starting:")
+	for _, violation := range e.Violations {
+		b.WriteString("\n  [ ] ")
+		b.WriteString(violation)
+	}
+	return b.String()
+}
+
+// ProductionViolations проверяет настройки, недопустимые в production.
+// В development-режиме эти же значения разрешены ради удобства.
+func (c *Config) ProductionViolations() []string {
+	var violations []string
+
+	switch {
+	case c.JWT.Secret == DefaultJWTSecret:
+		violations = append(violations, "jwt.secret: replace the built-in default secret")
+	case len(c.JWT.Secret) < MinProductionSecretLength:
+		violations = append(violations, "jwt.secret: use at least 32 bytes of random data")
+	}
+	for _, secret := range c.JWT.PreviousSecrets {
+		if len(secret) < MinProductionSecretLength {
+			violations = append(violations, "jwt.previous_secrets: every accepted secret must be at least 32 bytes")
+			break
+		}
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			violations = append(violations, "cors.allowed_origins: list explicit origins instead of \"*\"")
+			break
+		}
+	}
+
+	if !c.TLS.Enabled() && !c.TLS.BehindProxy {
+		violations = append(violations, "tls: set cert_file/key_file, or behind_proxy if a reverse proxy terminates TLS")
+	}
+
+	if !c.Database.Encryption.Enabled() && !c.Database.Encryption.AllowUnencrypted {
+		violations = append(violations, "database.encryption: encrypt personal data at rest (current_key, keys, index_key), or set allow_unencrypted until piicrypt encrypt has run")
+	}
+
+	if c.Auth.HideRegistrationConflicts && !c.Mail.Enabled() {
+		violations = append(violations, "mail.smtp_address: auth.hide_registration_conflicts reports registration results by email, configure an SMTP server")
+	}
+
+	if c.Debug.Pprof {
+		violations = append(violations, "debug.pprof: disable debug endpoints")
+	}
+
+	return violations
+}
+
+// ProductionWarnings перечисляет проверки production, отключенные явно;
+// сервер запускается, но напоминает о них при каждом старте
+func (c *Config) ProductionWarnings() []string {
+	var warnings []string
+
+	if !c.Database.Encryption.Enabled() && c.Database.Encryption.AllowUnencrypted {
+		warnings = append(warnings, "database.encryption: personal data is stored unencrypted (allow_unencrypted), set current_key, keys and index_key and run piicrypt encrypt")
+	}
+
+	return warnings
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func setProductionEnv(t *testing.T) {
+	t.Setenv("APP_ENV", EnvProduction)
+	t.Setenv("JWT_SECRET", strings.Repeat("s", MinProductionSecretLength))
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://game.example.com")
+	t.Setenv("TLS_BEHIND_PROXY", "true")
+}
+
+func productionViolations(t *testing.T) []string {
+	t.Helper()
+	_, err := NewManager("")
+	var productionErr *ProductionError
+	if !errors.As(err, &productionErr) {
+		t.Fatalf("NewManager = %v, want ProductionError", err)
+	}
+	return productionErr.Violations
+}
+
+func TestProductionRequiresEncryption(t *testing.T) {
+	setProductionEnv(t)
+
+	violations := productionViolations(t)
+	if len(violations) != 1 || !strings.HasPrefix(violations[0], "database.encryption") {
+		t.Errorf("Violations = %q, want only database.encryption", violations)
+	}
+}
+
+// allow_unencrypted снимает требование явно, и сервер напоминает об этом
+func TestProductionAllowUnencryptedWarns(t *testing.T) {
+	setProductionEnv(t)
+	t.Setenv("DATABASE_ENCRYPTION_ALLOW_UNENCRYPTED", "true")
+
+	m, err := NewManager("")
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	warnings := m.Get().ProductionWarnings()
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "database.encryption") {
+		t.Errorf("ProductionWarnings = %q, want the database.encryption warning", warnings)
+	}
+}
+
+func TestProductionAllowUnencryptedKeepsOtherChecks(t *testing.T) {
+	setProductionEnv(t)
+	t.Setenv("DATABASE_ENCRYPTION_ALLOW_UNENCRYPTED", "true")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+
+	violations := productionViolations(t)
+	if len(violations) != 1 || !strings.HasPrefix(violations[0], "cors.allowed_origins") {
+		t.Errorf("Violations = %q, want only cors.allowed_origins", violations)
+	}
+}
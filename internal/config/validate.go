@@ -0,0 +1,348 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/tlsutil"
+	"LOIL-auth-server/internal/validation"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ValidationError содержит все найденные проблемы конфигурации сразу,
+// чтобы их можно было исправить за один проход
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+var validLogLevels = map[string]bool{
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+}
+
+// keyVersionPattern - версия перца или ключа шифрования записывается в хеш
+// или шифртекст между разделителями
+var keyVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,16}$`)
+
+// minKeyLength - перец или ключ короче 256 бит можно подобрать
+const minKeyLength = 32
+
+// Validate проверяет конфигурацию целиком
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !strings.EqualFold(c.Environment, EnvDevelopment) && !strings.EqualFold(c.Environment, EnvProduction) {
+		add("environment: %q must be %s or %s", c.Environment, EnvDevelopment, EnvProduction)
+	}
+
+	// Сервер
+	if c.Server.Address == "" {
+		add("server.address: must not be empty")
+	} else if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		add("server.address: %q is not a valid host:port", c.Server.Address)
+	}
+	if c.Server.ReadTimeout <= 0 {
+		add("server.read_timeout: must be positive")
+	}
+	if c.Server.ReadHeaderTimeout <= 0 {
+		add("server.read_header_timeout: must be positive")
+	}
+	if c.Server.WriteTimeout <= 0 {
+		add("server.write_timeout: must be positive")
+	}
+	if c.Server.IdleTimeout <= 0 {
+		add("server.idle_timeout: must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout: must be positive")
+	}
+
+	// TLS
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		add("tls: cert_file and key_file must be set together")
+	}
+	if _, err := tlsutil.ParseVersion(c.TLS.MinVersion); err != nil {
+		add("tls.min_version: %v", err)
+	}
+	if _, err := tlsutil.ParseCipherSuites(c.TLS.CipherSuites); err != nil {
+		add("tls.cipher_suites: %v", err)
+	}
+	if c.TLS.Enabled() && c.TLS.ReloadInterval <= 0 {
+		add("tls.reload_interval: must be positive")
+	}
+	if c.TLS.RedirectAddress != "" {
+		if !c.TLS.Enabled() {
+			add("tls.redirect_address: requires tls.cert_file and tls.key_file")
+		} else if _, _, err := net.SplitHostPort(c.TLS.RedirectAddress); err != nil {
+			add("tls.redirect_address: %q is not a valid host:port", c.TLS.RedirectAddress)
+		}
+	}
+	validateMTLS("tls.mtls", c.TLS, c.TLS.MTLS, add)
+	validateMTLS("tls.admin", c.TLS, c.TLS.Admin, add)
+	if c.TLS.Admin.Enabled() && c.TLS.MTLS.Enabled() {
+		if c.TLS.Admin.Address == c.TLS.MTLS.Address {
+			add("tls.admin.address: must differ from tls.mtls.address")
+		}
+		// С общим CA отличить администратора от игрового сервера можно только по имени
+		if c.TLS.Admin.ClientCAFile == c.TLS.MTLS.ClientCAFile && len(c.TLS.Admin.AllowedClients) == 0 {
+			add("tls.admin.allowed_clients: required when tls.admin shares client_ca_file with tls.mtls")
+		}
+	}
+
+	// База данных
+	if c.Database.DSN != "" {
+		dsn, err := url.Parse(c.Database.DSN)
+		if err != nil || (dsn.Scheme != "postgres" && dsn.Scheme != "postgresql") {
+			add("database.dsn: must be a postgres:// or postgresql:// URL")
+		}
+	} else if c.Database.Path == "" {
+		add("database.path: must not be empty")
+	}
+	if c.Database.QueryTimeout <= 0 {
+		add("database.query_timeout: must be positive")
+	}
+	if mode := c.Database.SQLite.JournalMode; mode != "" && !oneOf(strings.ToLower(mode), "delete", "truncate", "persist", "memory", "wal", "off") {
+		add("database.sqlite.journal_mode: unknown mode %q", mode)
+	}
+	if sync := c.Database.SQLite.Synchronous; sync != "" && !oneOf(strings.ToLower(sync), "off", "normal", "full", "extra") {
+		add("database.sqlite.synchronous: unknown value %q", sync)
+	}
+	if c.Database.SQLite.BusyTimeout < 0 {
+		add("database.sqlite.busy_timeout: must not be negative")
+	}
+	if c.Database.SQLite.MaxReadConns < 0 {
+		add("database.sqlite.max_read_conns: must not be negative")
+	}
+	validateKeys("database.encryption", c.Database.Encryption.CurrentKey, "current_key", c.Database.Encryption.Keys, add)
+	if c.Database.Encryption.Enabled() && len(c.Database.Encryption.IndexKey) < minKeyLength {
+		add("database.encryption.index_key: must be at least %d bytes when encryption is enabled", minKeyLength)
+	}
+	validateDomains("database.canonical.ignore_dots_domains", c.Database.Canonical.IgnoreDotsDomains, add)
+	validateDomains("database.canonical.strip_plus_domains", c.Database.Canonical.StripPlusDomains, add)
+
+	// Почта
+	if c.Mail.Enabled() {
+		if _, _, err := net.SplitHostPort(c.Mail.SMTPAddress); err != nil {
+			add("mail.smtp_address: %q is not a valid host:port", c.Mail.SMTPAddress)
+		}
+		if c.Mail.From == "" {
+			add("mail.from: required when mail.smtp_address is set")
+		}
+	}
+	if c.Mail.From != "" && validation.Email.Check("from", c.Mail.From) != nil {
+		add("mail.from: %q is not a valid email address", c.Mail.From)
+	}
+
+	// Хеширование паролей
+	switch c.Hashing.Algorithm {
+	case HashArgon2id:
+		argon := c.Hashing.Argon2id
+		if argon.MemoryKiB < 8*uint32(argon.Parallelism) || argon.Iterations == 0 || argon.Parallelism == 0 {
+			add("hashing.argon2id: memory_kib, iterations and parallelism must be positive, memory_kib at least 8*parallelism")
+		}
+		// Хеши с большими параметрами потом не пройдут проверку при входе
+		if argon.MemoryKiB > hashing.MaxArgon2Memory || argon.Iterations > hashing.MaxArgon2Iterations {
+			add("hashing.argon2id: memory_kib must be at most %d and iterations at most %d", hashing.MaxArgon2Memory, hashing.MaxArgon2Iterations)
+		}
+		if argon.SaltLength < 8 || argon.KeyLength < 16 {
+			add("hashing.argon2id: salt_length must be at least 8 and key_length at least 16")
+		}
+	case HashBcrypt:
+	default:
+		add("hashing.algorithm: %q must be %s or %s", c.Hashing.Algorithm, HashArgon2id, HashBcrypt)
+	}
+	if c.Hashing.Bcrypt.Cost < bcrypt.MinCost || c.Hashing.Bcrypt.Cost > bcrypt.MaxCost {
+		add("hashing.bcrypt.cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if c.Hashing.Workers < 0 {
+		add("hashing.workers: must not be negative")
+	}
+	if c.Hashing.QueueSize < 0 {
+		add("hashing.queue_size: must not be negative")
+	}
+	validateKeys("hashing.pepper", c.Hashing.Pepper.Current, "current", c.Hashing.Pepper.Keys, add)
+
+	// Проверка по базе утечек
+	if c.Breach.FlagOnLogin && !c.Breach.Enabled() {
+		add("breach.flag_on_login: requires breach.filter_file")
+	}
+
+	// JWT
+	if c.JWT.Secret == "" {
+		add("jwt.secret: must not be empty")
+	}
+	for i, secret := range c.JWT.PreviousSecrets {
+		if secret == "" {
+			add("jwt.previous_secrets[%d]: must not be empty", i)
+		}
+	}
+	if c.JWT.TTL <= 0 {
+		add("jwt.ttl: must be positive")
+	}
+
+	problems = append(problems, c.validateReloadable()...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateReloadable проверяет секции, которые можно менять через SIGHUP
+func (c *Config) validateReloadable() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// CORS
+	if len(c.CORS.AllowedOrigins) == 0 {
+		add("cors.allowed_origins: at least one origin is required")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			add("cors.allowed_origins: %q must be \"*\" or scheme://host[:port]", origin)
+		}
+	}
+
+	// Ограничение частоты запросов
+	if c.RateLimit.Enabled {
+		if c.RateLimit.RequestsPerMinute <= 0 {
+			add("rate_limit.requests_per_minute: must be positive when rate limiting is enabled")
+		}
+		if c.RateLimit.Burst <= 0 {
+			add("rate_limit.burst: must be positive when rate limiting is enabled")
+		}
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			add("rate_limit.trusted_proxies: %q must be an IP address or CIDR", proxy)
+		}
+	}
+
+	// Игровые фамилии
+	if len(c.GameSurname.Alphabets) == 0 {
+		add("game_surname.alphabets: at least one alphabet is required")
+	}
+	for i, alphabet := range c.GameSurname.Alphabets {
+		if !oneOf(alphabet, AlphabetLatin, AlphabetCyrillic) {
+			add("game_surname.alphabets: %q must be %s or %s", alphabet, AlphabetLatin, AlphabetCyrillic)
+		} else if slices.Contains(c.GameSurname.Alphabets[:i], alphabet) {
+			add("game_surname.alphabets: %q is listed twice", alphabet)
+		}
+	}
+
+	// Проверка имен
+	if c.Names.RefreshInterval <= 0 {
+		add("names.refresh_interval: must be positive")
+	}
+
+	// Политика паролей
+	if c.Password.MinLength < 1 {
+		add("password.min_length: must be positive")
+	}
+	if c.Password.MaxBytes < c.Password.MinLength {
+		add("password.max_bytes: must not be less than password.min_length")
+	}
+	if c.Password.MinClasses < 0 || c.Password.MinClasses > 4 {
+		add("password.min_classes: must be between 0 and 4")
+	}
+	if c.Password.MaxRepeat < 0 {
+		add("password.max_repeat: must not be negative")
+	}
+	if c.Password.MinScore < 0 || c.Password.MinScore > 4 {
+		add("password.min_score: must be between 0 and 4")
+	}
+
+	// Логирование
+	if !validLogLevels[strings.ToLower(c.Log.Level)] {
+		add("log.level: %q must be one of debug, info, warn, error", c.Log.Level)
+	}
+
+	// Локализация
+	if !i18n.Supported(c.I18n.DefaultLocale) {
+		add("i18n.default_locale: %q must be one of %s", c.I18n.DefaultLocale, strings.Join(i18n.Locales(), ", "))
+	}
+
+	return problems
+}
+
+// validateKeys проверяет версионированные секреты: перец паролей или ключи
+// шифрования. current - версия для новых данных, она должна быть среди keys
+func validateKeys(section, current, currentField string, keys map[string]string, add func(format string, args ...interface{})) {
+	// Версии по порядку, чтобы список проблем не менялся от запуска к запуску
+	for _, version := range slices.Sorted(maps.Keys(keys)) {
+		if !keyVersionPattern.MatchString(version) {
+			add("%s.keys: version %q must be 1-16 letters, digits, _ or -", section, version)
+		}
+		if len(keys[version]) < minKeyLength {
+			add("%s.keys[%s]: must be at least %d bytes", section, version, minKeyLength)
+		}
+	}
+	if current != "" {
+		if _, ok := keys[current]; !ok {
+			add("%s.%s: %q is not listed in %s.keys", section, currentField, current, section)
+		}
+	}
+}
+
+// validateMTLS проверяет листенер с обязательным клиентским сертификатом
+// (tls.mtls или tls.admin): он работает только вместе с основным TLS и
+// требует CA, которым подписаны сертификаты клиентов
+func validateMTLS(section string, tls TLSConfig, m MTLSConfig, add func(format string, args ...interface{})) {
+	if !m.Enabled() {
+		return
+	}
+	if !tls.Enabled() {
+		add("%s.address: requires tls.cert_file and tls.key_file", section)
+	}
+	if _, _, err := net.SplitHostPort(m.Address); err != nil {
+		add("%s.address: %q is not a valid host:port", section, m.Address)
+	}
+	if m.ClientCAFile == "" {
+		add("%s.client_ca_file: required when %s.address is set", section, section)
+	}
+}
+
+// validateDomains проверяет список почтовых доменов: канонический email
+// сравнивается с ними в нижнем регистре
+func validateDomains(field string, domains []string, add func(format string, args ...interface{})) {
+	for _, domain := range domains {
+		if domain == "*" {
+			continue
+		}
+		if domain == "" || domain != strings.ToLower(domain) || strings.ContainsAny(domain, "@ ") {
+			add("%s: %q must be a lowercase domain name or *", field, domain)
+		}
+	}
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
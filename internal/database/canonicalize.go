@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/gamesurname"
+)
+
+// CanonicalOptions управляет CanonicalizeUsers
+type CanonicalOptions struct {
+	// All пересчитывает все строки, а не только те, где ключей еще нет;
+	// нужен после изменения правил database.canonical или включения шифрования
+	All bool
+	// DryRun только считает, что нужно изменить, и ищет совпадения
+	DryRun bool
+	// BatchSize - сколько строк читается за раз; 0 - 500
+	BatchSize int
+}
+
+// Collision - пользователь, чей канонический логин, email или фамилия уже
+// принадлежит другому. Его ключ остается пустым: такой аккаунт находится только
+// по точному значению, пока одному из пользователей не сменят это поле.
+type Collision struct {
+	// Field - login, email или game_surname
+	Field  string `json:"field"`
+	UserID int    `json:"userId"`
+	// ExistingID - владелец ключа; 0, если его занял параллельный запрос
+	// и найти владельца не удалось
+	ExistingID int `json:"existingId"`
+}
+
+// CanonicalReport - итог CanonicalizeUsers. В режиме DryRun счетчики
+// показывают, сколько ключей было бы записано.
+type CanonicalReport struct {
+	Scanned int `json:"scanned"`
+	// Logins, Emails и GameSurnames - сколько строк получили новый ключ
+	// логина, email и фамилии
+	Logins       int         `json:"logins"`
+	Emails       int         `json:"emails"`
+	GameSurnames int         `json:"gameSurnames"`
+	Collisions   []Collision `json:"collisions,omitempty"`
+	// Changed - ID пользователей, сменивших логин, email или фамилию во время прохода;
+	// их подхватит повторный запуск
+	Changed []int `json:"changed,omitempty"`
+}
+
+type storedKeys struct {
+	id             int
+	login          string
+	loginCanonical sql.NullString
+	email          string
+	emailCanonical sql.NullString
+	emailIndex     sql.NullString
+
+	gameSurname          string
+	gameSurnameCanonical sql.NullString
+	gameSurnameLatin     sql.NullString
+}
+
+// keyAssignment - запись одного канонического ключа строки
+type keyAssignment struct {
+	field string
+	// key - значение ключа, по которому ищется его владелец
+	key string
+	// find пуст у неуникальных значений: их можно записать всегда
+	find       string
+	findArgs   []interface{}
+	update     string
+	updateArgs []interface{}
+}
+
+// CanonicalizeUsers заполняет канонические логины, email и фамилии, а также
+// фамилии латиницей: после миграций, которые добавили эти столбцы, и после
+// изменения правил. Строки, чей ключ уже
+// занят другим пользователем, не меняются и попадают в Collisions. Строки
+// обновляются, только если поле не изменилось с момента чтения,
+// поэтому проход можно запускать на работающем сервере.
+func (s *sqlStore) CanonicalizeUsers(ctx context.Context, opts CanonicalOptions) (CanonicalReport, error) {
+	var report CanonicalReport
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEncryptionBatch
+	}
+
+	// Без All читаются только строки без ключей: с шифрованием ключ email -
+	// слепой индекс, а открытого канонического email быть не должно
+	incomplete := "login_canonical IS NULL OR email_canonical IS NULL"
+	if s.opts.Fields != nil {
+		incomplete = "login_canonical IS NULL OR email_index IS NULL OR email_canonical IS NOT NULL"
+	}
+	incomplete += " OR game_surname_canonical IS NULL OR game_surname_latin IS NULL"
+	if opts.All {
+		incomplete = "1 = 1"
+	}
+	selectBatch := s.dialect.rebind(`SELECT id, login, login_canonical, email, email_canonical, email_index,
+			game_surname, game_surname_canonical, game_surname_latin
+		FROM users WHERE id > ? AND (` + incomplete + `) ORDER BY id LIMIT ?`)
+	findLogin := s.dialect.rebind("SELECT id FROM users WHERE login_canonical = ? AND id <> ?")
+	findEmail := s.dialect.rebind("SELECT id FROM users WHERE (email_canonical = ? OR email_index = ?) AND id <> ?")
+	updateLogin := s.dialect.rebind("UPDATE users SET login_canonical = ? WHERE id = ? AND login = ?")
+	updateEmail := s.dialect.rebind("UPDATE users SET email_canonical = ?, email_index = ? WHERE id = ? AND email = ?")
+	findGameSurname := s.dialect.rebind("SELECT id FROM users WHERE game_surname_canonical = ? AND id <> ?")
+	updateGameSurname := s.dialect.rebind("UPDATE users SET game_surname_canonical = ? WHERE id = ? AND game_surname = ?")
+	updateGameSurnameLatin := s.dialect.rebind("UPDATE users SET game_surname_latin = ? WHERE id = ? AND game_surname = ?")
+
+	// В режиме DryRun ключи не записываются, поэтому совпадения между
+	// строками одного прохода ищутся здесь
+	planned := map[string]map[string]int{"login": {}, "email": {}, "game_surname": {}}
+
+	lastID := 0
+	for {
+		batch, err := s.readKeys(ctx, selectBatch, lastID, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+		lastID = batch[len(batch)-1].id
+
+		for _, row := range batch {
+			report.Scanned++
+
+			var assignments []keyAssignment
+			if login := canonical.Login(row.login); !sameKey(row.loginCanonical, login) {
+				assignments = append(assignments, keyAssignment{
+					field: "login", key: login,
+					find: findLogin, findArgs: []interface{}{login, row.id},
+					update: updateLogin, updateArgs: []interface{}{login, row.id, row.login},
+				})
+			}
+
+			email, err := s.openEmail(row.email)
+			if err != nil {
+				return report, fmt.Errorf("user %d email: %w", row.id, err)
+			}
+			emailCanonical, emailIndex := s.emailKeys(email)
+			if !sameKey(row.emailCanonical, emailCanonical) || !sameKey(row.emailIndex, emailIndex) {
+				key := emailCanonical
+				if key == nil {
+					key = emailIndex
+				}
+				assignments = append(assignments, keyAssignment{
+					field: "email", key: key.(string),
+					find: findEmail, findArgs: []interface{}{emailCanonical, emailIndex, row.id},
+					update: updateEmail, updateArgs: []interface{}{emailCanonical, emailIndex, row.id, row.email},
+				})
+			}
+
+			if surname := canonical.Surname(row.gameSurname); !sameKey(row.gameSurnameCanonical, surname) {
+				assignments = append(assignments, keyAssignment{
+					field: "game_surname", key: surname,
+					find: findGameSurname, findArgs: []interface{}{surname, row.id},
+					update: updateGameSurname, updateArgs: []interface{}{surname, row.id, row.gameSurname},
+				})
+			}
+			// Форма латиницей не уникальна и пишется даже при совпадении фамилий
+			if latin := gamesurname.Transliterate(row.gameSurname); !sameKey(row.gameSurnameLatin, latin) {
+				assignments = append(assignments, keyAssignment{
+					field: "game_surname_latin", key: latin,
+					update: updateGameSurnameLatin, updateArgs: []interface{}{latin, row.id, row.gameSurname},
+				})
+			}
+
+			for _, a := range assignments {
+				existingID, err := s.assignKey(ctx, a, opts.DryRun, planned[a.field])
+				if errors.Is(err, ErrUserNotFound) {
+					if n := len(report.Changed); n == 0 || report.Changed[n-1] != row.id {
+						report.Changed = append(report.Changed, row.id)
+					}
+					continue
+				}
+				if err != nil {
+					return report, err
+				}
+				if existingID != 0 {
+					report.Collisions = append(report.Collisions, Collision{Field: a.field, UserID: row.id, ExistingID: max(existingID, 0)})
+					continue
+				}
+				if opts.DryRun && a.find != "" {
+					planned[a.field][a.key] = row.id
+				}
+				switch a.field {
+				case "login":
+					report.Logins++
+				case "email":
+					report.Emails++
+				case "game_surname":
+					report.GameSurnames++
+				}
+			}
+		}
+	}
+}
+
+// assignKey записывает ключ a. Возвращает ID пользователя, которому ключ
+// уже принадлежит (-1, если владельца найти не удалось), или
+// ErrUserNotFound, если строка изменилась с момента чтения.
+func (s *sqlStore) assignKey(ctx context.Context, a keyAssignment, dryRun bool, planned map[string]int) (int, error) {
+	if id, ok := planned[a.key]; ok {
+		return id, nil
+	}
+	existingID, err := s.keyOwner(ctx, a)
+	if err != nil || existingID != 0 || dryRun {
+		return existingID, err
+	}
+
+	result, err := s.db.ExecContext(ctx, a.update, a.updateArgs...)
+	if mapped := mapConstraintError(err); errors.Is(mapped, ErrLoginTaken) || errors.Is(mapped, ErrEmailTaken) || errors.Is(mapped, ErrGameSurnameTaken) {
+		// Ключ заняли между проверкой и записью
+		if existingID, err = s.keyOwner(ctx, a); err != nil || existingID != 0 {
+			return existingID, err
+		}
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, checkAffected(result)
+}
+
+func (s *sqlStore) keyOwner(ctx context.Context, a keyAssignment) (int, error) {
+	if a.find == "" {
+		return 0, nil
+	}
+	var id int
+	err := s.db.QueryRowContext(ctx, a.find, a.findArgs...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// sameKey сообщает, что в столбце уже записан ключ want; nil - NULL
+func sameKey(current sql.NullString, want interface{}) bool {
+	if want == nil {
+		return !current.Valid
+	}
+	return current.Valid && current.String == want.(string)
+}
+
+func (s *sqlStore) readKeys(ctx context.Context, query string, afterID, limit int) ([]storedKeys, error) {
+	rows, err := s.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []storedKeys
+	for rows.Next() {
+		var row storedKeys
+		if err := rows.Scan(&row.id, &row.login, &row.loginCanonical, &row.email, &row.emailCanonical, &row.emailIndex,
+			&row.gameSurname, &row.gameSurnameCanonical, &row.gameSurnameLatin); err != nil {
+			return nil, err
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/fieldcrypt"
+)
+
+// emailField - имя поля для шифрования и слепого индекса email
+const emailField = "users.email"
+
+// ErrEncryptionDisabled - операции с ключами шифрования без настроенных ключей
+var ErrEncryptionDisabled = errors.New("database encryption is not configured")
+
+// FieldCodecFrom готовит шифрование персональных данных из секции
+// database.encryption; если шифрование выключено, возвращает nil
+func FieldCodecFrom(cfg config.EncryptionConfig) (*fieldcrypt.Codec, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for version, key := range cfg.Keys {
+		keys[version] = []byte(key)
+	}
+	return fieldcrypt.New(cfg.CurrentKey, keys, []byte(cfg.IndexKey))
+}
+
+// sealEmail готовит email к записи: шифртекст, если шифрование включено,
+// иначе сам email
+func (s *sqlStore) sealEmail(email string) (string, error) {
+	if s.opts.Fields == nil {
+		return email, nil
+	}
+	return s.opts.Fields.Encrypt(emailField, email)
+}
+
+// emailKeys возвращает ключи поиска и уникальности email: канонический email
+// для email_canonical или, если шифрование включено, его слепой индекс для
+// email_index, чтобы открытый адрес не попадал в базу. Второй ключ - NULL.
+func (s *sqlStore) emailKeys(email string) (emailCanonical, index interface{}) {
+	key := s.opts.Emails.Email(email)
+	if s.opts.Fields == nil {
+		return key, nil
+	}
+	return nil, s.opts.Fields.BlindIndex(emailField, key)
+}
+
+// openEmail расшифровывает прочитанный email; незашифрованный возвращается как есть
+func (s *sqlStore) openEmail(stored string) (string, error) {
+	if s.opts.Fields == nil {
+		if fieldcrypt.IsEncrypted(stored) {
+			return "", fieldcrypt.ErrNotConfigured
+		}
+		return stored, nil
+	}
+	return s.opts.Fields.Decrypt(emailField, stored)
+}
+
+// EmailEncryptionOptions управляет EncryptEmails
+type EmailEncryptionOptions struct {
+	// Rotate перешифровывает email, зашифрованные не текущей версией ключа
+	Rotate bool
+	// DryRun только считает, что нужно изменить
+	DryRun bool
+	// BatchSize - сколько строк читается за раз; 0 - 500
+	BatchSize int
+}
+
+// EmailEncryptionReport - итог EncryptEmails. В режиме DryRun счетчики
+// показывают, сколько строк было бы изменено.
+type EmailEncryptionReport struct {
+	Scanned int `json:"scanned"`
+	// Encrypted - email, хранившиеся открыто
+	Encrypted int `json:"encrypted"`
+	// Reencrypted - email, перешифрованные текущим ключом
+	Reencrypted int `json:"reencrypted"`
+	// Reindexed - строки, у которых обновлен только слепой индекс
+	Reindexed int `json:"reindexed"`
+	// ByKey - сколько email зашифровано каждой версией ключа после прохода,
+	// а в режиме DryRun - сейчас
+	ByKey map[string]int `json:"byKey"`
+	// Conflicts - ID пользователей, чей email совпал с email другого
+	// пользователя; такие строки остаются как были
+	Conflicts []int `json:"conflicts,omitempty"`
+	// Changed - ID пользователей, сменивших email во время прохода;
+	// их подхватит повторный запуск
+	Changed []int `json:"changed,omitempty"`
+}
+
+const defaultEncryptionBatch = 500
+
+type storedEmail struct {
+	id        int
+	email     string
+	canonical sql.NullString
+	index     sql.NullString
+}
+
+// EncryptEmails шифрует email, которые хранятся открыто, заменяет открытый
+// канонический email слепым индексом и с Rotate перешифровывает email старых версий ключа. Строки
+// обрабатываются пачками и обновляются, только если email не изменился с
+// момента чтения, поэтому проход можно запускать на работающем сервере и
+// прерывать: повторный запуск продолжит с того же места.
+func (s *sqlStore) EncryptEmails(ctx context.Context, opts EmailEncryptionOptions) (EmailEncryptionReport, error) {
+	report := EmailEncryptionReport{ByKey: make(map[string]int)}
+	codec := s.opts.Fields
+	if codec == nil {
+		return report, ErrEncryptionDisabled
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultEncryptionBatch
+	}
+
+	selectBatch := s.dialect.rebind("SELECT id, email, email_canonical, email_index FROM users WHERE id > ? ORDER BY id LIMIT ?")
+	updateRow := s.dialect.rebind("UPDATE users SET email = ?, email_canonical = NULL, email_index = ? WHERE id = ? AND email = ?")
+
+	lastID := 0
+	for {
+		// Пачка читается целиком до обновлений: у SQLite запись идет через
+		// то же единственное соединение
+		batch, err := s.readEmails(ctx, selectBatch, lastID, batchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+		lastID = batch[len(batch)-1].id
+
+		for _, row := range batch {
+			report.Scanned++
+
+			email, err := s.openEmail(row.email)
+			if err != nil {
+				return report, fmt.Errorf("user %d email: %w", row.id, err)
+			}
+			version, encrypted := fieldcrypt.Version(row.email)
+			index := codec.BlindIndex(emailField, s.opts.Emails.Email(email))
+
+			reencrypt := !encrypted || (opts.Rotate && version != codec.CurrentVersion())
+			reindex := !row.index.Valid || row.index.String != index || row.canonical.Valid
+			if !reencrypt && !reindex {
+				report.ByKey[version]++
+				continue
+			}
+
+			stored := row.email
+			if reencrypt {
+				if stored, err = codec.Encrypt(emailField, email); err != nil {
+					return report, err
+				}
+			}
+
+			if !opts.DryRun {
+				result, err := s.db.ExecContext(ctx, updateRow, stored, index, row.id, row.email)
+				if errors.Is(mapConstraintError(err), ErrEmailTaken) {
+					report.Conflicts = append(report.Conflicts, row.id)
+					continue
+				}
+				if err != nil {
+					return report, err
+				}
+				if errors.Is(checkAffected(result), ErrUserNotFound) {
+					report.Changed = append(report.Changed, row.id)
+					continue
+				}
+			}
+
+			switch {
+			case !encrypted:
+				report.Encrypted++
+			case reencrypt:
+				report.Reencrypted++
+			default:
+				report.Reindexed++
+			}
+			switch {
+			case !opts.DryRun && reencrypt:
+				report.ByKey[codec.CurrentVersion()]++
+			case encrypted:
+				report.ByKey[version]++
+			}
+		}
+	}
+}
+
+func (s *sqlStore) readEmails(ctx context.Context, query string, afterID, limit int) ([]storedEmail, error) {
+	rows, err := s.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batch []storedEmail
+	for rows.Next() {
+		var row storedEmail
+		if err := rows.Scan(&row.id, &row.email, &row.canonical, &row.index); err != nil {
+			return nil, err
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Доменные ошибки хранилища. Обработчики сравнивают их через errors.Is,
+// а не по тексту.
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrLoginTaken       = errors.New("login already exists")
+	ErrGameSurnameTaken = errors.New("game surname already exists")
+	ErrEmailTaken       = errors.New("email already exists")
+	// ErrUserIDTaken - при импорте с сохранением ID такой ID уже занят
+	ErrUserIDTaken = errors.New("user id already exists")
+
+	ErrNameRuleNotFound = errors.New("name rule not found")
+	// ErrNameRuleExists - такой шаблон с тем же действием уже есть
+	ErrNameRuleExists = errors.New("name rule already exists")
+)
+
+// uniqueColumnErrors сопоставляет столбцы с UNIQUE-ограничением доменным ошибкам
+var uniqueColumnErrors = map[string]error{
+	"users.login":                  ErrLoginTaken,
+	"users.login_canonical":        ErrLoginTaken,
+	"users.game_surname":           ErrGameSurnameTaken,
+	"users.game_surname_canonical": ErrGameSurnameTaken,
+	"users.email":                  ErrEmailTaken,
+	"users.email_index":            ErrEmailTaken,
+	"users.email_canonical":        ErrEmailTaken,
+	"users.id":                     ErrUserIDTaken,
+	"name_rules.pattern":           ErrNameRuleExists,
+}
+
+// uniqueConstraintErrors сопоставляет ограничения PostgreSQL доменным ошибкам
+var uniqueConstraintErrors = map[string]error{
+	"users_login_key":                  ErrLoginTaken,
+	"users_login_canonical_key":        ErrLoginTaken,
+	"users_game_surname_key":           ErrGameSurnameTaken,
+	"users_game_surname_canonical_key": ErrGameSurnameTaken,
+	"users_email_key":                  ErrEmailTaken,
+	"users_email_index_key":            ErrEmailTaken,
+	"users_email_canonical_key":        ErrEmailTaken,
+	"users_pkey":                       ErrUserIDTaken,
+	"name_rules_pattern_action_key":    ErrNameRuleExists,
+}
+
+// pqUniqueViolation - SQLSTATE нарушения уникальности в PostgreSQL
+const pqUniqueViolation = "23505"
+
+// mapConstraintError переводит нарушение UNIQUE-ограничения SQLite или PostgreSQL
+// в доменную ошибку. Остальные ошибки возвращаются без изменений.
+func mapConstraintError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == pqUniqueViolation {
+			if domainErr, ok := uniqueConstraintErrors[pqErr.Constraint]; ok {
+				return domainErr
+			}
+		}
+		return err
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	// Повтор ID при импорте нарушает первичный ключ, а не UNIQUE
+	if code := sqliteErr.Code(); code != sqlite3.SQLITE_CONSTRAINT_UNIQUE && code != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return err
+	}
+
+	// Текст ошибки: "UNIQUE constraint failed: users.email"
+	message := sqliteErr.Error()
+	for column, domainErr := range uniqueColumnErrors {
+		if strings.Contains(message, column) {
+			return domainErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"time"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/models"
+)
+
+// MemoryStore - потокобезопасное хранилище в памяти для тестов и локальной разработки.
+// Повторяет поведение SQLiteDB, включая UNIQUE-ограничения таблицы users.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	nextID int
+	users  map[int]*models.User
+
+	// Индексы уникальных полей: значение -> ID пользователя. Логин, фамилия
+	// и email хранятся в каноническом виде, как в SQL-хранилищах с правилами
+	// по умолчанию
+	byLogin       map[string]int
+	byGameSurname map[string]int
+	byEmail       map[string]int
+
+	nameRules      map[int]models.NameRule
+	nextNameRuleID int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:        1,
+		users:         make(map[int]*models.User),
+		byLogin:       make(map[string]int),
+		byGameSurname: make(map[string]int),
+		byEmail:       make(map[string]int),
+		nameRules:     make(map[int]models.NameRule),
+	}
+}
+
+func (m *MemoryStore) CreateUser(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkUnique(user); err != nil {
+		return err
+	}
+
+	user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+	stored := *user
+	stored.ID = m.nextID
+	m.nextID++
+	m.insert(stored)
+
+	user.ID = stored.ID
+	return nil
+}
+
+// checkUnique проверяет UNIQUE-ограничения; вызывается под m.mu
+func (m *MemoryStore) checkUnique(user *models.User) error {
+	if _, exists := m.byLogin[canonical.Login(user.Login)]; exists {
+		return ErrLoginTaken
+	}
+	if _, exists := m.byGameSurname[canonical.Surname(user.GameSurname)]; exists {
+		return ErrGameSurnameTaken
+	}
+	if _, exists := m.byEmail[canonicalEmail(user.Email)]; exists {
+		return ErrEmailTaken
+	}
+	return nil
+}
+
+// insert сохраняет копию пользователя и обновляет индексы; вызывается под m.mu
+func (m *MemoryStore) insert(stored models.User) {
+	now := time.Now()
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+
+	m.users[stored.ID] = &stored
+	m.byLogin[canonical.Login(stored.Login)] = stored.ID
+	m.byGameSurname[canonical.Surname(stored.GameSurname)] = stored.ID
+	m.byEmail[canonicalEmail(stored.Email)] = stored.ID
+}
+
+func (m *MemoryStore) ImportUser(ctx context.Context, user *models.User) error {
+	if user.ID == 0 {
+		return m.CreateUser(ctx, user)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.users[user.ID]; exists {
+		return ErrUserIDTaken
+	}
+	if err := m.checkUnique(user); err != nil {
+		return err
+	}
+
+	user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+	m.insert(*user)
+	if user.ID >= m.nextID {
+		m.nextID = user.ID + 1
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *MemoryStore) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
+	return m.getBy(ctx, m.byLogin, canonical.Login(login))
+}
+
+func (m *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.getBy(ctx, m.byEmail, canonicalEmail(email))
+}
+
+func (m *MemoryStore) GetUserByGameSurname(ctx context.Context, gameSurname string) (*models.User, error) {
+	return m.getBy(ctx, m.byGameSurname, canonical.Surname(gameSurname))
+}
+
+func (m *MemoryStore) getBy(ctx context.Context, index map[string]int, value string) (*models.User, error) {
+	m.mu.RLock()
+	id, ok := index[value]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return m.GetUserByID(ctx, id)
+}
+
+func (m *MemoryStore) UpdateUser(ctx context.Context, userID int, update UserUpdate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	// Сначала проверяем все ограничения, чтобы не применить обновление частично
+	if update.GameSurname != nil {
+		if id, exists := m.byGameSurname[canonical.Surname(*update.GameSurname)]; exists && id != userID {
+			return ErrGameSurnameTaken
+		}
+	}
+	if update.Email != nil {
+		if id, exists := m.byEmail[canonicalEmail(*update.Email)]; exists && id != userID {
+			return ErrEmailTaken
+		}
+	}
+
+	if update.GameSurname != nil {
+		delete(m.byGameSurname, canonical.Surname(user.GameSurname))
+		user.GameSurname = *update.GameSurname
+		user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+		m.byGameSurname[canonical.Surname(user.GameSurname)] = userID
+	}
+	if update.Email != nil {
+		delete(m.byEmail, canonicalEmail(user.Email))
+		user.Email = *update.Email
+		m.byEmail[canonicalEmail(user.Email)] = userID
+	}
+	if update.Locale != nil {
+		user.Locale = *update.Locale
+	}
+	user.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (m *MemoryStore) UpdatePassword(ctx context.Context, userID int, newPasswordHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	user.Password = newPasswordHash
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) SetPasswordBreached(ctx context.Context, userID int, breached bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	user.PasswordBreached = breached
+	return nil
+}
+
+func (m *MemoryStore) UserExists(ctx context.Context, login string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, exists := m.byLogin[canonical.Login(login)]
+	return exists, nil
+}
+
+// canonicalEmail - канонический email по правилам по умолчанию
+func canonicalEmail(email string) string {
+	return canonical.EmailRules{}.Email(email)
+}
+
+// Ping всегда успешен: хранилище в памяти доступно, пока жив процесс
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// PendingMigrations всегда 0: хранилищу в памяти схема не нужна
+func (m *MemoryStore) PendingMigrations(ctx context.Context, migrationFS fs.FS) (int, error) {
+	return 0, nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,14 @@
+package database_test
+
+import (
+	"testing"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/database/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.UserStore {
+		return database.NewMemoryStore()
+	})
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io/fs"
 	"path/filepath"
@@ -9,36 +11,20 @@ import (
 	"strings"
 )
 
-func (s *SQLiteDB) RunMigrations(migrationFS fs.FS) error {
+func runMigrations(db *sql.DB, dialect dialect, migrationFS fs.FS) error {
 	// Создаем таблицу для отслеживания миграций
-	_, err := s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version INTEGER PRIMARY KEY,
-			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
+	if _, err := db.Exec(dialect.migrationsTable); err != nil {
 		return fmt.Errorf("create migrations table: %w", err)
 	}
 
 	// Получаем список примененных миграций
-	appliedVersions := make(map[int]bool)
-	rows, err := s.db.Query("SELECT version FROM schema_migrations")
+	appliedVersions, err := appliedMigrations(context.Background(), db)
 	if err != nil {
-		return fmt.Errorf("query applied migrations: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
-			return fmt.Errorf("scan migration version: %w", err)
-		}
-		appliedVersions[version] = true
+		return err
 	}
 
 	// Загружаем миграции из файловой системы
-	migrations, err := s.loadMigrations(migrationFS)
+	migrations, err := loadMigrations(migrationFS)
 	if err != nil {
 		return fmt.Errorf("load migrations: %w", err)
 	}
@@ -46,7 +32,7 @@ func (s *SQLiteDB) RunMigrations(migrationFS fs.FS) error {
 	// Применяем непримененные миграции
 	for _, migration := range migrations {
 		if !appliedVersions[migration.Version] {
-			if err := s.applyMigration(migration); err != nil {
+			if err := applyMigration(db, dialect, migration); err != nil {
 				return fmt.Errorf("apply migration %d: %w", migration.Version, err)
 			}
 		}
@@ -55,7 +41,50 @@ func (s *SQLiteDB) RunMigrations(migrationFS fs.FS) error {
 	return nil
 }
 
-func (s *SQLiteDB) loadMigrations(migrationFS fs.FS) ([]Migration, error) {
+func pendingMigrations(ctx context.Context, db *sql.DB, migrationFS fs.FS) (int, error) {
+	migrations, err := loadMigrations(migrationFS)
+	if err != nil {
+		return 0, fmt.Errorf("load migrations: %w", err)
+	}
+
+	appliedVersions, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !appliedVersions[migration.Version] {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedVersions := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scan migration version: %w", err)
+		}
+		appliedVersions[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return appliedVersions, nil
+}
+
+func loadMigrations(migrationFS fs.FS) ([]Migration, error) {
 	var migrations []Migration
 
 	// Читаем все .sql файлы из папки миграций
@@ -65,7 +94,7 @@ func (s *SQLiteDB) loadMigrations(migrationFS fs.FS) ([]Migration, error) {
 		}
 
 		if !d.IsDir() && filepath.Ext(d.Name()) == ".sql" {
-			migration, err := s.parseMigrationFile(migrationFS, d.Name())
+			migration, err := parseMigrationFile(migrationFS, d.Name())
 			if err != nil {
 				return err
 			}
@@ -87,7 +116,7 @@ func (s *SQLiteDB) loadMigrations(migrationFS fs.FS) ([]Migration, error) {
 	return migrations, nil
 }
 
-func (s *SQLiteDB) parseMigrationFile(migrationFS fs.FS, filename string) (Migration, error) {
+func parseMigrationFile(migrationFS fs.FS, filename string) (Migration, error) {
 	// Извлекаем версию из имени файла: 001_create_users.sql -> 1
 	versionStr := strings.Split(filename, "_")[0]
 	version, err := strconv.Atoi(versionStr)
@@ -139,10 +168,10 @@ func parseMigrationSections(content string) (string, string) {
 		strings.TrimSpace(strings.Join(downLines, "\n"))
 }
 
-func (s *SQLiteDB) applyMigration(migration Migration) error {
+func applyMigration(db *sql.DB, dialect dialect, migration Migration) error {
 	fmt.Printf("Applying migration: %s\n", migration.Name)
 
-	tx, err := s.db.Begin()
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
@@ -154,7 +183,7 @@ func (s *SQLiteDB) applyMigration(migration Migration) error {
 	}
 
 	// Записываем в таблицу миграций
-	_, err = tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migration.Version)
+	_, err = tx.Exec(dialect.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), migration.Version)
 	if err != nil {
 		return fmt.Errorf("record migration: %w", err)
 	}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"LOIL-auth-server/internal/models"
+)
+
+// ListNameRules возвращает все правила имен в порядке добавления
+func (s *sqlStore) ListNameRules(ctx context.Context) ([]models.NameRule, error) {
+	ctx, cancel, err := s.query(ctx, s.stmts.listNameRules)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	rows, err := s.stmts.listNameRules.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.NameRule
+	for rows.Next() {
+		var rule models.NameRule
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &rule.Action, &rule.Note, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *sqlStore) AddNameRule(ctx context.Context, rule *models.NameRule) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.addNameRule)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	createdAt := time.Now()
+	err = s.stmts.addNameRule.QueryRowContext(ctx, rule.Pattern, rule.Action, rule.Note, createdAt).Scan(&rule.ID)
+	if err != nil {
+		return mapConstraintError(err)
+	}
+	rule.CreatedAt = createdAt
+	return nil
+}
+
+func (s *sqlStore) DeleteNameRule(ctx context.Context, id int) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.deleteNameRule)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	result, err := s.stmts.deleteNameRule.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNameRuleNotFound
+	}
+	return nil
+}
+
+// ListNameRules возвращает копии правил в порядке добавления
+func (m *MemoryStore) ListNameRules(ctx context.Context) ([]models.NameRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := make([]models.NameRule, 0, len(m.nameRules))
+	for _, rule := range m.nameRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+func (m *MemoryStore) AddNameRule(ctx context.Context, rule *models.NameRule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.nameRules {
+		if existing.Pattern == rule.Pattern && existing.Action == rule.Action {
+			return ErrNameRuleExists
+		}
+	}
+	m.nextNameRuleID++
+	rule.ID = m.nextNameRuleID
+	rule.CreatedAt = time.Now()
+	m.nameRules[rule.ID] = *rule
+	return nil
+}
+
+func (m *MemoryStore) DeleteNameRule(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.nameRules[id]; !ok {
+		return ErrNameRuleNotFound
+	}
+	delete(m.nameRules, id)
+	return nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"fmt"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/config"
+)
+
+// OptionsFrom переводит секцию database конфигурации в Options
+func OptionsFrom(cfg config.DatabaseConfig) Options {
+	return Options{
+		QueryTimeout: cfg.QueryTimeout,
+		SQLite: SQLiteOptions{
+			JournalMode:  cfg.SQLite.JournalMode,
+			Synchronous:  cfg.SQLite.Synchronous,
+			BusyTimeout:  cfg.SQLite.BusyTimeout,
+			ForeignKeys:  cfg.SQLite.ForeignKeys,
+			MaxReadConns: cfg.SQLite.MaxReadConns,
+		},
+		Emails: canonical.EmailRules{
+			IgnoreDotsDomains: cfg.Canonical.IgnoreDotsDomains,
+			StripPlusDomains:  cfg.Canonical.StripPlusDomains,
+		},
+	}
+}
+
+// Open подключает PostgreSQL, если задан DSN, иначе SQLite-файл.
+// Миграции для выбранного хранилища лежат в migrations/<cfg.Driver()>
+func Open(cfg config.DatabaseConfig) (Backend, error) {
+	opts := OptionsFrom(cfg)
+	fields, err := FieldCodecFrom(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("database encryption: %w", err)
+	}
+	opts.Fields = fields
+
+	if cfg.Driver() == config.DriverPostgres {
+		return NewPostgresDB(cfg.DSN, opts)
+	}
+	return NewSQLiteDB(cfg.Path, opts)
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresDB - хранилище пользователей в PostgreSQL. Уникальность полей
+// обеспечивают ограничения users_*_key из migrations/postgres.
+type PostgresDB struct {
+	sqlStore
+}
+
+func NewPostgresDB(dsn string, opts Options) (*PostgresDB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresDB{sqlStore{db: db, reader: db, dialect: postgresDialect, opts: opts}}, nil
+}
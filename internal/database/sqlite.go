@@ -3,182 +3,122 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
-	"LOIL-auth-server/internal/models"
-
 	_ "modernc.org/sqlite"
 )
 
 type SQLiteDB struct {
-	db *sql.DB
-}
-
-func NewSQLiteDB(dbPath string) (*SQLiteDB, error) {
-	db, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	return &SQLiteDB{db: db}, nil
+	sqlStore
 }
 
-func (s *SQLiteDB) Close() error {
-	return s.db.Close()
+// SQLiteOptions - прагмы и пулы соединений SQLite. Нулевое значение оставляет
+// настройки драйвера по умолчанию и один общий пул.
+type SQLiteOptions struct {
+	JournalMode string
+	Synchronous string
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// MaxReadConns > 0 включает отдельный пул чтения из стольких соединений,
+	// а запись идет через единственное соединение и не конкурирует сама с собой
+	MaxReadConns int
 }
 
-// Проверка уникальности полей
-func (s *SQLiteDB) CheckUniqueFields(login, gameSurname, email string) error {
-	var count int
-
-	// Проверка логина
-	err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE login = ?", login).Scan(&count)
-	if err != nil {
-		return err
+// pragmas возвращает прагмы, которые применяются к каждому новому соединению
+func (o SQLiteOptions) pragmas() []string {
+	var pragmas []string
+	// busy_timeout первым, чтобы смена journal_mode тоже дожидалась блокировки
+	if o.BusyTimeout > 0 {
+		pragmas = append(pragmas, o.busyTimeoutPragma())
 	}
-	if count > 0 {
-		return fmt.Errorf("login already exists")
+	if o.JournalMode != "" {
+		pragmas = append(pragmas, "journal_mode("+o.JournalMode+")")
 	}
-
-	// Проверка игровой фамилии
-	err = s.db.QueryRow("SELECT COUNT(*) FROM users WHERE game_surname = ?", gameSurname).Scan(&count)
-	if err != nil {
-		return err
+	if o.Synchronous != "" {
+		pragmas = append(pragmas, "synchronous("+o.Synchronous+")")
 	}
-	if count > 0 {
-		return fmt.Errorf("game surname already exists")
+	if o.ForeignKeys {
+		pragmas = append(pragmas, "foreign_keys(1)")
 	}
-
-	// Проверка email
-	err = s.db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count)
-	if err != nil {
-		return err
-	}
-	if count > 0 {
-		return fmt.Errorf("email already exists")
-	}
-
-	return nil
+	return pragmas
 }
 
-// Создание пользователя
-func (s *SQLiteDB) CreateUser(user *models.User) error {
-	if err := s.CheckUniqueFields(user.Login, user.GameSurname, user.Email); err != nil {
-		return err
-	}
+func (o SQLiteOptions) busyTimeoutPragma() string {
+	return fmt.Sprintf("busy_timeout(%d)", o.BusyTimeout.Milliseconds())
+}
 
-	insertSQL := `
-	INSERT INTO users (login, game_surname, email, password) 
-	VALUES (?, ?, ?, ?)
-	`
+func NewSQLiteDB(dbPath string, opts Options) (*SQLiteDB, error) {
+	pragmas := opts.SQLite.pragmas()
 
-	result, err := s.db.Exec(insertSQL, user.Login, user.GameSurname, user.Email, user.Password)
-	if err != nil {
-		return err
+	// База в памяти существует только внутри своего соединения,
+	// поэтому отдельный пул чтения увидел бы пустую базу
+	separateReader := opts.SQLite.MaxReadConns > 0 && !isInMemorySQLite(dbPath)
+	if !separateReader {
+		db, err := openSQLite(sqliteDSN(dbPath, pragmas))
+		if err != nil {
+			return nil, err
+		}
+		return &SQLiteDB{sqlStore{db: db, reader: db, dialect: sqliteDialect, opts: opts}}, nil
 	}
 
-	// Получаем ID созданного пользователя
-	id, err := result.LastInsertId()
+	// Писатель открывается первым: journal_mode=WAL сохраняется в файле
+	// и должен быть включен до того, как подключатся читатели.
+	// BEGIN IMMEDIATE сразу берет блокировку записи, и транзакция не упирается
+	// в SQLITE_BUSY посередине при попытке повысить блокировку.
+	writer, err := openSQLite(sqliteDSN(dbPath, pragmas, "_txlock=immediate"))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	writer.SetMaxOpenConns(1)
 
-	user.ID = int(id)
-	return nil
-}
-
-// Получение пользователя по логину
-func (s *SQLiteDB) GetUserByLogin(login string) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`
-		SELECT id, login, game_surname, email, password, created_at, updated_at 
-		FROM users WHERE login = ?
-	`, login).Scan(&user.ID, &user.Login, &user.GameSurname, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
-
+	// Читателям нужна только прагма ожидания; query_only защищает от случайной записи
+	var readerPragmas []string
+	if opts.SQLite.BusyTimeout > 0 {
+		readerPragmas = append(readerPragmas, opts.SQLite.busyTimeoutPragma())
+	}
+	readerPragmas = append(readerPragmas, "query_only(1)")
+	reader, err := openSQLite(sqliteDSN(dbPath, readerPragmas))
 	if err != nil {
+		writer.Close()
 		return nil, err
 	}
+	reader.SetMaxOpenConns(opts.SQLite.MaxReadConns)
+	reader.SetMaxIdleConns(opts.SQLite.MaxReadConns)
 
-	return &user, nil
+	return &SQLiteDB{sqlStore{db: writer, reader: reader, dialect: sqliteDialect, opts: opts}}, nil
 }
 
-// Получение пользователя по ID
-func (s *SQLiteDB) GetUserByID(id int) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`
-		SELECT id, login, game_surname, email, password, created_at, updated_at 
-		FROM users WHERE id = ?
-	`, id).Scan(&user.ID, &user.Login, &user.GameSurname, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
-
+func openSQLite(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
-}
-
-// Получение пользователя по email
-func (s *SQLiteDB) GetUserByEmail(email string) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`
-		SELECT id, login, game_surname, email, password, created_at, updated_at 
-		FROM users WHERE email = ?
-	`, email).Scan(&user.ID, &user.Login, &user.GameSurname, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
-
-	if err != nil {
+	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	return &user, nil
+	return db, nil
 }
 
-// Обновление профиля пользователя
-func (s *SQLiteDB) UpdateUser(userID int, updates map[string]string) error {
-	// Базовая проверка - нельзя обновлять пароль через этот метод
-	if _, exists := updates["password"]; exists {
-		return fmt.Errorf("password cannot be updated through this method")
+// sqliteDSN добавляет к пути прагмы в формате драйвера: path?_pragma=busy_timeout(5000)
+func sqliteDSN(path string, pragmas []string, params ...string) string {
+	for _, pragma := range pragmas {
+		params = append(params, "_pragma="+pragma)
 	}
-
-	// Начинаем построение запроса
-	query := "UPDATE users SET "
-	params := []interface{}{}
-	first := true
-
-	// Динамически добавляем поля для обновления
-	for field, value := range updates {
-		if !first {
-			query += ", "
-		}
-		query += field + " = ?"
-		params = append(params, value)
-		first = false
+	if len(params) == 0 {
+		return path
 	}
 
-	// Добавляем обновление времени и условие WHERE
-	query += ", updated_at = ? WHERE id = ?"
-	params = append(params, time.Now(), userID)
-
-	_, err := s.db.Exec(query, params...)
-	return err
-}
-
-// Обновление пароля
-func (s *SQLiteDB) UpdatePassword(userID int, newPasswordHash string) error {
-	_, err := s.db.Exec(`
-		UPDATE users SET password = ?, updated_at = ? 
-		WHERE id = ?
-	`, newPasswordHash, time.Now(), userID)
-
-	return err
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(params, "&")
 }
 
-// Проверка существования пользователя
-func (s *SQLiteDB) UserExists(login string) (bool, error) {
-	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE login = ?", login).Scan(&count)
-	return count > 0, err
+func isInMemorySQLite(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory")
 }
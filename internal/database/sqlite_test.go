@@ -0,0 +1,34 @@
+package database_test
+
+import (
+	"testing"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/database/storetest"
+	"LOIL-auth-server/internal/fieldcrypt"
+)
+
+func TestSQLiteDB(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.UserStore {
+		return storetest.NewSQLiteDB(t, database.Options{})
+	})
+}
+
+// Шифрование email и правила почтовых сервисов не должны менять поведение
+// хранилища для остальных полей
+func TestSQLiteDBEncrypted(t *testing.T) {
+	fields, err := fieldcrypt.New("1",
+		map[string][]byte{"1": []byte("0123456789abcdef0123456789abcdef")},
+		[]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storetest.Run(t, func(t *testing.T) database.UserStore {
+		return storetest.NewSQLiteDB(t, database.Options{
+			Fields: fields,
+			Emails: canonical.EmailRules{IgnoreDotsDomains: []string{"gmail.com"}},
+		})
+	})
+}
@@ -0,0 +1,457 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/fieldcrypt"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/models"
+)
+
+// Options - настройки, общие для SQL-хранилищ
+type Options struct {
+	// QueryTimeout ограничивает каждый запрос; 0 - только контекст вызывающего
+	QueryTimeout time.Duration
+	// SQLite - настройки подключения к SQLite, остальными хранилищами игнорируются
+	SQLite SQLiteOptions
+	// Fields шифрует персональные данные; nil - данные хранятся открыто
+	Fields *fieldcrypt.Codec
+	// Emails - правила канонического email для поиска и уникальности
+	Emails canonical.EmailRules
+}
+
+// dialect - различия SQL-диалектов, которые нужны общему коду
+type dialect struct {
+	// numbered - плейсхолдеры $1, $2 вместо ?
+	numbered bool
+	// migrationsTable создает таблицу schema_migrations
+	migrationsTable string
+	// syncIDSequence сдвигает счетчик ID после вставки с явным ID; SQLite
+	// делает это сам
+	syncIDSequence string
+}
+
+var (
+	sqliteDialect = dialect{
+		migrationsTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}
+	postgresDialect = dialect{
+		numbered: true,
+		migrationsTable: `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		syncIDSequence: "SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(id) FROM users))",
+	}
+)
+
+// rebind заменяет ? на $1, $2... для диалектов с нумерованными плейсхолдерами
+func (d dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+const userColumns = "id, login, game_surname, game_surname_latin, email, password, locale, password_breached, created_at, updated_at"
+
+// Запросы хранилища пользователей. Все они готовятся один раз в Prepare;
+// изменяемые столбцы перечислены явно, а не собираются из ввода.
+var userQueries = struct {
+	create, createWithID            string
+	getByID, getByLogin, getByEmail string
+	getByGameSurname                string
+	update, updatePassword          string
+	setPasswordBreached             string
+	loginExists                     string
+	listNameRules, addNameRule      string
+	deleteNameRule                  string
+}{
+	create: `INSERT INTO users (login, login_canonical, game_surname, game_surname_canonical, game_surname_latin,
+			email, email_canonical, email_index, password, locale)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+	createWithID: `INSERT INTO users (id, login, login_canonical, game_surname, game_surname_canonical, game_surname_latin,
+			email, email_canonical, email_index, password, locale)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	getByID: "SELECT " + userColumns + " FROM users WHERE id = ?",
+	// Строки без канонического значения (совпавшие с другими при
+	// пересчете) ищутся по точному; точное совпадение в приоритете
+	getByLogin: "SELECT " + userColumns + ` FROM users
+		WHERE login_canonical = ? OR (login_canonical IS NULL AND login = ?)
+		ORDER BY CASE WHEN login = ? THEN 0 ELSE 1 END LIMIT 1`,
+	// Ключ email - канонический email или, если шифрование включено, его
+	// слепой индекс; второй параметр тогда NULL и ничему не равен
+	getByEmail: "SELECT " + userColumns + ` FROM users
+		WHERE email_canonical = ? OR email_index = ?
+			OR (email_canonical IS NULL AND email_index IS NULL AND email = ?)
+		ORDER BY CASE WHEN email = ? THEN 0 ELSE 1 END LIMIT 1`,
+	getByGameSurname: "SELECT " + userColumns + ` FROM users
+		WHERE game_surname_canonical = ? OR (game_surname_canonical IS NULL AND game_surname = ?)
+		ORDER BY CASE WHEN game_surname = ? THEN 0 ELSE 1 END LIMIT 1`,
+	// NULL в параметре оставляет столбец без изменений
+	update: `UPDATE users SET
+			game_surname = COALESCE(?, game_surname),
+			game_surname_canonical = COALESCE(?, game_surname_canonical),
+			game_surname_latin = COALESCE(?, game_surname_latin),
+			email = COALESCE(?, email),
+			email_canonical = COALESCE(?, email_canonical),
+			email_index = COALESCE(?, email_index),
+			locale = COALESCE(?, locale),
+			updated_at = ?
+		WHERE id = ?`,
+	updatePassword:      "UPDATE users SET password = ?, updated_at = ? WHERE id = ?",
+	setPasswordBreached: "UPDATE users SET password_breached = ? WHERE id = ?",
+	loginExists:         "SELECT EXISTS (SELECT 1 FROM users WHERE login_canonical = ? OR login = ?)",
+	listNameRules:       "SELECT id, pattern, action, note, created_at FROM name_rules ORDER BY id",
+	addNameRule:         "INSERT INTO name_rules (pattern, action, note, created_at) VALUES (?, ?, ?, ?) RETURNING id",
+	deleteNameRule:      "DELETE FROM name_rules WHERE id = ?",
+}
+
+type userStatements struct {
+	create, createWithID            *sql.Stmt
+	getByID, getByLogin, getByEmail *sql.Stmt
+	getByGameSurname                *sql.Stmt
+	update, updatePassword          *sql.Stmt
+	setPasswordBreached             *sql.Stmt
+	loginExists                     *sql.Stmt
+	listNameRules, addNameRule      *sql.Stmt
+	deleteNameRule                  *sql.Stmt
+}
+
+// errNotPrepared возвращается, если Prepare не был вызван после миграций
+var errNotPrepared = errors.New("database statements are not prepared")
+
+// sqlStore - реализация UserStore поверх database/sql, общая для SQLite и PostgreSQL.
+// Запись и миграции идут через db, чтение - через reader; у PostgreSQL это один пул.
+type sqlStore struct {
+	db      *sql.DB
+	reader  *sql.DB
+	dialect dialect
+	opts    Options
+	stmts   userStatements
+}
+
+func (s *sqlStore) Close() error {
+	for _, stmt := range s.stmts.all() {
+		stmt.Close()
+	}
+	if s.reader != s.db {
+		s.reader.Close()
+	}
+	return s.db.Close()
+}
+
+// Ping проверяет доступность базы данных
+func (s *sqlStore) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return err
+	}
+	return s.reader.PingContext(ctx)
+}
+
+func (s *sqlStore) RunMigrations(migrationFS fs.FS) error {
+	return runMigrations(s.db, s.dialect, migrationFS)
+}
+
+// PendingMigrations возвращает количество миграций, которые еще не применены
+func (s *sqlStore) PendingMigrations(ctx context.Context, migrationFS fs.FS) (int, error) {
+	return pendingMigrations(ctx, s.reader, migrationFS)
+}
+
+// Prepare готовит все запросы. Вызывается после миграций, так как
+// подготовка проверяет, что таблицы и столбцы существуют; до этого
+// методы хранилища возвращают errNotPrepared.
+func (s *sqlStore) Prepare(ctx context.Context) error {
+	var stmts userStatements
+	targets := []struct {
+		dst   **sql.Stmt
+		query string
+		read  bool
+	}{
+		{&stmts.create, userQueries.create, false},
+		{&stmts.createWithID, userQueries.createWithID, false},
+		{&stmts.getByID, userQueries.getByID, true},
+		{&stmts.getByLogin, userQueries.getByLogin, true},
+		{&stmts.getByEmail, userQueries.getByEmail, true},
+		{&stmts.getByGameSurname, userQueries.getByGameSurname, true},
+		{&stmts.update, userQueries.update, false},
+		{&stmts.updatePassword, userQueries.updatePassword, false},
+		{&stmts.setPasswordBreached, userQueries.setPasswordBreached, false},
+		{&stmts.loginExists, userQueries.loginExists, true},
+		{&stmts.listNameRules, userQueries.listNameRules, true},
+		{&stmts.addNameRule, userQueries.addNameRule, false},
+		{&stmts.deleteNameRule, userQueries.deleteNameRule, false},
+	}
+
+	for _, target := range targets {
+		pool := s.db
+		if target.read {
+			pool = s.reader
+		}
+		stmt, err := pool.PrepareContext(ctx, s.dialect.rebind(target.query))
+		if err != nil {
+			for _, prepared := range stmts.all() {
+				prepared.Close()
+			}
+			return fmt.Errorf("prepare %q: %w", target.query, err)
+		}
+		*target.dst = stmt
+	}
+
+	s.stmts = stmts
+	return nil
+}
+
+func (st userStatements) all() []*sql.Stmt {
+	var stmts []*sql.Stmt
+	for _, stmt := range []*sql.Stmt{
+		st.create, st.createWithID, st.getByID, st.getByLogin, st.getByEmail, st.getByGameSurname,
+		st.update, st.updatePassword, st.setPasswordBreached,
+		st.loginExists, st.listNameRules, st.addNameRule, st.deleteNameRule,
+	} {
+		if stmt != nil {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// query ограничивает контекст запроса таймаутом из Options
+func (s *sqlStore) query(ctx context.Context, stmt *sql.Stmt) (context.Context, context.CancelFunc, error) {
+	if stmt == nil {
+		return nil, nil, errNotPrepared
+	}
+	if s.opts.QueryTimeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.opts.QueryTimeout)
+	return ctx, cancel, nil
+}
+
+// Создание пользователя. Уникальность проверяют ограничения UNIQUE в самой
+// базе: отдельная проверка перед INSERT пропускала бы параллельные регистрации
+func (s *sqlStore) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.create)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	email, err := s.sealEmail(user.Email)
+	if err != nil {
+		return err
+	}
+	emailCanonical, emailIndex := s.emailKeys(user.Email)
+
+	user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+
+	err = s.stmts.create.QueryRowContext(ctx, user.Login, canonical.Login(user.Login),
+		user.GameSurname, canonical.Surname(user.GameSurname), user.GameSurnameLatin,
+		email, emailCanonical, emailIndex, user.Password, user.Locale).Scan(&user.ID)
+	if err != nil {
+		return mapConstraintError(err)
+	}
+	return nil
+}
+
+// ImportUser сохраняет пользователя из другой системы, при необходимости с его прежним ID
+func (s *sqlStore) ImportUser(ctx context.Context, user *models.User) error {
+	if user.ID == 0 {
+		return s.CreateUser(ctx, user)
+	}
+
+	ctx, cancel, err := s.query(ctx, s.stmts.createWithID)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	email, err := s.sealEmail(user.Email)
+	if err != nil {
+		return err
+	}
+	emailCanonical, emailIndex := s.emailKeys(user.Email)
+
+	user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+
+	_, err = s.stmts.createWithID.ExecContext(ctx, user.ID, user.Login, canonical.Login(user.Login),
+		user.GameSurname, canonical.Surname(user.GameSurname), user.GameSurnameLatin,
+		email, emailCanonical, emailIndex, user.Password, user.Locale)
+	if err != nil {
+		return mapConstraintError(err)
+	}
+
+	if s.dialect.syncIDSequence != "" {
+		if _, err := s.db.ExecContext(ctx, s.dialect.syncIDSequence); err != nil {
+			return fmt.Errorf("sync id sequence: %w", err)
+		}
+	}
+	return nil
+}
+
+// Получение пользователя по ID
+func (s *sqlStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return s.getUser(ctx, s.stmts.getByID, id)
+}
+
+// Получение пользователя по логину
+func (s *sqlStore) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
+	return s.getUser(ctx, s.stmts.getByLogin, canonical.Login(login), login, login)
+}
+
+// Получение пользователя по email
+func (s *sqlStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	emailCanonical, emailIndex := s.emailKeys(email)
+	return s.getUser(ctx, s.stmts.getByEmail, emailCanonical, emailIndex, email, email)
+}
+
+// Получение пользователя по игровой фамилии (в нормализованном виде)
+func (s *sqlStore) GetUserByGameSurname(ctx context.Context, gameSurname string) (*models.User, error) {
+	return s.getUser(ctx, s.stmts.getByGameSurname, canonical.Surname(gameSurname), gameSurname, gameSurname)
+}
+
+func (s *sqlStore) getUser(ctx context.Context, stmt *sql.Stmt, args ...interface{}) (*models.User, error) {
+	ctx, cancel, err := s.query(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	var user models.User
+	var gameSurnameLatin sql.NullString
+	err = stmt.QueryRowContext(ctx, args...).Scan(&user.ID, &user.Login, &user.GameSurname, &gameSurnameLatin, &user.Email, &user.Password, &user.Locale, &user.PasswordBreached, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Строки до миграции дополняются при старте; до тех пор форма
+	// латиницей вычисляется на лету
+	user.GameSurnameLatin = gameSurnameLatin.String
+	if !gameSurnameLatin.Valid {
+		user.GameSurnameLatin = gamesurname.Transliterate(user.GameSurname)
+	}
+
+	if user.Email, err = s.openEmail(user.Email); err != nil {
+		return nil, fmt.Errorf("user %d email: %w", user.ID, err)
+	}
+	return &user, nil
+}
+
+// Обновление профиля пользователя
+func (s *sqlStore) UpdateUser(ctx context.Context, userID int, update UserUpdate) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.update)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	// nil оставляет email и его ключи без изменений
+	var email, emailCanonical, emailIndex interface{}
+	if update.Email != nil {
+		if email, err = s.sealEmail(*update.Email); err != nil {
+			return err
+		}
+		emailCanonical, emailIndex = s.emailKeys(*update.Email)
+	}
+
+	// nil оставляет фамилию и ее ключи без изменений
+	var gameSurnameCanonical, gameSurnameLatin interface{}
+	if update.GameSurname != nil {
+		gameSurnameCanonical = canonical.Surname(*update.GameSurname)
+		gameSurnameLatin = gamesurname.Transliterate(*update.GameSurname)
+	}
+
+	result, err := s.stmts.update.ExecContext(ctx, update.GameSurname, gameSurnameCanonical, gameSurnameLatin,
+		email, emailCanonical, emailIndex, update.Locale, time.Now(), userID)
+	if err != nil {
+		return mapConstraintError(err)
+	}
+
+	return checkAffected(result)
+}
+
+// Обновление пароля
+func (s *sqlStore) UpdatePassword(ctx context.Context, userID int, newPasswordHash string) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.updatePassword)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	result, err := s.stmts.updatePassword.ExecContext(ctx, newPasswordHash, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// SetPasswordBreached ставит или снимает отметку о пароле из утечки. updated_at
+// не меняется: профиль пользователь не редактировал
+func (s *sqlStore) SetPasswordBreached(ctx context.Context, userID int, breached bool) error {
+	ctx, cancel, err := s.query(ctx, s.stmts.setPasswordBreached)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	result, err := s.stmts.setPasswordBreached.ExecContext(ctx, breached, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// checkAffected возвращает ErrUserNotFound, если UPDATE не затронул ни одной строки
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Проверка существования пользователя
+func (s *sqlStore) UserExists(ctx context.Context, login string) (bool, error) {
+	ctx, cancel, err := s.query(ctx, s.stmts.loginExists)
+	if err != nil {
+		return false, err
+	}
+	defer cancel()
+
+	var exists bool
+	err = s.stmts.loginExists.QueryRowContext(ctx, canonical.Login(login), login).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"io/fs"
+
+	"LOIL-auth-server/internal/models"
+)
+
+// UserStore - хранилище пользователей. Реализации обязаны давать одинаковую
+// семантику уникальности (login, email и game_surname сравниваются в
+// каноническом виде, см. пакет canonical), заполнять GameSurnameLatin
+// и возвращать доменные ошибки из errors.go; это проверяет пакет storetest.
+// Все методы прерываются при отмене ctx.
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetUserByGameSurname ищет по фамилии, нормализованной utils.NormalizeGameSurname
+	GetUserByGameSurname(ctx context.Context, gameSurname string) (*models.User, error)
+	UpdateUser(ctx context.Context, userID int, update UserUpdate) error
+	UpdatePassword(ctx context.Context, userID int, newPasswordHash string) error
+	// SetPasswordBreached отмечает, что пароль пользователя найден в базе утечек
+	SetPasswordBreached(ctx context.Context, userID int, breached bool) error
+	UserExists(ctx context.Context, login string) (bool, error)
+}
+
+// UserImporter переносит пользователей из других систем. Если user.ID не 0,
+// пользователь сохраняется с этим ID, иначе ID назначает хранилище.
+type UserImporter interface {
+	ImportUser(ctx context.Context, user *models.User) error
+}
+
+// EmailEncryptor переводит сохраненные email на текущие ключи шифрования
+type EmailEncryptor interface {
+	EncryptEmails(ctx context.Context, opts EmailEncryptionOptions) (EmailEncryptionReport, error)
+}
+
+// UserCanonicalizer заполняет канонические логины, email и фамилии, по которым
+// ищутся пользователи, и находит аккаунты, совпадающие после приведения
+type UserCanonicalizer interface {
+	CanonicalizeUsers(ctx context.Context, opts CanonicalOptions) (CanonicalReport, error)
+}
+
+// NameRuleStore хранит шаблоны логинов и фамилий, которые администраторы
+// запретили или разрешили (пакет namepolicy)
+type NameRuleStore interface {
+	ListNameRules(ctx context.Context) ([]models.NameRule, error)
+	// AddNameRule сохраняет правило и заполняет его ID и CreatedAt
+	AddNameRule(ctx context.Context, rule *models.NameRule) error
+	DeleteNameRule(ctx context.Context, id int) error
+}
+
+// UserUpdate - изменяемые поля профиля; nil означает "не менять".
+// Пароль меняется только через UpdatePassword.
+type UserUpdate struct {
+	GameSurname *string
+	Email       *string
+	Locale      *string
+}
+
+// Empty сообщает, что обновлять нечего
+func (u UserUpdate) Empty() bool {
+	return u.GameSurname == nil && u.Email == nil && u.Locale == nil
+}
+
+// HealthChecker используется readiness-пробой
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	PendingMigrations(ctx context.Context, migrationFS fs.FS) (int, error)
+}
+
+// Backend - хранилище, с которым работает сервер: пользователи, проверки
+// готовности и собственный набор миграций. После RunMigrations нужно
+// вызвать Prepare.
+type Backend interface {
+	UserStore
+	UserImporter
+	EmailEncryptor
+	UserCanonicalizer
+	NameRuleStore
+	HealthChecker
+	RunMigrations(migrationFS fs.FS) error
+	Prepare(ctx context.Context) error
+	Close() error
+}
+
+var (
+	_ Backend       = (*SQLiteDB)(nil)
+	_ Backend       = (*PostgresDB)(nil)
+	_ UserStore     = (*MemoryStore)(nil)
+	_ UserImporter  = (*MemoryStore)(nil)
+	_ NameRuleStore = (*MemoryStore)(nil)
+	_ HealthChecker = (*MemoryStore)(nil)
+)
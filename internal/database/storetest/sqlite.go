@@ -0,0 +1,51 @@
+package storetest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"LOIL-auth-server/internal/database"
+)
+
+// NewSQLiteDB открывает базу во временном каталоге теста с теми же
+// настройками, что в config.example.yaml, и применяет миграции.
+// Нужна и тестам хранилища, и тестам обработчиков, поэтому путь к
+// миграциям считается от этого файла, а не от каталога теста.
+func NewSQLiteDB(t testing.TB, opts database.Options) *database.SQLiteDB {
+	t.Helper()
+	opts.QueryTimeout = 5 * time.Second
+	opts.SQLite = database.SQLiteOptions{
+		JournalMode:  "wal",
+		Synchronous:  "normal",
+		BusyTimeout:  5 * time.Second,
+		ForeignKeys:  true,
+		MaxReadConns: 4,
+	}
+
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "auth.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(os.DirFS(migrationsDir(t, "sqlite"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Prepare(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// migrationsDir возвращает каталог migrations/<driver> в корне репозитория
+func migrationsDir(t testing.TB, driver string) string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("storetest: cannot locate migrations")
+	}
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations", driver)
+}
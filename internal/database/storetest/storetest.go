@@ -0,0 +1,536 @@
+// Package storetest - общий набор проверок для реализаций database.UserStore.
+// Каждое хранилище подключает его в своем тесте:
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) database.UserStore {
+//			return database.NewMemoryStore()
+//		})
+//	}
+//
+// Реализации для SQLite и PostgreSQL подключены в internal/database:
+// sqlite_test.go и postgres_test.go. Для PostgreSQL нужна отдельная база,
+// DSN берется из TEST_POSTGRES_DSN, и без него тест пропускается.
+//
+// NewSQLiteDB - общая тестовая база SQLite для тестов хранилища и обработчиков.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+)
+
+// Factory создает пустое хранилище для одного подтеста
+type Factory func(t *testing.T) database.UserStore
+
+// Run прогоняет все проверки на свежем хранилище для каждого подтеста
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store database.UserStore)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"NotFound", testNotFound},
+		{"UniqueLogin", testUniqueLogin},
+		{"UniqueGameSurname", testUniqueGameSurname},
+		{"UniqueEmail", testUniqueEmail},
+		{"UniqueIsCanonical", testUniqueIsCanonical},
+		{"LookupIsCanonical", testLookupIsCanonical},
+		{"GameSurnameHomoglyphs", testGameSurnameHomoglyphs},
+		{"GameSurnameLatin", testGameSurnameLatin},
+		{"UpdateUser", testUpdateUser},
+		{"UpdateUserConflict", testUpdateUserConflict},
+		{"UpdateUserNotFound", testUpdateUserNotFound},
+		{"UpdatePassword", testUpdatePassword},
+		{"SetPasswordBreached", testSetPasswordBreached},
+		{"UserExists", testUserExists},
+		{"CanceledContext", testCanceledContext},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"NameRules", testNameRules},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newUser(n int) *models.User {
+	return &models.User{
+		Login:       fmt.Sprintf("player%d", n),
+		GameSurname: fmt.Sprintf("Surname%d", n),
+		Email:       fmt.Sprintf("player%d@example.com", n),
+		Password:    "hash",
+		Locale:      "ru",
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func mustCreate(t *testing.T, store database.UserStore, user *models.User) {
+	t.Helper()
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", user.Login, err)
+	}
+}
+
+func testCreateAndGet(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	mustCreate(t, store, user)
+	if user.ID == 0 {
+		t.Fatal("CreateUser did not assign ID")
+	}
+
+	second := newUser(2)
+	mustCreate(t, store, second)
+	if second.ID == user.ID {
+		t.Fatalf("CreateUser assigned duplicate ID %d", second.ID)
+	}
+
+	lookups := map[string]func() (*models.User, error){
+		"GetUserByID":    func() (*models.User, error) { return store.GetUserByID(ctx, user.ID) },
+		"GetUserByLogin": func() (*models.User, error) { return store.GetUserByLogin(ctx, user.Login) },
+		"GetUserByEmail": func() (*models.User, error) { return store.GetUserByEmail(ctx, user.Email) },
+		"GetUserByGameSurname": func() (*models.User, error) {
+			return store.GetUserByGameSurname(ctx, user.GameSurname)
+		},
+	}
+	for name, lookup := range lookups {
+		got, err := lookup()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.ID != user.ID || got.Login != user.Login || got.GameSurname != user.GameSurname ||
+			got.Email != user.Email || got.Password != user.Password || got.Locale != user.Locale {
+			t.Errorf("%s = %+v, want %+v", name, got, user)
+		}
+		if got.CreatedAt.IsZero() {
+			t.Errorf("%s: CreatedAt is not set", name)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	if _, err := store.GetUserByID(ctx, 42); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUserByID: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetUserByLogin(ctx, "nobody"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUserByLogin: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUserByEmail: got %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetUserByGameSurname(ctx, "Nobody"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUserByGameSurname: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func testUniqueLogin(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	mustCreate(t, store, newUser(1))
+
+	dup := newUser(2)
+	dup.Login = newUser(1).Login
+	if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrLoginTaken) {
+		t.Errorf("CreateUser with taken login: got %v, want ErrLoginTaken", err)
+	}
+}
+
+func testUniqueGameSurname(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	mustCreate(t, store, newUser(1))
+
+	dup := newUser(2)
+	dup.GameSurname = newUser(1).GameSurname
+	if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrGameSurnameTaken) {
+		t.Errorf("CreateUser with taken game surname: got %v, want ErrGameSurnameTaken", err)
+	}
+}
+
+func testUniqueEmail(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	mustCreate(t, store, newUser(1))
+
+	dup := newUser(2)
+	dup.Email = newUser(1).Email
+	if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrEmailTaken) {
+		t.Errorf("CreateUser with taken email: got %v, want ErrEmailTaken", err)
+	}
+}
+
+// Логин и email сравниваются в каноническом виде: без регистра, пробелов
+// по краям и с NFKC, поэтому "PLAYER1" и "ｐｌａｙｅｒ１" - тот же логин
+func testUniqueIsCanonical(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	mustCreate(t, store, newUser(1))
+
+	for _, login := range []string{"PLAYER1", " Player1 ", "ｐｌａｙｅｒ１"} {
+		dup := newUser(2)
+		dup.Login = login
+		if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrLoginTaken) {
+			t.Errorf("CreateUser with login %q: got %v, want ErrLoginTaken", login, err)
+		}
+	}
+
+	dup := newUser(2)
+	dup.Email = "Player1@Example.COM"
+	if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrEmailTaken) {
+		t.Errorf("CreateUser with email differing only in case: got %v, want ErrEmailTaken", err)
+	}
+
+	other := newUser(3)
+	mustCreate(t, store, other)
+	err := store.UpdateUser(ctx, other.ID, database.UserUpdate{Email: ptr("PLAYER1@example.com")})
+	if !errors.Is(err, database.ErrEmailTaken) {
+		t.Errorf("UpdateUser to email differing only in case: got %v, want ErrEmailTaken", err)
+	}
+}
+
+func testLookupIsCanonical(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	user.Login = "Player1"
+	user.Email = "Player1@Example.com"
+	mustCreate(t, store, user)
+
+	byLogin, err := store.GetUserByLogin(ctx, " PLAYER1")
+	if err != nil {
+		t.Fatalf("GetUserByLogin: %v", err)
+	}
+	if byLogin.ID != user.ID || byLogin.Login != "Player1" {
+		t.Errorf("GetUserByLogin: got %d %q, want %d with login as entered", byLogin.ID, byLogin.Login, user.ID)
+	}
+
+	byEmail, err := store.GetUserByEmail(ctx, "player1@EXAMPLE.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if byEmail.ID != user.ID || byEmail.Email != "Player1@Example.com" {
+		t.Errorf("GetUserByEmail: got %d %q, want %d with email as entered", byEmail.ID, byEmail.Email, user.ID)
+	}
+
+	if exists, err := store.UserExists(ctx, "player1"); err != nil || !exists {
+		t.Errorf("UserExists: got %v, %v, want true", exists, err)
+	}
+}
+
+func testGameSurnameHomoglyphs(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	user.GameSurname = "Ivanov"
+	mustCreate(t, store, user)
+
+	// В "Ivаnov" и "Ivanоv" кириллические "а" и "о"
+	for _, surname := range []string{"Ivаnov", "Ivanоv", "IVANOV"} {
+		dup := newUser(2)
+		dup.GameSurname = surname
+		if err := store.CreateUser(ctx, dup); !errors.Is(err, database.ErrGameSurnameTaken) {
+			t.Errorf("CreateUser with game surname %q: got %v, want ErrGameSurnameTaken", surname, err)
+		}
+	}
+
+	got, err := store.GetUserByGameSurname(ctx, "Ivаnov")
+	if err != nil {
+		t.Fatalf("GetUserByGameSurname: %v", err)
+	}
+	if got.ID != user.ID || got.GameSurname != "Ivanov" {
+		t.Errorf("GetUserByGameSurname: got %d %q, want %d with surname as entered", got.ID, got.GameSurname, user.ID)
+	}
+
+	coco := newUser(3)
+	coco.GameSurname = "Coco"
+	mustCreate(t, store, coco)
+	other := newUser(4)
+	mustCreate(t, store, other)
+	// "Сосо" целиком кириллицей
+	err = store.UpdateUser(ctx, other.ID, database.UserUpdate{GameSurname: ptr("Сосо")})
+	if !errors.Is(err, database.ErrGameSurnameTaken) {
+		t.Errorf("UpdateUser to Cyrillic look-alike surname: got %v, want ErrGameSurnameTaken", err)
+	}
+
+	// Та же фамилия, записанная другими буквами, - другая фамилия
+	ivanov := newUser(5)
+	ivanov.GameSurname = "Иванов"
+	mustCreate(t, store, ivanov)
+}
+
+func testGameSurnameLatin(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	user.GameSurname = "Щукина"
+	mustCreate(t, store, user)
+	if user.GameSurnameLatin != "Shchukina" {
+		t.Errorf("CreateUser: GameSurnameLatin = %q, want Shchukina", user.GameSurnameLatin)
+	}
+
+	got, err := store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.GameSurnameLatin != "Shchukina" {
+		t.Errorf("GetUserByID: GameSurnameLatin = %q, want Shchukina", got.GameSurnameLatin)
+	}
+
+	if err := store.UpdateUser(ctx, user.ID, database.UserUpdate{GameSurname: ptr("Ёлкин")}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if got, err = store.GetUserByGameSurname(ctx, "Ёлкин"); err != nil {
+		t.Fatalf("GetUserByGameSurname: %v", err)
+	}
+	if got.GameSurnameLatin != "Elkin" {
+		t.Errorf("after UpdateUser: GameSurnameLatin = %q, want Elkin", got.GameSurnameLatin)
+	}
+
+	latin := newUser(2)
+	mustCreate(t, store, latin)
+	if latin.GameSurnameLatin != latin.GameSurname {
+		t.Errorf("Latin surname: GameSurnameLatin = %q, want %q", latin.GameSurnameLatin, latin.GameSurname)
+	}
+}
+
+func testUpdateUser(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	mustCreate(t, store, user)
+
+	err := store.UpdateUser(ctx, user.ID, database.UserUpdate{
+		GameSurname: ptr("Updated"),
+		Email:       ptr("updated@example.com"),
+		Locale:      ptr("en"),
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	got, err := store.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.GameSurname != "Updated" || got.Email != "updated@example.com" || got.Locale != "en" {
+		t.Errorf("after UpdateUser got %+v", got)
+	}
+
+	// Старый email освобождается, новый находится
+	if _, err := store.GetUserByEmail(ctx, user.Email); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUserByEmail(old): got %v, want ErrUserNotFound", err)
+	}
+	if _, err := store.GetUserByEmail(ctx, "updated@example.com"); err != nil {
+		t.Errorf("GetUserByEmail(new): %v", err)
+	}
+
+	// Обновление на собственное значение не считается конфликтом
+	if err := store.UpdateUser(ctx, user.ID, database.UserUpdate{Email: ptr("updated@example.com")}); err != nil {
+		t.Errorf("UpdateUser with own email: %v", err)
+	}
+}
+
+func testUpdateUserConflict(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	first, second := newUser(1), newUser(2)
+	mustCreate(t, store, first)
+	mustCreate(t, store, second)
+
+	if err := store.UpdateUser(ctx, second.ID, database.UserUpdate{Email: &first.Email}); !errors.Is(err, database.ErrEmailTaken) {
+		t.Errorf("UpdateUser to taken email: got %v, want ErrEmailTaken", err)
+	}
+	if err := store.UpdateUser(ctx, second.ID, database.UserUpdate{GameSurname: &first.GameSurname}); !errors.Is(err, database.ErrGameSurnameTaken) {
+		t.Errorf("UpdateUser to taken game surname: got %v, want ErrGameSurnameTaken", err)
+	}
+
+	got, err := store.GetUserByID(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Email != second.Email || got.GameSurname != second.GameSurname {
+		t.Errorf("failed UpdateUser changed the user: %+v", got)
+	}
+}
+
+func testUpdateUserNotFound(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	if err := store.UpdateUser(ctx, 42, database.UserUpdate{Email: ptr("x@example.com")}); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("UpdateUser: got %v, want ErrUserNotFound", err)
+	}
+	if err := store.UpdatePassword(ctx, 42, "hash"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("UpdatePassword: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func testUpdatePassword(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	mustCreate(t, store, user)
+
+	if err := store.UpdatePassword(ctx, user.ID, "new-hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+
+	got, err := store.GetUserByLogin(ctx, user.Login)
+	if err != nil {
+		t.Fatalf("GetUserByLogin: %v", err)
+	}
+	if got.Password != "new-hash" {
+		t.Errorf("Password = %q, want new-hash", got.Password)
+	}
+}
+
+func testSetPasswordBreached(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	mustCreate(t, store, user)
+
+	for _, breached := range []bool{true, false} {
+		if err := store.SetPasswordBreached(ctx, user.ID, breached); err != nil {
+			t.Fatalf("SetPasswordBreached(%v): %v", breached, err)
+		}
+		got, err := store.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if got.PasswordBreached != breached {
+			t.Errorf("PasswordBreached = %v, want %v", got.PasswordBreached, breached)
+		}
+	}
+
+	if err := store.SetPasswordBreached(ctx, 9999, true); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("SetPasswordBreached for missing user: got %v, want ErrUserNotFound", err)
+	}
+}
+
+func testUserExists(t *testing.T, store database.UserStore) {
+	ctx := context.Background()
+	user := newUser(1)
+	mustCreate(t, store, user)
+
+	exists, err := store.UserExists(ctx, user.Login)
+	if err != nil || !exists {
+		t.Errorf("UserExists(%q) = %v, %v; want true", user.Login, exists, err)
+	}
+	exists, err = store.UserExists(ctx, "nobody")
+	if err != nil || exists {
+		t.Errorf("UserExists(nobody) = %v, %v; want false", exists, err)
+	}
+}
+
+func testCanceledContext(t *testing.T, store database.UserStore) {
+	user := newUser(1)
+	mustCreate(t, store, user)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.GetUserByID(canceled, user.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetUserByID with canceled context: got %v, want context.Canceled", err)
+	}
+	if err := store.CreateUser(canceled, newUser(2)); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateUser with canceled context: got %v, want context.Canceled", err)
+	}
+}
+
+// Параллельные регистрации с одинаковыми полями: ровно одна проходит,
+// остальные получают конфликт нужного поля, а не произвольную ошибку
+func testConcurrentCreate(t *testing.T, store database.UserStore) {
+	const workers = 16
+
+	conflicts := []struct {
+		field   string
+		wantErr error
+		user    func(n int) *models.User
+	}{
+		{"login", database.ErrLoginTaken, func(n int) *models.User {
+			user := newUser(100 + n)
+			user.Login = "samelogin"
+			return user
+		}},
+		{"game_surname", database.ErrGameSurnameTaken, func(n int) *models.User {
+			user := newUser(200 + n)
+			user.GameSurname = "Samesurname"
+			return user
+		}},
+		{"email", database.ErrEmailTaken, func(n int) *models.User {
+			user := newUser(300 + n)
+			user.Email = "same@example.com"
+			return user
+		}},
+	}
+
+	for _, conflict := range conflicts {
+		errs := make([]error, workers)
+		var wg sync.WaitGroup
+		for n := 0; n < workers; n++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				errs[n] = store.CreateUser(context.Background(), conflict.user(n))
+			}(n)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, conflict.wantErr):
+				t.Errorf("%s: concurrent CreateUser: got %v, want %v", conflict.field, err, conflict.wantErr)
+			}
+		}
+		if created != 1 {
+			t.Errorf("%s: %d concurrent CreateUser calls succeeded, want exactly 1", conflict.field, created)
+		}
+	}
+}
+
+func testNameRules(t *testing.T, store database.UserStore) {
+	rules, ok := store.(database.NameRuleStore)
+	if !ok {
+		t.Skip("store does not keep name rules")
+	}
+	ctx := context.Background()
+
+	block := &models.NameRule{Pattern: "*petrov*", Action: models.NameRuleBlock, Note: "test"}
+	allow := &models.NameRule{Pattern: "*petrov*", Action: models.NameRuleAllow}
+	for _, rule := range []*models.NameRule{block, allow} {
+		if err := rules.AddNameRule(ctx, rule); err != nil {
+			t.Fatalf("AddNameRule(%s): %v", rule.Action, err)
+		}
+		if rule.ID == 0 || rule.CreatedAt.IsZero() {
+			t.Errorf("AddNameRule did not fill ID and CreatedAt: %+v", rule)
+		}
+	}
+	if err := rules.AddNameRule(ctx, &models.NameRule{Pattern: "*petrov*", Action: models.NameRuleBlock}); !errors.Is(err, database.ErrNameRuleExists) {
+		t.Errorf("duplicate AddNameRule: got %v, want ErrNameRuleExists", err)
+	}
+
+	list, err := rules.ListNameRules(ctx)
+	if err != nil {
+		t.Fatalf("ListNameRules: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != block.ID || list[0].Note != "test" || list[1].ID != allow.ID {
+		t.Errorf("ListNameRules = %+v, want block then allow", list)
+	}
+
+	if err := rules.DeleteNameRule(ctx, block.ID); err != nil {
+		t.Fatalf("DeleteNameRule: %v", err)
+	}
+	if err := rules.DeleteNameRule(ctx, block.ID); !errors.Is(err, database.ErrNameRuleNotFound) {
+		t.Errorf("second DeleteNameRule: got %v, want ErrNameRuleNotFound", err)
+	}
+	list, err = rules.ListNameRules(ctx)
+	if err != nil {
+		t.Fatalf("ListNameRules: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != allow.ID {
+		t.Errorf("ListNameRules after delete = %+v, want only the allow rule", list)
+	}
+}
@@ -0,0 +1,215 @@
+// Package fieldcrypt шифрует отдельные поля записей (email и другие
+// персональные данные) перед записью в базу, чтобы копия файла базы или дамп
+// не раскрывали их.
+//
+// Шифрование конвертное: каждое значение шифруется своим случайным ключом
+// данных (AES-256-GCM), а ключ данных - ключом шифрования ключей выбранной
+// версии. Версия записывается в значение, поэтому ключи можно менять:
+// новые значения шифруются текущей версией, старые читаются своей, пока их
+// не перешифруют.
+//
+// Случайное шифрование не позволяет искать по значению, поэтому рядом
+// хранится слепой индекс - HMAC значения отдельным ключом. По нему работают
+// поиск и UNIQUE-ограничение.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix отмечает зашифрованное значение:
+// enc:<версия ключа>:<зашифрованный ключ данных>:<nonce и шифртекст>
+const prefix = "enc:"
+
+// indexLength - длина слепого индекса в байтах; 128 бит исключают случайные совпадения
+const indexLength = 16
+
+// keyLength - длина ключей AES-256
+const keyLength = 32
+
+var (
+	// ErrUnknownKey - значение зашифровано ключом, версии которого нет в конфигурации
+	ErrUnknownKey = errors.New("field encrypted with an unknown key version")
+	// ErrCorrupted - значение повреждено или зашифровано для другого поля
+	ErrCorrupted = errors.New("encrypted field is corrupted")
+	// ErrNotConfigured - значение зашифровано, а ключи не заданы
+	ErrNotConfigured = errors.New("field is encrypted but encryption keys are not configured")
+)
+
+// Codec шифрует и расшифровывает поля. Безопасен для параллельного использования.
+type Codec struct {
+	current string
+	keys    map[string][]byte
+	index   []byte
+}
+
+// New готовит Codec. keys - секреты ключей шифрования по версиям, current -
+// версия для новых значений, indexKey - секрет слепого индекса. Из секретов
+// выводятся ключи AES через HKDF, поэтому длина секрета может быть любой
+// (но не меньше 32 байт энтропии).
+func New(current string, keys map[string][]byte, indexKey []byte) (*Codec, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("current key version %q has no key", current)
+	}
+	if len(indexKey) == 0 {
+		return nil, errors.New("blind index key is empty")
+	}
+
+	c := &Codec{current: current, keys: make(map[string][]byte, len(keys))}
+	for version, secret := range keys {
+		if version == "" || strings.Contains(version, ":") {
+			return nil, fmt.Errorf("invalid key version %q", version)
+		}
+		key, err := hkdf.Key(sha256.New, secret, nil, "loil-fieldcrypt-kek", keyLength)
+		if err != nil {
+			return nil, err
+		}
+		c.keys[version] = key
+	}
+	index, err := hkdf.Key(sha256.New, indexKey, nil, "loil-fieldcrypt-index", keyLength)
+	if err != nil {
+		return nil, err
+	}
+	c.index = index
+	return c, nil
+}
+
+// CurrentVersion - версия ключа, которой шифруются новые значения
+func (c *Codec) CurrentVersion() string {
+	return c.current
+}
+
+// Encrypt шифрует значение поля field текущим ключом. Имя поля входит в
+// аутентифицированные данные: шифртекст email нельзя подставить в другое поле.
+func (c *Codec) Encrypt(field, plaintext string) (string, error) {
+	dataKey := make([]byte, keyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+
+	wrapped, err := seal(c.keys[c.current], []byte(c.current), dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(field), []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + c.current + ":" +
+		base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение поля field. Незашифрованные значения,
+// оставшиеся с тех пор, как шифрование не было включено, возвращаются как есть.
+func (c *Codec) Decrypt(field, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	version, wrapped, sealed, ok := split(value)
+	if !ok {
+		return "", ErrCorrupted
+	}
+	kek, known := c.keys[version]
+	if !known {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, version)
+	}
+
+	dataKey, err := open(kek, []byte(version), wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, []byte(field), sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex - детерминированный ключевой хеш значения поля field для поиска
+// и проверки уникальности. Без ключа индекса по нему нельзя перебрать значения.
+func (c *Codec) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, c.index)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:indexLength])
+}
+
+// Version возвращает версию ключа зашифрованного значения; ok равен false
+// для незашифрованного значения
+func Version(value string) (version string, ok bool) {
+	version, _, _, ok = split(value)
+	return version, ok
+}
+
+// IsEncrypted сообщает, что значение записано Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+func split(value string) (version string, wrapped, sealed []byte, ok bool) {
+	rest, found := strings.CutPrefix(value, prefix)
+	if !found {
+		return "", nil, nil, false
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", nil, nil, false
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, false
+	}
+	sealed, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, false
+	}
+	return parts[0], wrapped, sealed, true
+}
+
+// seal шифрует AES-256-GCM и возвращает nonce вместе с шифртекстом
+func seal(key, additionalData, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, additionalData, sealed []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCorrupted
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
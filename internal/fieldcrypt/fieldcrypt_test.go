@@ -0,0 +1,215 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	key1     = bytes.Repeat([]byte{1}, 32)
+	key2     = bytes.Repeat([]byte{2}, 32)
+	indexKey = bytes.Repeat([]byte{9}, 32)
+)
+
+func newCodec(t *testing.T, current string, keys map[string][]byte) *Codec {
+	t.Helper()
+	c, err := New(current, keys, indexKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	for _, plaintext := range []string{"john@example.com", "", "почта@пример.рф", strings.Repeat("x", 1000)} {
+		encrypted, err := c.Encrypt("email", plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if !IsEncrypted(encrypted) {
+			t.Errorf("Encrypt(%q) = %q, not marked as encrypted", plaintext, encrypted)
+		}
+		if plaintext != "" && strings.Contains(encrypted, plaintext) {
+			t.Errorf("Encrypt(%q) leaks the plaintext: %q", plaintext, encrypted)
+		}
+		if version, ok := Version(encrypted); !ok || version != "1" {
+			t.Errorf("Version(%q) = %q, %v, want 1, true", encrypted, version, ok)
+		}
+
+		decrypted, err := c.Decrypt("email", encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt = %q, want %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestEncryptIsRandomized(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	a, _ := c.Encrypt("email", "john@example.com")
+	b, _ := c.Encrypt("email", "john@example.com")
+	if a == b {
+		t.Error("two encryptions of the same value are equal")
+	}
+}
+
+func TestDecryptPlaintextPassesThrough(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	got, err := c.Decrypt("email", "john@example.com")
+	if err != nil || got != "john@example.com" {
+		t.Errorf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+	if _, ok := Version("john@example.com"); ok {
+		t.Error("Version reports a plaintext value as encrypted")
+	}
+}
+
+func TestDecryptWrongField(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	encrypted, err := c.Encrypt("email", "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Decrypt("login", encrypted); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Decrypt with another field = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestDecryptCorrupted(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	encrypted, err := c.Encrypt("email", "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(encrypted, ":")
+
+	// Замена первого символа base64 меняет первый байт
+	flip := func(s string) string {
+		replacement := "A"
+		if s[0] == 'A' {
+			replacement = "B"
+		}
+		return replacement + s[1:]
+	}
+
+	tests := map[string]string{
+		"no parts":       "enc:1",
+		"bad base64":     "enc:1:!!!:" + parts[3],
+		"short sealed":   "enc:1:" + parts[2] + ":AAAA",
+		"tampered data":  strings.Join([]string{parts[0], parts[1], parts[2], flip(parts[3])}, ":"),
+		"tampered key":   strings.Join([]string{parts[0], parts[1], flip(parts[2]), parts[3]}, ":"),
+		"too many parts": encrypted + ":x",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := c.Decrypt("email", value); !errors.Is(err, ErrCorrupted) {
+				t.Errorf("Decrypt(%q) = %v, want ErrCorrupted", value, err)
+			}
+		})
+	}
+}
+
+func TestDecryptAfterRotation(t *testing.T) {
+	old := newCodec(t, "1", map[string][]byte{"1": key1})
+	encrypted, err := old.Encrypt("email", "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newCodec(t, "2", map[string][]byte{"1": key1, "2": key2})
+	if rotated.CurrentVersion() != "2" {
+		t.Errorf("CurrentVersion = %q, want 2", rotated.CurrentVersion())
+	}
+	if got, err := rotated.Decrypt("email", encrypted); err != nil || got != "john@example.com" {
+		t.Errorf("Decrypt of a value under the previous key = %q, %v", got, err)
+	}
+
+	reencrypted, err := rotated.Encrypt("email", "john@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := Version(reencrypted); version != "2" {
+		t.Errorf("new value encrypted with key %q, want 2", version)
+	}
+
+	// Ключ 1 убран из конфигурации раньше, чем значения перешифрованы
+	retired := newCodec(t, "2", map[string][]byte{"2": key2})
+	if _, err := retired.Decrypt("email", encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with a removed key = %v, want ErrUnknownKey", err)
+	}
+
+	// Тот же номер версии с другим секретом - подмена ключа, а не ротация
+	replaced := newCodec(t, "1", map[string][]byte{"1": key2})
+	if _, err := replaced.Decrypt("email", encrypted); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Decrypt with a different secret = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	c := newCodec(t, "1", map[string][]byte{"1": key1})
+
+	index := c.BlindIndex("email", "john@example.com")
+	if index != c.BlindIndex("email", "john@example.com") {
+		t.Error("blind index is not deterministic")
+	}
+	// Значение зафиксировано: при его изменении индексы в базах перестанут
+	// совпадать и поиск по email сломается
+	if want := "SQ3HkabKLxJJwqofVJrHmQ"; index != want {
+		t.Errorf("BlindIndex = %q, want %q", index, want)
+	}
+	if c.BlindIndex("email", "jane@example.com") == index {
+		t.Error("different values have the same index")
+	}
+	if c.BlindIndex("login", "john@example.com") == index {
+		t.Error("different fields have the same index")
+	}
+	// Разделитель не дает склеить поле со значением
+	if c.BlindIndex("ab", "c") == c.BlindIndex("a", "bc") {
+		t.Error("field and value boundary is ambiguous")
+	}
+
+	// Ротация ключей шифрования не меняет индекс
+	rotated := newCodec(t, "2", map[string][]byte{"1": key1, "2": key2})
+	if rotated.BlindIndex("email", "john@example.com") != index {
+		t.Error("blind index depends on the encryption keys")
+	}
+
+	other, err := New("1", map[string][]byte{"1": key1}, bytes.Repeat([]byte{8}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.BlindIndex("email", "john@example.com") == index {
+		t.Error("blind index does not depend on the index key")
+	}
+}
+
+func TestNewRejectsBadKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		keys     map[string][]byte
+		indexKey []byte
+	}{
+		{"missing current", "2", map[string][]byte{"1": key1}, indexKey},
+		{"empty index key", "1", map[string][]byte{"1": key1}, nil},
+		{"empty version", "1", map[string][]byte{"1": key1, "": key2}, indexKey},
+		{"colon in version", "1", map[string][]byte{"1": key1, "a:b": key2}, indexKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.current, tt.keys, tt.indexKey); err == nil {
+				t.Error("New accepted invalid keys")
+			}
+		})
+	}
+}
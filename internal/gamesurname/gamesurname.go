@@ -0,0 +1,156 @@
+// Package gamesurname проверяет игровые фамилии по алфавитам из конфигурации
+// и переводит их в латиницу для игровых клиентов, которые не показывают
+// кириллицу. Уникальность фамилий с учетом похожих букв - в пакете canonical.
+package gamesurname
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/validation"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Длина фамилии в буквах
+const (
+	minLength = 2
+	maxLength = 20
+)
+
+type alphabet struct {
+	// class - класс символов регулярного выражения, совместимый с JavaScript RegExp
+	class    string
+	contains func(r rune) bool
+}
+
+var alphabets = map[string]alphabet{
+	config.AlphabetLatin: {
+		class: "a-zA-Z",
+		contains: func(r rune) bool {
+			return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+		},
+	},
+	// Русский алфавит; ё в Unicode стоит вне диапазона а-я
+	config.AlphabetCyrillic: {
+		class: "а-яА-ЯёЁ",
+		contains: func(r rune) bool {
+			return 'а' <= r && r <= 'я' || 'А' <= r && r <= 'Я' || r == 'ё' || r == 'Ё'
+		},
+	},
+}
+
+// Policy - из каких букв можно составить новую фамилию. Те же значения
+// отдаются клиентам через GET /api/auth/validation-rules.
+type Policy struct {
+	// Alphabets - допустимые алфавиты; пусто - только латиница
+	Alphabets []string `json:"alphabets"`
+}
+
+// PolicyFrom переводит настройки из конфигурации в политику
+func PolicyFrom(cfg config.GameSurnameConfig) Policy {
+	return Policy{Alphabets: cfg.Alphabets}
+}
+
+func (p Policy) alphabets() []alphabet {
+	names := p.Alphabets
+	if len(names) == 0 {
+		names = []string{config.AlphabetLatin}
+	}
+	result := make([]alphabet, 0, len(names))
+	for _, name := range names {
+		if a, ok := alphabets[name]; ok {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// compiledRules - готовые правила по шаблону. Политика строится из
+// конфигурации на каждый запрос, а разных наборов алфавитов всего несколько,
+// поэтому выражение компилируется один раз на набор.
+var compiledRules sync.Map
+
+// Rule - правило поля для форм клиентов: фамилия целиком из букв одного алфавита
+func (p Policy) Rule() validation.Rule {
+	allowed := p.alphabets()
+	words := make([]string, 0, len(allowed))
+	for _, a := range allowed {
+		words = append(words, "["+a.class+"]+")
+	}
+	pattern := "^(?:" + strings.Join(words, "|") + ")$"
+
+	if rule, ok := compiledRules.Load(pattern); ok {
+		return rule.(validation.Rule)
+	}
+	rule := validation.NewRule(validation.Rule{
+		Required:  true,
+		MinLength: minLength,
+		MaxLength: maxLength,
+		Pattern:   pattern,
+	})
+	compiledRules.Store(pattern, rule)
+	return rule
+}
+
+// Check проверяет фамилию. Если все буквы допустимы, но взяты из разных
+// алфавитов ("Ivаnov" с кириллической "а"), возвращается отдельный код
+// CodeMixedAlphabets, чтобы клиент объяснил, что не так.
+func (p Policy) Check(field, value string) *validation.FieldError {
+	value = norm.NFC.String(value)
+	fieldErr := p.Rule().Check(field, value)
+	if fieldErr == nil || fieldErr.Code != validation.CodeInvalidFormat {
+		return fieldErr
+	}
+	if p.allLetters(value) {
+		return validation.NewFieldError(field, validation.CodeMixedAlphabets, nil)
+	}
+	return fieldErr
+}
+
+// allLetters сообщает, что каждая буква есть хотя бы в одном допустимом алфавите
+func (p Policy) allLetters(value string) bool {
+	allowed := p.alphabets()
+	for _, r := range value {
+		found := false
+		for _, a := range allowed {
+			if a.contains(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Transliterate записывает фамилию латиницей по правилам загранпаспорта РФ
+// (ICAO Doc 9303): "Щукина" - "Shchukina", "Ёлкин" - "Elkin". Латинские буквы
+// остаются как есть; заглавная буква кириллицы дает заглавную первую букву.
+func Transliterate(surname string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(surname) {
+		latin, ok := translit[unicode.ToLower(r)]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsUpper(r) && latin != "" {
+			latin = strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		b.WriteString(latin)
+	}
+	return b.String()
+}
+
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+}
@@ -0,0 +1,124 @@
+package gamesurname
+
+import (
+	"testing"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/validation"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Иванов", "Ivanov"},
+		{"Щукина", "Shchukina"},
+		{"Ёлкин", "Elkin"},
+		{"Жуков", "Zhukov"},
+		{"Хабибуллин", "Khabibullin"},
+		{"Цой", "Tsoi"},
+		{"Чайковский", "Chaikovskii"},
+		{"Шишкин", "Shishkin"},
+		{"Юдина", "Iudina"},
+		{"Яковлев", "Iakovlev"},
+		{"Объедков", "Obieedkov"},
+		{"Гоголь", "Gogol"},
+		{"Эйлер", "Eiler"},
+		{"Крылов", "Krylov"},
+		// Латиница не меняется
+		{"Smith", "Smith"},
+		{"", ""},
+		// Составная й собирается перед заменой
+		{"Чайкин", "Chaikin"},
+		// Заглавная буква дает заглавную первую букву сочетания
+		{"ЩУКИН", "ShchUKIN"},
+	}
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	latin := PolicyFrom(config.GameSurnameConfig{Alphabets: []string{config.AlphabetLatin}})
+	both := PolicyFrom(config.GameSurnameConfig{Alphabets: []string{config.AlphabetLatin, config.AlphabetCyrillic}})
+
+	tests := []struct {
+		name   string
+		policy Policy
+		value  string
+		code   string
+	}{
+		{"latin", latin, "Ivanov", ""},
+		{"empty policy is latin", Policy{}, "Ivanov", ""},
+		{"cyrillic not allowed", latin, "Иванов", validation.CodeInvalidFormat},
+		{"cyrillic allowed", both, "Иванов", ""},
+		{"yo allowed", both, "Ёлкин", ""},
+		{"decomposed short i", both, "Чайкин", ""},
+		{"mixed alphabets", both, "Ivаnov", validation.CodeMixedAlphabets},
+		{"mixed with latin only", latin, "Ivаnov", validation.CodeInvalidFormat},
+		{"ukrainian i", both, "Іванов", validation.CodeInvalidFormat},
+		{"digits", both, "Ivanov2", validation.CodeInvalidFormat},
+		{"space", both, "Van Dyke", validation.CodeInvalidFormat},
+		{"required", both, "", validation.CodeRequired},
+		{"too short", both, "I", validation.CodeTooShort},
+		{"too long", both, "Abcdefghijklmnopqrstu", validation.CodeTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldErr := tt.policy.Check("gameSurname", tt.value)
+			code := ""
+			if fieldErr != nil {
+				code = fieldErr.Code
+			}
+			if code != tt.code {
+				t.Errorf("Check(%q) = %q, want %q", tt.value, code, tt.code)
+			}
+		})
+	}
+}
+
+func TestRuleCompiledOnce(t *testing.T) {
+	cfg := config.GameSurnameConfig{Alphabets: []string{config.AlphabetLatin, config.AlphabetCyrillic}}
+	first := PolicyFrom(cfg).Rule()
+	second := PolicyFrom(cfg).Rule()
+	if first.Pattern != second.Pattern || first.Check("f", "Иванов") != nil {
+		t.Fatalf("unexpected rule %q", first.Pattern)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		PolicyFrom(cfg).Check("gameSurname", "Иванов")
+	})
+	// Компиляция выражения заняла бы десятки выделений
+	if allocs > 10 {
+		t.Errorf("Check allocates %.0f times per call, the rule is probably recompiled", allocs)
+	}
+}
+
+// Каждая буква русского алфавита, неотличимая от латинской, сводится к
+// латинской: иначе "Сосо" заняла бы фамилию отдельно от "Coco"
+func TestHomoglyphSurnamesCollide(t *testing.T) {
+	pairs := [][2]string{
+		{"Coco", "Сосо"},
+		{"Ivanov", "Ivаnov"},
+		{"Hope", "Норе"},
+		{"Kamtex", "Камтех"},
+		{"Bypte", "Вурте"},
+	}
+	both := Policy{Alphabets: []string{config.AlphabetLatin, config.AlphabetCyrillic}}
+	for _, pair := range pairs {
+		if canonical.Surname(pair[0]) != canonical.Surname(pair[1]) {
+			t.Errorf("%q and %q look the same but are different surnames", pair[0], pair[1])
+		}
+		if both.Check("gameSurname", pair[0]) != nil {
+			t.Errorf("%q rejected", pair[0])
+		}
+	}
+
+	// Фамилии, которые различаются на вид, остаются разными
+	if canonical.Surname("Петров") == canonical.Surname("Petrov") {
+		t.Error("Петров and Petrov are the same surname")
+	}
+}
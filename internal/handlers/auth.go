@@ -1,46 +1,120 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
+	"LOIL-auth-server/internal/apierror"
 	"LOIL-auth-server/internal/config"
 	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/mail"
 	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/namepolicy"
+	"LOIL-auth-server/internal/passwordpolicy"
 	"LOIL-auth-server/internal/utils"
+	"LOIL-auth-server/internal/validation"
 	"LOIL-auth-server/pkg/logger"
 )
 
 type AuthHandler struct {
-	db     *database.SQLiteDB
-	logger *logger.Logger
+	db       database.UserStore
+	hasher   *hashing.Pool
+	breaches passwordpolicy.BreachList
+	names    *namepolicy.Filter
+	mailer   mail.Sender
+	cfg      *config.Manager
+	logger   *logger.Logger
 }
 
-func NewAuthHandler(db *database.SQLiteDB, logger *logger.Logger) *AuthHandler {
+// NewAuthHandler создает обработчик; breaches - база утечек или nil, если проверка
+// выключена; names - фильтр логинов и фамилий; mailer отправляет письма о результате регистрации, если включен
+// auth.hide_registration_conflicts
+func NewAuthHandler(db database.UserStore, hasher *hashing.Pool, breaches passwordpolicy.BreachList, names *namepolicy.Filter, mailer mail.Sender, cfg *config.Manager, logger *logger.Logger) *AuthHandler {
 	return &AuthHandler{
-		db:     db,
-		logger: logger,
+		db:       db,
+		hasher:   hasher,
+		breaches: breaches,
+		names:    names,
+		mailer:   mailer,
+		cfg:      cfg,
+		logger:   logger,
 	}
 }
 
+// passwordPolicy - текущая политика паролей. Настройки меняются через SIGHUP,
+// поэтому политика собирается при каждом запросе
+func (h *AuthHandler) passwordPolicy() passwordpolicy.Policy {
+	return passwordpolicy.PolicyFrom(h.cfg.Get().Password).WithBreaches(h.breaches)
+}
+
+// gameSurnamePolicy - текущие допустимые алфавиты фамилий
+func (h *AuthHandler) gameSurnamePolicy() gamesurname.Policy {
+	return gamesurname.PolicyFrom(h.cfg.Get().GameSurname)
+}
+
 type RegisterRequest struct {
 	Login           string `json:"login"`
 	GameSurname     string `json:"gameSurname"`
 	Email           string `json:"email"`
 	Password        string `json:"password"`
 	PasswordConfirm string `json:"passwordConfirm"`
+	// Locale - необязательный язык писем; по умолчанию язык запроса
+	Locale string `json:"locale,omitempty"`
+}
+
+// Validate проверяет все поля и возвращает validation.Errors со всеми нарушениями.
+// Оценка стойкости пароля возвращается и при ошибках, чтобы показать подсказки.
+func (req RegisterRequest) Validate(policy passwordpolicy.Policy, surnames gamesurname.Policy) (passwordpolicy.Strength, error) {
+	var v validation.Validator
+	v.Check("login", req.Login, validation.Login)
+	v.Add(surnames.Check("gameSurname", req.GameSurname))
+	v.Check("email", req.Email, validation.Email)
+	strength, passwordErr := policy.Check("password", req.Password, passwordpolicy.UserInputs{
+		Login:       req.Login,
+		Email:       req.Email,
+		GameSurname: req.GameSurname,
+	})
+	v.Add(passwordErr)
+	v.Match("passwordConfirm", req.PasswordConfirm, req.Password)
+	if req.Locale != "" {
+		v.OneOf("locale", req.Locale, i18n.Locales())
+	}
+	return strength, v.Err()
 }
 
 type LoginRequest struct {
+	// Identifier - логин или email; с auth.login_by_game_surname еще и игровая фамилия
+	Identifier string `json:"identifier"`
+	// Login - прежнее имя поля, используется, если Identifier пуст
 	Login    string `json:"login"`
 	Password string `json:"password"`
 }
 
+// identifier возвращает, как пользователь себя назвал
+func (req LoginRequest) identifier() string {
+	if req.Identifier != "" {
+		return req.Identifier
+	}
+	return req.Login
+}
+
 type AuthResponse struct {
 	Success bool        `json:"success"`
 	Token   string      `json:"token,omitempty"`
 	User    interface{} `json:"user,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	// PasswordStrength - оценка нового пароля при регистрации
+	PasswordStrength *passwordpolicy.Strength `json:"passwordStrength,omitempty"`
+	// Pending - регистрация принята, а ее результат придет письмом
+	// (auth.hide_registration_conflicts); токена в ответе нет
+	Pending bool `json:"pending,omitempty"`
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -49,44 +123,36 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Register: invalid JSON input")
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Invalid input"})
-		return
-	}
-
-	// Валидация
-	if !utils.ValidateLogin(req.Login) {
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Login must be 3-20 characters (letters, numbers, underscore)"})
+		apierror.Write(w, r, apierror.ErrInvalidInput)
 		return
 	}
 
-	if !utils.ValidateGameSurname(req.GameSurname) {
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Game surname must contain only Latin letters (2-20 characters)"})
-		return
-	}
-
-	if !utils.ValidateEmail(req.Email) {
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Invalid email format"})
+	// Валидация всех полей сразу
+	strength, err := req.Validate(h.passwordPolicy(), h.gameSurnamePolicy())
+	if err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
+	strength = strength.Localized(i18n.FromContext(r.Context()))
 
-	if !utils.ValidatePassword(req.Password) {
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Password must be at least 6 characters"})
-		return
-	}
+	// Нормализуем фамилию
+	normalizedSurname := utils.NormalizeGameSurname(req.GameSurname)
 
-	if req.Password != req.PasswordConfirm {
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Passwords do not match"})
+	// Служебные имена, брань и правила администраторов
+	var names validation.Validator
+	names.Add(h.names.Check(r.Context(), "login", req.Login))
+	names.Add(h.names.Check(r.Context(), "gameSurname", normalizedSurname))
+	if err := names.Err(); err != nil {
+		h.logger.Info("Register: name not allowed -", req.Login, normalizedSurname)
+		apierror.Write(w, r, err)
 		return
 	}
 
-	// Нормализуем фамилию
-	normalizedSurname := utils.NormalizeGameSurname(req.GameSurname)
-
 	// Хэшируем пароль
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := h.hasher.Hash(r.Context(), req.Password)
 	if err != nil {
 		h.logger.Error("Register: password hashing failed:", err)
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Server error"})
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -96,40 +162,100 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		GameSurname: normalizedSurname,
 		Email:       req.Email,
 		Password:    hashedPassword,
+		Locale:      req.Locale,
+	}
+	if user.Locale == "" {
+		user.Locale = i18n.FromContext(r.Context())
 	}
 
-	// Сохраняем в базу
-	if err := h.db.CreateUser(user); err != nil {
-		h.logger.Error("Register: database error:", err)
-
-		errorMsg := "Registration failed"
-		switch err.Error() {
-		case "login already exists":
-			errorMsg = "Login already exists"
-		case "game surname already exists":
-			errorMsg = "Game surname already exists"
-		case "email already exists":
-			errorMsg = "Email already exists"
+	// Сохраняем в базу. Со скрытием конфликтов ответ одинаков и при занятом
+	// логине, фамилии или email, а что произошло, узнает владелец email из письма
+	hideConflicts := h.cfg.Get().Auth.HideRegistrationConflicts
+	if err := h.db.CreateUser(r.Context(), user); err != nil {
+		if hideConflicts && isRegistrationConflict(err) {
+			h.logger.Info("Register: conflict reported by email -", err)
+			h.notifyConflict(r, user, err)
+			writePending(w, strength)
+			return
 		}
-
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: errorMsg})
+		h.logger.Error("Register: database error:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	if hideConflicts {
+		h.logger.Info("Register: user created, result sent by email -", user.Login)
+		h.sendEmail(r, user.Email, user.Locale, "welcome", i18n.Params{"login": user.Login, "gameSurname": user.GameSurname})
+		writePending(w, strength)
 		return
 	}
 
 	// Генерируем JWT токен
-	cfg, _ := config.Load()
-	token, err := utils.GenerateJWT(user.ID, user.Login, user.GameSurname, cfg.JWTSecret)
+	jwtCfg := h.cfg.Get().JWT
+	token, err := utils.GenerateJWT(user.ID, user.Login, user.GameSurname, jwtCfg.Secret, jwtCfg.TTL)
 	if err != nil {
 		h.logger.Error("Register: JWT generation failed:", err)
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Server error"})
+		apierror.Write(w, r, err)
 		return
 	}
 
 	h.logger.Info("Register: user created successfully -", user.Login)
 	json.NewEncoder(w).Encode(AuthResponse{
-		Success: true,
-		Token:   token,
-		User:    user.ToResponse(),
+		Success:          true,
+		Token:            token,
+		User:             user.ToResponse(),
+		PasswordStrength: &strength,
+	})
+}
+
+// isRegistrationConflict сообщает, что регистрации помешал занятый логин,
+// фамилия или email
+func isRegistrationConflict(err error) bool {
+	return errors.Is(err, database.ErrLoginTaken) ||
+		errors.Is(err, database.ErrGameSurnameTaken) ||
+		errors.Is(err, database.ErrEmailTaken)
+}
+
+// notifyConflict пишет на указанный при регистрации email, почему аккаунт не
+// создан. Если email уже чей-то, письмо получает его владелец: ему
+// напоминают логин, а не сообщают, что занято еще
+func (h *AuthHandler) notifyConflict(r *http.Request, user *models.User, conflict error) {
+	owner, err := h.db.GetUserByEmail(r.Context(), user.Email)
+	switch {
+	case err == nil:
+		h.sendEmail(r, owner.Email, owner.Locale, "registration_conflict", i18n.Params{"login": owner.Login})
+		return
+	case !errors.Is(err, database.ErrUserNotFound):
+		h.logger.Error("Register: looking up email owner failed:", err)
+		return
+	}
+
+	template := "login_taken"
+	if errors.Is(conflict, database.ErrGameSurnameTaken) {
+		template = "game_surname_taken"
+	}
+	h.sendEmail(r, user.Email, user.Locale, template, i18n.Params{"login": user.Login, "gameSurname": user.GameSurname})
+}
+
+// sendEmail ставит письмо в очередь; ошибки только пишутся в лог, так как
+// ответ клиенту не должен от них зависеть
+func (h *AuthHandler) sendEmail(r *http.Request, to, locale, template string, params i18n.Params) {
+	email, err := i18n.RenderEmail(locale, template, params)
+	if err != nil {
+		h.logger.Error("Register: rendering email failed:", err)
+		return
+	}
+	if err := h.mailer.Send(r.Context(), mail.Message{To: to, Subject: email.Subject, Body: email.Body}); err != nil {
+		h.logger.Error("Register: sending email failed:", err)
+	}
+}
+
+// writePending - ответ на регистрацию, когда ее результат сообщается письмом
+func writePending(w http.ResponseWriter, strength passwordpolicy.Strength) {
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(AuthResponse{
+		Success:          true,
+		Pending:          true,
+		PasswordStrength: &strength,
 	})
 }
 
@@ -139,31 +265,60 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("Login: invalid JSON input")
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Invalid input"})
+		apierror.Write(w, r, apierror.ErrInvalidInput)
 		return
 	}
 
-	// Ищем пользователя
-	user, err := h.db.GetUserByLogin(req.Login)
+	// Ищем пользователя. Ответ не говорит, что именно не совпало:
+	// идентификатор или пароль
+	identifier := req.identifier()
+	user, err := h.findUser(r.Context(), identifier)
+	if errors.Is(err, database.ErrUserNotFound) {
+		h.logger.Error("Login: user not found -", identifierForLog(identifier))
+		// Пароль все равно проверяется, против фиктивного хеша: иначе ответ
+		// для незарегистрированного логина приходил бы заметно быстрее
+		if err := h.hasher.VerifyDummy(r.Context(), req.Password); err != nil {
+			h.logger.Error("Login: password check failed:", err)
+			apierror.Write(w, r, err)
+			return
+		}
+		apierror.Write(w, r, apierror.ErrInvalidCredentials)
+		return
+	}
 	if err != nil {
-		h.logger.Error("Login: user not found -", req.Login)
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Invalid login or password"})
+		h.logger.Error("Login: database error:", err)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	// Проверяем пароль
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		h.logger.Error("Login: invalid password for user -", req.Login)
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Invalid login or password"})
+	match, needsRehash, err := h.hasher.Verify(r.Context(), req.Password, user.Password)
+	if err != nil {
+		h.logger.Error("Login: password check failed:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	if !match {
+		h.logger.Error("Login: invalid password for user -", user.Login)
+		apierror.Write(w, r, apierror.ErrInvalidCredentials)
 		return
 	}
 
+	// Хеш устаревшего алгоритма или с прежними параметрами пересчитываем,
+	// пока пароль известен. Ошибка здесь не мешает входу: попробуем в следующий раз
+	if needsRehash {
+		h.rehashPassword(r, user.ID, req.Password)
+	}
+	if h.breaches != nil && h.cfg.Get().Breach.FlagOnLogin {
+		h.flagBreachedPassword(r, user, req.Password)
+	}
+
 	// Генерируем JWT
-	cfg, _ := config.Load()
-	token, err := utils.GenerateJWT(user.ID, user.Login, user.GameSurname, cfg.JWTSecret)
+	jwtCfg := h.cfg.Get().JWT
+	token, err := utils.GenerateJWT(user.ID, user.Login, user.GameSurname, jwtCfg.Secret, jwtCfg.TTL)
 	if err != nil {
 		h.logger.Error("Login: JWT generation failed:", err)
-		json.NewEncoder(w).Encode(AuthResponse{Success: false, Error: "Server error"})
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -174,3 +329,147 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		User:    user.ToResponse(),
 	})
 }
+
+// findUser ищет пользователя по идентификатору входа. Email отличается по @,
+// которого не бывает в логине и фамилии. Логин проверяется раньше фамилии:
+// если у одного игрока логин совпал с фамилией другого, по этому значению
+// входит первый. На каждый идентификатор находится не больше одного
+// пользователя, поэтому пароль проверяется один раз, как и при входе по логину.
+func (h *AuthHandler) findUser(ctx context.Context, identifier string) (*models.User, error) {
+	if strings.Contains(identifier, "@") {
+		return h.db.GetUserByEmail(ctx, identifier)
+	}
+	user, err := h.db.GetUserByLogin(ctx, identifier)
+	if errors.Is(err, database.ErrUserNotFound) && h.cfg.Get().Auth.LoginByGameSurname {
+		return h.db.GetUserByGameSurname(ctx, utils.NormalizeGameSurname(strings.TrimSpace(identifier)))
+	}
+	return user, err
+}
+
+// identifierForLog скрывает имя почтового ящика: email в базе может храниться
+// зашифрованным, и в логах его быть не должно
+func identifierForLog(identifier string) string {
+	if at := strings.LastIndexByte(identifier, '@'); at >= 0 {
+		return "***" + identifier[at:]
+	}
+	return identifier
+}
+
+func (h *AuthHandler) rehashPassword(r *http.Request, userID int, password string) {
+	hash, err := h.hasher.Hash(r.Context(), password)
+	if err != nil {
+		h.logger.Warn("Login: password rehash skipped for user ID", userID, err)
+		return
+	}
+	if err := h.db.UpdatePassword(r.Context(), userID, hash); err != nil {
+		h.logger.Warn("Login: storing rehashed password failed for user ID", userID, err)
+		return
+	}
+	h.logger.Info("Login: password hash upgraded for user ID", userID)
+}
+
+// flagBreachedPassword сверяет пароль с базой утечек и обновляет отметку
+// аккаунта. Отметка снимается, если пароль сменили в обход сервера или
+// обновили базу; ошибка записи не мешает входу
+func (h *AuthHandler) flagBreachedPassword(r *http.Request, user *models.User, password string) {
+	breached := h.breaches.Contains(password)
+	if breached == user.PasswordBreached {
+		return
+	}
+	if err := h.db.SetPasswordBreached(r.Context(), user.ID, breached); err != nil {
+		h.logger.Warn("Login: storing breached password flag failed for user ID", user.ID, err)
+		return
+	}
+	user.PasswordBreached = breached
+	if breached {
+		h.logger.Warn("Login: password found in breach list for user ID", user.ID)
+	}
+}
+
+type VerifyTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyToken - внутренний эндпоинт для игровых серверов (доступен только через mTLS):
+// проверяет токен игрока и возвращает актуальные данные пользователя
+func (h *AuthHandler) VerifyToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req VerifyTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("VerifyToken: invalid JSON input")
+		apierror.Write(w, r, apierror.ErrInvalidInput)
+		return
+	}
+
+	claims, err := utils.ValidateJWT(req.Token, h.cfg.Get().JWT.VerificationKeys())
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidToken.Wrap(err))
+		return
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), claims.UserID)
+	if err != nil {
+		h.logger.Error("VerifyToken: user lookup failed - ID:", claims.UserID, err)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(AuthResponse{
+		Success: true,
+		User:    user.ToResponse(),
+	})
+}
+
+type ValidationRulesResponse struct {
+	Success           bool                       `json:"success"`
+	Rules             map[string]validation.Rule `json:"rules"`
+	PasswordPolicy    passwordpolicy.Policy      `json:"passwordPolicy"`
+	GameSurnamePolicy gamesurname.Policy         `json:"gameSurnamePolicy"`
+}
+
+// ValidationRules отдает правила полей, чтобы веб-форма и лаунчер проверяли их так же, как сервер.
+// Правила меняются через SIGHUP, поэтому клиент может хранить ответ, но
+// перед использованием сверяет его по ETag: неизменные правила приходят
+// как 304 без тела, измененные - сразу, без ожидания истечения кеша.
+func (h *AuthHandler) ValidationRules(w http.ResponseWriter, r *http.Request) {
+	policy := h.passwordPolicy()
+	surnames := h.gameSurnamePolicy()
+	rules := validation.Rules()
+	rules["password"] = policy.Rule()
+	rules["gameSurname"] = surnames.Rule()
+
+	body, err := json.Marshal(ValidationRulesResponse{
+		Success:           true,
+		Rules:             rules,
+		PasswordPolicy:    policy,
+		GameSurnamePolicy: surnames,
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInternal.Wrap(err))
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// etagMatches сравнивает If-None-Match с ETag ответа по правилам слабого
+// сравнения (RFC 9110, 13.1.2): префикс W/ не учитывается
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
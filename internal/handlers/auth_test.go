@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/database/storetest"
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/mail"
+	"LOIL-auth-server/internal/namepolicy"
+	"LOIL-auth-server/pkg/logger"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2id - дешевые параметры: тесты проверяют логику обработчиков,
+// а не стойкость хеша
+var testArgon2id = hashing.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// newTestHasher собирает хешер как сервер: preferred для новых паролей,
+// bcrypt и старые алгоритмы - только для проверки импортированных хешей
+func newTestHasher(preferred hashing.Argon2id) *hashing.Hasher {
+	return hashing.NewHasher(preferred, append([]hashing.Algorithm{hashing.Bcrypt{Cost: bcrypt.MinCost}}, hashing.LegacyAlgorithms()...)...)
+}
+
+func newTestAuthHandler(t *testing.T) *AuthHandler {
+	t.Helper()
+	return newTestAuthHandlerWith(t, storetest.NewSQLiteDB(t, database.Options{}), newTestHasher(testArgon2id))
+}
+
+// newTestAuthHandlerWith создает обработчик над готовой базой, чтобы
+// несколько обработчиков с разными хешерами видели одних пользователей
+func newTestAuthHandlerWith(t *testing.T, db *database.SQLiteDB, passwordHasher *hashing.Hasher) *AuthHandler {
+	t.Helper()
+	cfg, err := config.NewManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	appLogger := logger.NewLogger()
+
+	hasher := hashing.NewPool(passwordHasher, 4, 64)
+	t.Cleanup(hasher.Close)
+
+	return NewAuthHandler(db, hasher, nil, namepolicy.NewFilter(db, cfg, appLogger), mail.NewLogSender(appLogger), cfg, appLogger)
+}
+
+// Одновременные регистрации с одним логином и email: ровно одна успешна,
+// остальные получают 409, а не 500 от гонки в базе
+func TestRegisterConcurrentDuplicates(t *testing.T) {
+	h := newTestAuthHandler(t)
+
+	const n = 20
+	statuses := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"login":"racer","gameSurname":"Racer%c","email":"racer@example.com","password":"Vx9#kq2Lm!pz","passwordConfirm":"Vx9#kq2Lm!pz"}`, 'a'+i)
+			rec := httptest.NewRecorder()
+			h.Register(rec, httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body)))
+			statuses[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("request %d: status %d, want 200 or 409", i, status)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d registrations succeeded, want exactly 1 (statuses %v)", created, statuses)
+	}
+}
+
+func TestValidationRulesRevalidation(t *testing.T) {
+	h := newTestAuthHandler(t)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/auth/validation-rules", nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ValidationRules(rec, r)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || !strings.Contains(first.Body.String(), `"gameSurnamePolicy"`) {
+		t.Fatalf("first request: status %d, ETag %q, body %s", first.Code, etag, first.Body)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cc)
+	}
+	if again := get(""); again.Header().Get("ETag") != etag {
+		t.Errorf("ETag changed without a config change: %q, then %q", etag, again.Header().Get("ETag"))
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec := get(header)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d with %d bytes, want 304 without body", header, rec.Code, rec.Body.Len())
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 without the ETag", header)
+		}
+	}
+	if rec := get(`"stale"`); rec.Code != http.StatusOK {
+		t.Errorf("stale ETag: status %d, want 200", rec.Code)
+	}
+}
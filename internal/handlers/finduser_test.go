@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"LOIL-auth-server/internal/canonical"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/database/storetest"
+)
+
+func TestFindUser(t *testing.T) {
+	t.Setenv("AUTH_LOGIN_BY_GAME_SURNAME", "true")
+	db := storetest.NewSQLiteDB(t, database.Options{
+		Emails: canonical.EmailRules{IgnoreDotsDomains: []string{"gmail.com"}, StripPlusDomains: []string{"gmail.com"}},
+	})
+	h := newTestAuthHandlerWith(t, db, newTestHasher(testArgon2id))
+	registerUser(t, h, "hunter", "Volkov", "john.smith@gmail.com")
+	// Логин второго игрока совпадает с игровой фамилией первого
+	registerUser(t, h, "volkov", "Zaitsev", "zaitsev@example.com")
+
+	tests := []struct {
+		name       string
+		identifier string
+		want       string
+	}{
+		{"login", "hunter", "hunter"},
+		{"login in another case", "HUNTER", "hunter"},
+		{"email", "john.smith@gmail.com", "hunter"},
+		{"email in another case", "John.Smith@GMail.com", "hunter"},
+		{"email canonical form", "johnsmith+loil@gmail.com", "hunter"},
+		{"game surname", "Zaitsev", "volkov"},
+		{"game surname in another case", "zaitsev", "volkov"},
+		// Логин важнее игровой фамилии другого игрока
+		{"login shadows game surname", "volkov", "volkov"},
+		{"unknown login", "nobody", ""},
+		{"unknown email", "nobody@example.com", ""},
+		// Email ищется только по email, даже если совпадает с чьим-то логином
+		{"email-like login", "hunter@", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := h.findUser(context.Background(), tt.identifier)
+			if tt.want == "" {
+				if !errors.Is(err, database.ErrUserNotFound) {
+					t.Errorf("findUser(%q) = %v, %v, want ErrUserNotFound", tt.identifier, user, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findUser(%q): %v", tt.identifier, err)
+			}
+			if user.Login != tt.want {
+				t.Errorf("findUser(%q) = %s, want %s", tt.identifier, user.Login, tt.want)
+			}
+		})
+	}
+}
+
+// Без auth.login_by_game_surname игровая фамилия не подходит как идентификатор
+func TestFindUserGameSurnameDisabled(t *testing.T) {
+	h := newTestAuthHandler(t)
+	registerUser(t, h, "hunter", "Volkov", "hunter@example.com")
+
+	if user, err := h.findUser(context.Background(), "Volkov"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("findUser(game surname) = %v, %v, want ErrUserNotFound", user, err)
+	}
+}
+
+// Прежние клиенты присылают логин в поле login, новые - в identifier;
+// если заполнены оба, используется identifier
+func TestLoginIdentifierFields(t *testing.T) {
+	h := newTestAuthHandler(t)
+	registerUser(t, h, "hunter", "Volkov", "hunter@example.com")
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"identifier", `{"identifier":"hunter","password":"` + testPassword + `"}`, http.StatusOK},
+		{"identifier email", `{"identifier":"hunter@example.com","password":"` + testPassword + `"}`, http.StatusOK},
+		{"legacy login field", `{"login":"hunter","password":"` + testPassword + `"}`, http.StatusOK},
+		{"identifier wins", `{"identifier":"hunter","login":"nobody","password":"` + testPassword + `"}`, http.StatusOK},
+		{"unknown identifier", `{"identifier":"nobody","login":"hunter","password":"` + testPassword + `"}`, http.StatusUnauthorized},
+		{"no identifier", `{"password":"` + testPassword + `"}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postLogin(h, tt.body); rec.Code != tt.want {
+				t.Errorf("status %d, want %d, body %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/pkg/logger"
+)
+
+type HealthHandler struct {
+	db           database.HealthChecker
+	migrationFS  fs.FS
+	logger       *logger.Logger
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db database.HealthChecker, migrationFS fs.FS, logger *logger.Logger) *HealthHandler {
+	return &HealthHandler{
+		db:          db,
+		migrationFS: migrationFS,
+		logger:      logger,
+	}
+}
+
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// SetShuttingDown переводит readiness в состояние "not ready", чтобы балансировщик
+// перестал присылать новые запросы, пока сервер дожидается завершения текущих
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live сообщает, что процесс жив и обрабатывает запросы
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+}
+
+// Ready проверяет, что сервер готов принимать трафик: база доступна и миграции актуальны
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := make(map[string]string)
+	ready := true
+
+	if h.shuttingDown.Load() {
+		checks["server"] = "shutting down"
+		ready = false
+	}
+
+	if err := h.db.Ping(ctx); err != nil {
+		h.logger.Error("Ready: database ping failed:", err)
+		checks["database"] = "unavailable"
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	pending, err := h.db.PendingMigrations(ctx, h.migrationFS)
+	switch {
+	case err != nil:
+		h.logger.Error("Ready: migration check failed:", err)
+		checks["migrations"] = "unknown"
+		ready = false
+	case pending > 0:
+		checks["migrations"] = "pending"
+		ready = false
+	default:
+		checks["migrations"] = "ok"
+	}
+
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(HealthResponse{Status: "unavailable", Checks: checks})
+		return
+	}
+
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok", Checks: checks})
+}
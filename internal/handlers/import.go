@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"LOIL-auth-server/internal/apierror"
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/userimport"
+	"LOIL-auth-server/pkg/logger"
+)
+
+// maxImportBody - предел тела запроса импорта; большие выгрузки грузятся командой importusers
+const maxImportBody = 32 << 20
+
+// ImportHandler - административный импорт пользователей. Доступен только
+// на административном адресе с mTLS (tls.admin).
+type ImportHandler struct {
+	db     database.UserImporter
+	cfg    *config.Manager
+	logger *logger.Logger
+}
+
+func NewImportHandler(db database.UserImporter, cfg *config.Manager, logger *logger.Logger) *ImportHandler {
+	return &ImportHandler{
+		db:     db,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+type ImportResponse struct {
+	Success bool              `json:"success"`
+	Report  userimport.Report `json:"report"`
+}
+
+// ImportUsers принимает JSON-массив записей или CSV (Content-Type: text/csv).
+// Параметры запроса: map_ids=true - назначить новые ID, dry_run=true - только проверить.
+func (h *ImportHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	opts, err := importOptions(r)
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidInput.Wrap(err))
+		return
+	}
+	opts.GameSurnames = gamesurname.PolicyFrom(h.cfg.Get().GameSurname)
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBody)
+	var records []userimport.Record
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		records, err = userimport.ParseCSV(body)
+	} else {
+		records, err = userimport.ParseJSON(body)
+	}
+	if err != nil {
+		h.logger.Error("ImportUsers: invalid input:", err)
+		apierror.Write(w, r, apierror.ErrInvalidInput.Wrap(err))
+		return
+	}
+
+	report, err := userimport.Import(r.Context(), h.db, records, opts)
+	if err != nil {
+		h.logger.Error("ImportUsers: import interrupted after", report.Imported, "users:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	h.logger.Info("ImportUsers: imported", report.Imported, "of", report.Total, "users, dry run:", opts.DryRun)
+	json.NewEncoder(w).Encode(ImportResponse{
+		Success: true,
+		Report:  report,
+	})
+}
+
+func importOptions(r *http.Request) (userimport.Options, error) {
+	var opts userimport.Options
+	query := r.URL.Query()
+	for name, target := range map[string]*bool{"map_ids": &opts.MapIDs, "dry_run": &opts.DryRun} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, err
+			}
+			*target = parsed
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/database/storetest"
+	"LOIL-auth-server/internal/hashing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "Vx9#kq2Lm!pz"
+
+// registerUser регистрирует пользователя через обработчик, как это делает клиент
+func registerUser(t *testing.T, h *AuthHandler, login, gameSurname, email string) {
+	t.Helper()
+	body := fmt.Sprintf(`{"login":%q,"gameSurname":%q,"email":%q,"password":%q,"passwordConfirm":%q}`,
+		login, gameSurname, email, testPassword, testPassword)
+	rec := httptest.NewRecorder()
+	h.Register(rec, httptest.NewRequest(http.MethodPost, "/api/auth/register", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Register(%s): status %d, body %s", login, rec.Code, rec.Body)
+	}
+}
+
+func postLogin(h *AuthHandler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(body)))
+	return rec
+}
+
+// Неизвестный логин проверяется против фиктивного хеша и получает тот же
+// ответ, что и неверный пароль
+func TestLoginUnknownUserMatchesWrongPassword(t *testing.T) {
+	h := newTestAuthHandler(t)
+	registerUser(t, h, "player", "Player", "player@example.com")
+
+	attempt := func(body string) (*httptest.ResponseRecorder, uint64) {
+		before := h.hasher.Stats().Completed
+		rec := postLogin(h, body)
+		return rec, h.hasher.Stats().Completed - before
+	}
+
+	wrong, wrongHashes := attempt(`{"identifier":"player","password":"Wrong#pass123"}`)
+	unknown, unknownHashes := attempt(`{"identifier":"nobody","password":"Wrong#pass123"}`)
+
+	if wrong.Code != http.StatusUnauthorized || unknown.Code != http.StatusUnauthorized {
+		t.Fatalf("status: wrong password %d, unknown login %d, want 401 for both", wrong.Code, unknown.Code)
+	}
+	if wrong.Body.String() != unknown.Body.String() {
+		t.Errorf("bodies differ:\nwrong password: %s\nunknown login:  %s", wrong.Body, unknown.Body)
+	}
+	if wrongHashes != 1 || unknownHashes != 1 {
+		t.Errorf("password checks on the pool: wrong password %d, unknown login %d, want 1 each", wrongHashes, unknownHashes)
+	}
+}
+
+// storedHash возвращает хеш пароля из базы
+func storedHash(t *testing.T, h *AuthHandler, login string) string {
+	t.Helper()
+	user, err := h.db.GetUserByLogin(context.Background(), login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.Password
+}
+
+// setStoredHash подменяет хеш пароля, как если бы пользователь пришел импортом
+func setStoredHash(t *testing.T, h *AuthHandler, login, hash string) {
+	t.Helper()
+	user, err := h.db.GetUserByLogin(context.Background(), login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.db.UpdatePassword(context.Background(), user.ID, hash); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loginAs(t *testing.T, h *AuthHandler, login string) {
+	t.Helper()
+	rec := postLogin(h, fmt.Sprintf(`{"identifier":%q,"password":%q}`, login, testPassword))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Login(%s): status %d, body %s", login, rec.Code, rec.Body)
+	}
+}
+
+// Хеш устаревшего алгоритма пересчитывается в argon2id при входе,
+// и тот же пароль подходит к новому хешу
+func TestLoginUpgradesLegacyHash(t *testing.T) {
+	bcryptHash, err := hashing.Bcrypt{Cost: bcrypt.MinCost}.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum([]byte("s4lt" + testPassword))
+	md5Hash, err := hashing.TagLegacy(hashing.AlgorithmMD5, hex.EncodeToString(md5Sum[:]), "s4lt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"bcrypt", bcryptHash},
+		{"md5", md5Hash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestAuthHandler(t)
+			registerUser(t, h, "veteran", "Veteran", "veteran@example.com")
+			setStoredHash(t, h, "veteran", tt.hash)
+
+			loginAs(t, h, "veteran")
+			upgraded := storedHash(t, h, "veteran")
+			if upgraded == tt.hash || !strings.HasPrefix(upgraded, "$argon2id$") {
+				t.Fatalf("stored hash after login = %q, want a new argon2id hash", upgraded)
+			}
+
+			loginAs(t, h, "veteran")
+			if again := storedHash(t, h, "veteran"); again != upgraded {
+				t.Errorf("current hash was rehashed again: %q, then %q", upgraded, again)
+			}
+		})
+	}
+}
+
+// После смены параметров argon2id хеш пересчитывается с новыми при входе
+func TestLoginRehashesOnArgon2idParamsChange(t *testing.T) {
+	db := storetest.NewSQLiteDB(t, database.Options{})
+	before := newTestAuthHandlerWith(t, db, newTestHasher(testArgon2id))
+	registerUser(t, before, "player", "Player", "player@example.com")
+	oldHash := storedHash(t, before, "player")
+
+	stronger := testArgon2id
+	stronger.Memory = 128
+	stronger.Iterations = 2
+	after := newTestAuthHandlerWith(t, db, newTestHasher(stronger))
+
+	loginAs(t, after, "player")
+	newHash := storedHash(t, after, "player")
+	if newHash == oldHash || !strings.Contains(newHash, "$m=128,t=2,p=1$") {
+		t.Fatalf("stored hash after login = %q, want m=128,t=2 (was %q)", newHash, oldHash)
+	}
+
+	loginAs(t, after, "player")
+	if again := storedHash(t, after, "player"); again != newHash {
+		t.Errorf("current hash was rehashed again: %q, then %q", newHash, again)
+	}
+}
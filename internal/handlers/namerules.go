@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"LOIL-auth-server/internal/apierror"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/namepolicy"
+	"LOIL-auth-server/internal/validation"
+	"LOIL-auth-server/pkg/logger"
+)
+
+// NameRulesHandler - правила администраторов для фильтра имен. Доступен
+// только на административном адресе с mTLS (tls.admin).
+type NameRulesHandler struct {
+	db     database.NameRuleStore
+	names  *namepolicy.Filter
+	logger *logger.Logger
+}
+
+func NewNameRulesHandler(db database.NameRuleStore, names *namepolicy.Filter, logger *logger.Logger) *NameRulesHandler {
+	return &NameRulesHandler{
+		db:     db,
+		names:  names,
+		logger: logger,
+	}
+}
+
+type NameRulesResponse struct {
+	Success bool              `json:"success"`
+	Rules   []models.NameRule `json:"rules"`
+}
+
+type NameRuleResponse struct {
+	Success bool             `json:"success"`
+	Rule    *models.NameRule `json:"rule"`
+}
+
+type NameCheckResponse struct {
+	Success bool               `json:"success"`
+	Verdict namepolicy.Verdict `json:"verdict"`
+}
+
+// AddNameRuleRequest - шаблон "слово", "слово*", "*слово" или "*слово*";
+// action: block или allow
+type AddNameRuleRequest struct {
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+	Note    string `json:"note"`
+}
+
+func (r *AddNameRuleRequest) Validate() error {
+	r.Pattern = strings.TrimSpace(r.Pattern)
+
+	var v validation.Validator
+	if err := namepolicy.ValidatePattern(r.Pattern); err != nil {
+		v.Add(validation.NewFieldError("pattern", validation.CodeInvalidFormat, map[string]interface{}{"reason": err.Error()}))
+	}
+	v.OneOf("action", r.Action, []string{models.NameRuleBlock, models.NameRuleAllow})
+	return v.Err()
+}
+
+// ListNameRules возвращает все правила
+func (h *NameRulesHandler) ListNameRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rules, err := h.db.ListNameRules(r.Context())
+	if err != nil {
+		h.logger.Error("ListNameRules: database error:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	if rules == nil {
+		rules = []models.NameRule{}
+	}
+
+	json.NewEncoder(w).Encode(NameRulesResponse{
+		Success: true,
+		Rules:   rules,
+	})
+}
+
+// AddNameRule добавляет правило; на этом экземпляре оно действует сразу,
+// на остальных - после names.refresh_interval
+func (h *NameRulesHandler) AddNameRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req AddNameRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("AddNameRule: invalid JSON input")
+		apierror.Write(w, r, apierror.ErrInvalidInput)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	rule := &models.NameRule{
+		Pattern: req.Pattern,
+		Action:  req.Action,
+		Note:    req.Note,
+	}
+	if err := h.db.AddNameRule(r.Context(), rule); err != nil {
+		h.logger.Error("AddNameRule: database error:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	h.names.Invalidate()
+
+	h.logger.Info("AddNameRule: added", rule.Action, "rule", rule.ID, "-", rule.Pattern)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(NameRuleResponse{
+		Success: true,
+		Rule:    rule,
+	})
+}
+
+// DeleteNameRule удаляет правило по ID и отвечает 204 без тела
+func (h *NameRulesHandler) DeleteNameRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apierror.Write(w, r, apierror.ErrInvalidInput.Wrap(err))
+		return
+	}
+
+	if err := h.db.DeleteNameRule(r.Context(), id); err != nil {
+		h.logger.Error("DeleteNameRule: rule", id, "-", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	h.names.Invalidate()
+
+	h.logger.Info("DeleteNameRule: deleted rule", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckName показывает, пропустит ли фильтр имя и какой шаблон сработал.
+// Проверяет и при выключенном names.enabled, чтобы правила можно было
+// отладить до включения.
+func (h *NameRulesHandler) CheckName(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		apierror.Write(w, r, validation.Errors{*validation.NewFieldError("name", validation.CodeRequired, nil)})
+		return
+	}
+
+	json.NewEncoder(w).Encode(NameCheckResponse{
+		Success: true,
+		Verdict: h.names.Verdict(r.Context(), name),
+	})
+}
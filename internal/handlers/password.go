@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"LOIL-auth-server/internal/apierror"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/passwordpolicy"
+	"LOIL-auth-server/internal/validation"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword    string `json:"currentPassword"`
+	NewPassword        string `json:"newPassword"`
+	NewPasswordConfirm string `json:"newPasswordConfirm"`
+}
+
+// currentPasswordRule - текущий пароль мог быть задан по старым правилам, поэтому проверяется только наличие
+var currentPasswordRule = validation.Rule{Required: true}
+
+// Validate проверяет поля запроса; новый пароль - по политике с учетом данных пользователя
+func (req ChangePasswordRequest) Validate(policy passwordpolicy.Policy, inputs passwordpolicy.UserInputs) (passwordpolicy.Strength, error) {
+	var v validation.Validator
+	v.Check("currentPassword", req.CurrentPassword, currentPasswordRule)
+	strength, passwordErr := policy.Check("newPassword", req.NewPassword, inputs)
+	v.Add(passwordErr)
+	v.Match("newPasswordConfirm", req.NewPasswordConfirm, req.NewPassword)
+	return strength, v.Err()
+}
+
+type PasswordResponse struct {
+	Success          bool                    `json:"success"`
+	PasswordStrength passwordpolicy.Strength `json:"passwordStrength"`
+}
+
+// ChangePassword меняет пароль после проверки текущего
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		apierror.Write(w, r, apierror.ErrUnauthorized)
+		return
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("ChangePassword: user lookup failed - ID:", userID, err)
+		apierror.Write(w, r, err)
+		return
+	}
+	r = withUserLocale(w, r, user)
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("ChangePassword: invalid JSON input")
+		apierror.Write(w, r, apierror.ErrInvalidInput)
+		return
+	}
+
+	strength, err := req.Validate(h.passwordPolicy(), passwordpolicy.UserInputs{
+		Login:       user.Login,
+		Email:       user.Email,
+		GameSurname: user.GameSurname,
+	})
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	match, _, err := h.hasher.Verify(r.Context(), req.CurrentPassword, user.Password)
+	if err != nil {
+		h.logger.Error("ChangePassword: password check failed:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	if !match {
+		h.logger.Warn("ChangePassword: wrong current password for user ID", userID)
+		apierror.Write(w, r, validation.Errors{*validation.NewFieldError("currentPassword", validation.CodeIncorrect, nil)})
+		return
+	}
+
+	hash, err := h.hasher.Hash(r.Context(), req.NewPassword)
+	if err != nil {
+		h.logger.Error("ChangePassword: password hashing failed:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := h.db.UpdatePassword(r.Context(), userID, hash); err != nil {
+		h.logger.Error("ChangePassword: database error:", err)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// Новый пароль прошел политику, включая проверку по базе утечек
+	if user.PasswordBreached {
+		if err := h.db.SetPasswordBreached(r.Context(), userID, false); err != nil {
+			h.logger.Warn("ChangePassword: clearing breached password flag failed for user ID", userID, err)
+		}
+	}
+
+	h.logger.Info("ChangePassword: password changed for user ID", userID)
+	json.NewEncoder(w).Encode(PasswordResponse{
+		Success:          true,
+		PasswordStrength: strength.Localized(i18n.FromContext(r.Context())),
+	})
+}
+
+type PasswordStrengthRequest struct {
+	Password    string `json:"password"`
+	Login       string `json:"login,omitempty"`
+	Email       string `json:"email,omitempty"`
+	GameSurname string `json:"gameSurname,omitempty"`
+}
+
+// PasswordStrength проверяет пароль по политике без сохранения, чтобы форма
+// показывала оценку и подсказки до отправки. Нарушение политики возвращается
+// как ошибка поля вместе с оценкой, статус ответа при этом 200.
+func (h *AuthHandler) PasswordStrength(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req PasswordStrengthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("PasswordStrength: invalid JSON input")
+		apierror.Write(w, r, apierror.ErrInvalidInput)
+		return
+	}
+
+	strength, fieldErr := h.passwordPolicy().Check("password", req.Password, passwordpolicy.UserInputs{
+		Login:       req.Login,
+		Email:       req.Email,
+		GameSurname: req.GameSurname,
+	})
+	locale := i18n.FromContext(r.Context())
+	response := PasswordStrengthResponse{
+		Success:          fieldErr == nil,
+		PasswordStrength: strength.Localized(locale),
+	}
+	if fieldErr != nil {
+		localized := *fieldErr
+		localized.Message = fieldErr.Localized(locale)
+		response.Fields = []validation.FieldError{localized}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+type PasswordStrengthResponse struct {
+	// Success - пароль удовлетворяет политике
+	Success          bool                    `json:"success"`
+	PasswordStrength passwordpolicy.Strength `json:"passwordStrength"`
+	Fields           []validation.FieldError `json:"fields,omitempty"`
+}
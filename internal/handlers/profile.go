@@ -5,20 +5,30 @@ import (
 	"fmt"
 	"net/http"
 
+	"LOIL-auth-server/internal/apierror"
 	"LOIL-auth-server/internal/config"
 	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/namepolicy"
 	"LOIL-auth-server/internal/utils"
+	"LOIL-auth-server/internal/validation"
 	"LOIL-auth-server/pkg/logger"
 )
 
 type ProfileHandler struct {
-	db     *database.SQLiteDB
+	db     database.UserStore
+	names  *namepolicy.Filter
+	cfg    *config.Manager
 	logger *logger.Logger
 }
 
-func NewProfileHandler(db *database.SQLiteDB, logger *logger.Logger) *ProfileHandler {
+func NewProfileHandler(db database.UserStore, names *namepolicy.Filter, cfg *config.Manager, logger *logger.Logger) *ProfileHandler {
 	return &ProfileHandler{
 		db:     db,
+		names:  names,
+		cfg:    cfg,
 		logger: logger,
 	}
 }
@@ -26,12 +36,28 @@ func NewProfileHandler(db *database.SQLiteDB, logger *logger.Logger) *ProfileHan
 type ProfileResponse struct {
 	Success bool        `json:"success"`
 	User    interface{} `json:"user,omitempty"`
-	Error   string      `json:"error,omitempty"`
 }
 
 type UpdateProfileRequest struct {
 	GameSurname *string `json:"gameSurname,omitempty"`
 	Email       *string `json:"email,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+}
+
+// Validate проверяет переданные поля; отсутствующие поля не меняются и не проверяются
+func (req UpdateProfileRequest) Validate(surnames gamesurname.Policy) error {
+	var v validation.Validator
+	if req.GameSurname != nil {
+		v.Add(surnames.Check("gameSurname", *req.GameSurname))
+	}
+	if req.Email != nil {
+		v.Check("email", *req.Email, validation.Email)
+	}
+	// Пустая строка сбрасывает настройку: язык снова берется из Accept-Language
+	if req.Locale != nil && *req.Locale != "" {
+		v.OneOf("locale", *req.Locale, i18n.Locales())
+	}
+	return v.Err()
 }
 
 // Вспомогательная функция для извлечения userID из токена
@@ -42,9 +68,8 @@ func (h *ProfileHandler) getUserIDFromToken(r *http.Request) (int, error) {
 	}
 
 	tokenString := authHeader[7:] // Remove "Bearer "
-	cfg, _ := config.Load()
 
-	claims, err := utils.ValidateJWT(tokenString, cfg.JWTSecret)
+	claims, err := utils.ValidateJWT(tokenString, h.cfg.Get().JWT.VerificationKeys())
 	if err != nil {
 		return 0, err
 	}
@@ -58,16 +83,17 @@ func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromToken(r)
 	if err != nil {
 		h.logger.Error("GetProfile: invalid token")
-		json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Invalid token"})
+		apierror.Write(w, r, apierror.ErrInvalidToken.Wrap(err))
 		return
 	}
 
-	user, err := h.db.GetUserByID(userID)
+	user, err := h.db.GetUserByID(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("GetProfile: user not found - ID:", userID)
-		json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "User not found"})
+		h.logger.Error("GetProfile: user lookup failed - ID:", userID, err)
+		apierror.Write(w, r, err)
 		return
 	}
+	withUserLocale(w, r, user)
 
 	h.logger.Info("GetProfile: profile retrieved for user -", user.Login)
 	json.NewEncoder(w).Encode(ProfileResponse{
@@ -82,59 +108,60 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.getUserIDFromToken(r)
 	if err != nil {
 		h.logger.Error("UpdateProfile: invalid token")
-		json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Invalid token"})
+		apierror.Write(w, r, apierror.ErrInvalidToken.Wrap(err))
+		return
+	}
+
+	// Пользователь нужен до разбора запроса, чтобы ошибки были на его языке
+	currentUser, err := h.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("UpdateProfile: user lookup failed - ID:", userID, err)
+		apierror.Write(w, r, err)
 		return
 	}
+	r = withUserLocale(w, r, currentUser)
 
 	var req UpdateProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Error("UpdateProfile: invalid JSON input")
-		json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Invalid input"})
+		apierror.Write(w, r, apierror.ErrInvalidInput)
 		return
 	}
 
 	// Валидация обновляемых полей
-	updates := make(map[string]string)
-
-	if req.GameSurname != nil {
-		if !utils.ValidateGameSurname(*req.GameSurname) {
-			json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Game surname must contain only Latin letters"})
-			return
-		}
-		normalizedSurname := utils.NormalizeGameSurname(*req.GameSurname)
-		updates["game_surname"] = normalizedSurname
+	if err := req.Validate(gamesurname.PolicyFrom(h.cfg.Get().GameSurname)); err != nil {
+		apierror.Write(w, r, err)
+		return
 	}
 
-	if req.Email != nil {
-		if !utils.ValidateEmail(*req.Email) {
-			json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Invalid email format"})
+	update := database.UserUpdate{
+		Email:  req.Email,
+		Locale: req.Locale,
+	}
+	if req.GameSurname != nil {
+		gameSurname := utils.NormalizeGameSurname(*req.GameSurname)
+		if fieldErr := h.names.Check(r.Context(), "gameSurname", gameSurname); fieldErr != nil {
+			h.logger.Info("UpdateProfile: name not allowed -", gameSurname)
+			apierror.Write(w, r, validation.Errors{*fieldErr})
 			return
 		}
-		updates["email"] = *req.Email
+		update.GameSurname = &gameSurname
 	}
 
 	// Если есть что обновлять
-	if len(updates) > 0 {
-		if err := h.db.UpdateUser(userID, updates); err != nil {
+	if !update.Empty() {
+		if err := h.db.UpdateUser(r.Context(), userID, update); err != nil {
 			h.logger.Error("UpdateProfile: database error:", err)
-
-			errorMsg := "Update failed"
-			if err.Error() == "game surname already exists" {
-				errorMsg = "Game surname already exists"
-			} else if err.Error() == "email already exists" {
-				errorMsg = "Email already exists"
-			}
-
-			json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: errorMsg})
+			apierror.Write(w, r, err)
 			return
 		}
 	}
 
 	// Получаем обновленные данные пользователя
-	user, err := h.db.GetUserByID(userID)
+	user, err := h.db.GetUserByID(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("UpdateProfile: failed to get updated user - ID:", userID)
-		json.NewEncoder(w).Encode(ProfileResponse{Success: false, Error: "Update completed but failed to retrieve user"})
+		h.logger.Error("UpdateProfile: failed to get updated user - ID:", userID, err)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -144,3 +171,12 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		User:    user.ToResponse(),
 	})
 }
+
+// withUserLocale применяет сохраненный язык пользователя вместо Accept-Language
+func withUserLocale(w http.ResponseWriter, r *http.Request, user *models.User) *http.Request {
+	if user.Locale == "" || !i18n.Supported(user.Locale) {
+		return r
+	}
+	w.Header().Set("Content-Language", user.Locale)
+	return r.WithContext(i18n.WithLocale(r.Context(), user.Locale))
+}
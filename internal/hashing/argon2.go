@@ -0,0 +1,120 @@
+package hashing
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Пределы параметров argon2id. Хеши вне них не проверяются: p=0 роняет
+// argon2.IDKey, а огромные m и t займут память и воркер на неограниченное время.
+const (
+	// MaxArgon2Memory - 1 ГиБ в КиБ
+	MaxArgon2Memory     = 1 << 20
+	MaxArgon2Iterations = 64
+)
+
+// Argon2id хеширует пароли argon2id и хранит их в формате PHC:
+// $argon2id$v=19$m=65536,t=3,p=2$<соль>$<хеш>
+type Argon2id struct {
+	// Memory в КиБ
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type argon2Params struct {
+	version     int
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt, key   []byte
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (a Argon2id) Verify(password, encoded string) (bool, error) {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (a Argon2id) Current(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	return params.version == argon2.Version &&
+		params.memory == a.Memory &&
+		params.iterations == a.Iterations &&
+		params.parallelism == a.Parallelism &&
+		uint32(len(params.salt)) == a.SaltLength &&
+		uint32(len(params.key)) == a.KeyLength
+}
+
+// parseArgon2id разбирает строку PHC: "", "argon2id", "v=19", "m=..,t=..,p=..", соль, хеш.
+// Все ошибки оборачивают ErrMalformedHash.
+func parseArgon2id(encoded string) (argon2Params, error) {
+	var params argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, fmt.Errorf("%w: invalid argon2id hash", ErrMalformedHash)
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return params, fmt.Errorf("%w: invalid argon2id version: %v", ErrMalformedHash, err)
+	}
+	if params.version != argon2.Version {
+		return params, fmt.Errorf("%w: unsupported argon2id version %d", ErrMalformedHash, params.version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, fmt.Errorf("%w: invalid argon2id parameters: %v", ErrMalformedHash, err)
+	}
+	if params.parallelism < 1 || params.iterations < 1 || params.iterations > MaxArgon2Iterations {
+		return params, fmt.Errorf("%w: argon2id t must be 1..%d and p at least 1", ErrMalformedHash, MaxArgon2Iterations)
+	}
+	if params.memory < 8*uint32(params.parallelism) || params.memory > MaxArgon2Memory {
+		return params, fmt.Errorf("%w: argon2id m must be between 8*p and %d KiB", ErrMalformedHash, MaxArgon2Memory)
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, fmt.Errorf("%w: invalid argon2id salt: %v", ErrMalformedHash, err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, fmt.Errorf("%w: invalid argon2id hash: %v", ErrMalformedHash, err)
+	}
+	if len(params.key) == 0 {
+		return params, fmt.Errorf("%w: invalid argon2id hash: empty key", ErrMalformedHash)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,69 @@
+package hashing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseArgon2idRejectsBadParameters(t *testing.T) {
+	const tail = "$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	tests := []struct {
+		name   string
+		params string
+	}{
+		{"zero parallelism", "m=16,t=1,p=0"},
+		{"zero iterations", "m=16,t=0,p=1"},
+		{"too many iterations", "m=16,t=65,p=1"},
+		{"memory below 8*p", "m=15,t=1,p=2"},
+		{"memory above limit", "m=4294967295,t=1,p=1"},
+		{"garbage", "m=x,t=1,p=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := "$argon2id$v=19$" + tt.params + tail
+			if _, err := parseArgon2id(encoded); !errors.Is(err, ErrMalformedHash) {
+				t.Errorf("parseArgon2id(%q) = %v, want ErrMalformedHash", tt.params, err)
+			}
+			if _, err := TagLegacy(AlgorithmArgon2id, encoded, ""); err == nil {
+				t.Errorf("TagLegacy accepted %q", tt.params)
+			}
+		})
+	}
+
+	if _, err := parseArgon2id("$argon2id$v=19$m=16,t=1,p=1" + tail); err != nil {
+		t.Errorf("valid hash rejected: %v", err)
+	}
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	a := Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := a.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := a.Verify("secret", hash); err != nil || !ok {
+		t.Errorf("Verify(correct) = %v, %v", ok, err)
+	}
+	if ok, err := a.Verify("wrong", hash); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v", ok, err)
+	}
+	if !a.Current(hash) {
+		t.Error("Current = false for a fresh hash")
+	}
+}
+
+func TestPoolRecoversFromPanic(t *testing.T) {
+	pool := NewPool(NewHasher(testArgon2id), 1, 1)
+	defer pool.Close()
+
+	err := pool.Do(context.Background(), func() { panic("boom") })
+	if !errors.Is(err, ErrMalformedHash) {
+		t.Fatalf("Do with panicking job = %v, want ErrMalformedHash", err)
+	}
+
+	// Воркер продолжает работать после паники
+	if _, err := pool.Hash(context.Background(), "secret"); err != nil {
+		t.Errorf("Hash after panic: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package hashing
+
+import (
+	"errors"
+	"strings"
+
+	"LOIL-auth-server/internal/validation"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxBytes - bcrypt учитывает только первые 72 байта пароля
+const bcryptMaxBytes = 72
+
+// Bcrypt хранит хеши в формате $2a$<cost>$...; используется для проверки
+// старых хешей и как запасной алгоритм
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	// Более длинный пароль - ошибка поля, а не сбой сервера
+	if fieldErr := (validation.Rule{MaxBytes: bcryptMaxBytes}).Check("password", password); fieldErr != nil {
+		return "", validation.Errors{*fieldErr}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(hash), err
+}
+
+func (b Bcrypt) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword), errors.Is(err, bcrypt.ErrPasswordTooLong):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (b Bcrypt) Current(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == b.Cost
+}
@@ -0,0 +1,81 @@
+package hashing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownHash - хеш не распознан ни одним из известных алгоритмов
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// ErrMalformedHash - хеш распознан, но испорчен или записан с параметрами
+// вне допустимых пределов
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Algorithm - одна схема хеширования паролей со своим форматом строки
+type Algorithm interface {
+	// Hash создает хеш с текущими параметрами
+	Hash(password string) (string, error)
+	// Recognizes сообщает, что хеш записан в формате этого алгоритма
+	Recognizes(encoded string) bool
+	// Verify проверяет пароль против хеша в формате этого алгоритма
+	Verify(password, encoded string) (bool, error)
+	// Current сообщает, что хеш создан с текущими параметрами
+	Current(encoded string) bool
+}
+
+// Hasher создает новые хеши предпочтительным алгоритмом и проверяет хеши
+// всех известных алгоритмов, подсказывая, когда хеш пора пересчитать
+type Hasher struct {
+	preferred Algorithm
+	known     []Algorithm
+	peppers   Peppers
+}
+
+// NewHasher возвращает Hasher, который хеширует алгоритмом preferred,
+// а проверять умеет и хеши алгоритмов legacy
+func NewHasher(preferred Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{
+		preferred: preferred,
+		known:     append([]Algorithm{preferred}, legacy...),
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.peppers.Current == "" {
+		return h.preferred.Hash(password)
+	}
+
+	hash, err := h.preferred.Hash(pepper(h.peppers.Keys[h.peppers.Current], password))
+	if err != nil {
+		return "", err
+	}
+	return pepperPrefix + h.peppers.Current + hash, nil
+}
+
+// Verify проверяет пароль. needsRehash равен true, если пароль верный,
+// но хеш создан другим алгоритмом, с устаревшими параметрами или не с
+// текущим перцем.
+func (h *Hasher) Verify(password, encoded string) (ok, needsRehash bool, err error) {
+	version := ""
+	if v, inner, peppered := splitPeppered(encoded); peppered {
+		key, known := h.peppers.Keys[v]
+		if !known {
+			return false, false, fmt.Errorf("%w %q", ErrUnknownPepper, v)
+		}
+		password, encoded, version = pepper(key, password), inner, v
+	}
+
+	for _, algorithm := range h.known {
+		if !algorithm.Recognizes(encoded) {
+			continue
+		}
+
+		ok, err := algorithm.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, algorithm != h.preferred || !algorithm.Current(encoded) || version != h.peppers.Current, nil
+	}
+	return false, false, ErrUnknownHash
+}
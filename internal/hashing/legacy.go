@@ -0,0 +1,219 @@
+package hashing
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Алгоритмы импортированных хешей. Значения совпадают с полем hash_algorithm
+// файлов импорта.
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	// AlgorithmMD5 - hex(md5(salt + password)), соль может быть пустой
+	AlgorithmMD5 = "md5"
+	// AlgorithmMD5Double - hex(md5(hex(md5(password)) + salt)), как в vBulletin и IPB
+	AlgorithmMD5Double = "md5_double"
+	// AlgorithmSHA1 - hex(sha1(salt + password)), соль может быть пустой
+	AlgorithmSHA1 = "sha1"
+	// AlgorithmPhpass - переносимые хеши phpass ($P$, $H$) из phpBB и WordPress
+	AlgorithmPhpass = "phpass"
+)
+
+// ErrLegacyHashOnly - старые алгоритмы только проверяют пароли, новые хеши ими не создаются
+var ErrLegacyHashOnly = errors.New("legacy algorithm can only verify passwords")
+
+// legacyDigest - солёный хеш старого форума или игрового сервера.
+// Хранится как $<tag>$<соль в base64>$<hex-хеш>, чтобы алгоритм был виден по строке.
+type legacyDigest struct {
+	tag string
+	sum func(password, salt string) []byte
+}
+
+var (
+	legacyMD5 = legacyDigest{tag: "md5", sum: func(password, salt string) []byte {
+		sum := md5.Sum([]byte(salt + password))
+		return sum[:]
+	}}
+	legacyMD5Double = legacyDigest{tag: "md5d", sum: func(password, salt string) []byte {
+		inner := md5.Sum([]byte(password))
+		sum := md5.Sum([]byte(hex.EncodeToString(inner[:]) + salt))
+		return sum[:]
+	}}
+	legacySHA1 = legacyDigest{tag: "sha1", sum: func(password, salt string) []byte {
+		sum := sha1.Sum([]byte(salt + password))
+		return sum[:]
+	}}
+)
+
+// LegacyAlgorithms возвращает все алгоритмы, хеши которых можно импортировать
+func LegacyAlgorithms() []Algorithm {
+	return []Algorithm{legacyMD5, legacyMD5Double, legacySHA1, phpass{}}
+}
+
+func (d legacyDigest) Hash(string) (string, error) {
+	return "", ErrLegacyHashOnly
+}
+
+func (d legacyDigest) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+d.tag+"$")
+}
+
+func (d legacyDigest) Verify(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[1] != d.tag {
+		return false, fmt.Errorf("invalid %s hash", d.tag)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("invalid %s salt: %w", d.tag, err)
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false, fmt.Errorf("invalid %s hash: %w", d.tag, err)
+	}
+
+	return subtle.ConstantTimeCompare(d.sum(password, string(salt)), want) == 1, nil
+}
+
+// Current всегда false: старый хеш пересчитывается при первом входе
+func (d legacyDigest) Current(string) bool {
+	return false
+}
+
+func (d legacyDigest) encode(hexHash, salt string) (string, error) {
+	raw, err := hex.DecodeString(hexHash)
+	if err != nil || len(raw) != len(d.sum("", "")) {
+		return "", fmt.Errorf("%s hash must be %d hex characters", d.tag, 2*len(d.sum("", "")))
+	}
+	return "$" + d.tag + "$" + base64.RawStdEncoding.EncodeToString([]byte(salt)) + "$" + hex.EncodeToString(raw), nil
+}
+
+// TagLegacy приводит импортированный хеш к виду, в котором его распознает Hasher.
+// Для md5, md5_double и sha1 соль хранится вместе с хешем; phpass, bcrypt
+// и argon2id уже содержат соль и параметры и сохраняются как есть, если
+// параметры в допустимых пределах (см. MaxArgon2Memory и MaxPhpassCountLog2).
+func TagLegacy(algorithm, hash, salt string) (string, error) {
+	hash = strings.TrimSpace(hash)
+
+	switch algorithm {
+	case AlgorithmMD5:
+		return legacyMD5.encode(hash, salt)
+	case AlgorithmMD5Double:
+		return legacyMD5Double.encode(hash, salt)
+	case AlgorithmSHA1:
+		return legacySHA1.encode(hash, salt)
+	case AlgorithmPhpass:
+		if !(phpass{}).Recognizes(hash) || len(hash) != phpassHashLength {
+			return "", fmt.Errorf("phpass hash must start with $P$ or $H$ and be %d characters", phpassHashLength)
+		}
+		if _, err := phpassCountLog2(hash); err != nil {
+			return "", err
+		}
+	case AlgorithmBcrypt:
+		if !(Bcrypt{}).Recognizes(hash) {
+			return "", fmt.Errorf("bcrypt hash must start with $2a$, $2b$ or $2y$")
+		}
+	case AlgorithmArgon2id:
+		if _, err := parseArgon2id(hash); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+
+	if salt != "" {
+		return "", fmt.Errorf("%s hash carries its own salt, salt must be empty", algorithm)
+	}
+	return hash, nil
+}
+
+const (
+	phpassItoa64     = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	phpassHashLength = 34
+)
+
+// Пределы log2 числа раундов phpass. Формат допускает до 30, но phpBB и
+// WordPress пишут 8-13; 2^30 раундов MD5 заняли бы воркер пула на секунды
+// на каждую попытку входа.
+const (
+	MinPhpassCountLog2 = 7
+	MaxPhpassCountLog2 = 13
+)
+
+// phpass - переносимый формат phpass: $P$<log2 итераций><8 символов соли><22 символа хеша>
+type phpass struct{}
+
+func (phpass) Hash(string) (string, error) {
+	return "", ErrLegacyHashOnly
+}
+
+func (phpass) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$P$") || strings.HasPrefix(encoded, "$H$")
+}
+
+func (phpass) Verify(password, encoded string) (bool, error) {
+	if len(encoded) != phpassHashLength {
+		return false, fmt.Errorf("invalid phpass hash length")
+	}
+	countLog2, err := phpassCountLog2(encoded)
+	if err != nil {
+		return false, err
+	}
+	salt := encoded[4:12]
+
+	sum := md5.Sum([]byte(salt + password))
+	for count := 1 << countLog2; count > 0; count-- {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+
+	computed := encoded[:12] + phpassEncode64(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
+
+func (phpass) Current(string) bool {
+	return false
+}
+
+// phpassCountLog2 читает log2 числа раундов и проверяет его пределы
+func phpassCountLog2(encoded string) (int, error) {
+	countLog2 := strings.IndexByte(phpassItoa64, encoded[3])
+	if countLog2 < MinPhpassCountLog2 || countLog2 > MaxPhpassCountLog2 {
+		return 0, fmt.Errorf("%w: phpass iteration count 2^%d outside 2^%d..2^%d", ErrMalformedHash, countLog2, MinPhpassCountLog2, MaxPhpassCountLog2)
+	}
+	return countLog2, nil
+}
+
+// phpassEncode64 - собственная base64-кодировка phpass (младшие биты первыми)
+func phpassEncode64(input []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(input); {
+		value := int(input[i])
+		i++
+		b.WriteByte(phpassItoa64[value&0x3f])
+		if i < len(input) {
+			value |= int(input[i]) << 8
+		}
+		b.WriteByte(phpassItoa64[(value>>6)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		if i < len(input) {
+			value |= int(input[i]) << 16
+		}
+		b.WriteByte(phpassItoa64[(value>>12)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		b.WriteByte(phpassItoa64[(value>>18)&0x3f])
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package hashing
+
+import (
+	"errors"
+	"testing"
+)
+
+// Векторы md5, md5_double и sha1 посчитаны независимо (Python hashlib);
+// вектор phpass - из test.php самой библиотеки phpass
+func TestLegacyKnownVectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		hash      string
+		salt      string
+		password  string
+	}{
+		{"md5 unsalted", AlgorithmMD5, "5f4dcc3b5aa765d61d8327deb882cf99", "", "password"},
+		{"md5 salted", AlgorithmMD5, "b17181e8689dbd042709f8e05a32dc0e", "s4lt", "password"},
+		{"md5 uppercase hex", AlgorithmMD5, "5F4DCC3B5AA765D61D8327DEB882CF99", "", "password"},
+		{"md5_double", AlgorithmMD5Double, "d16d0c074739eb548194c078e46406df", "s4lt", "password"},
+		{"sha1 unsalted", AlgorithmSHA1, "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "", "password"},
+		{"sha1 salted", AlgorithmSHA1, "87a90277c59b822f7254e2b5122bf6f6432969f9", "s4lt", "password"},
+		{"phpass", AlgorithmPhpass, "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", "", "test12345"},
+	}
+
+	hasher := NewHasher(testArgon2id, LegacyAlgorithms()...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagged, err := TagLegacy(tt.algorithm, tt.hash, tt.salt)
+			if err != nil {
+				t.Fatalf("TagLegacy: %v", err)
+			}
+
+			ok, needsRehash, err := hasher.Verify(tt.password, tagged)
+			if err != nil || !ok {
+				t.Fatalf("Verify(correct) = %v, %v", ok, err)
+			}
+			if !needsRehash {
+				t.Error("legacy hash does not ask for a rehash")
+			}
+			if ok, _, err := hasher.Verify(tt.password+"x", tagged); ok || err != nil {
+				t.Errorf("Verify(wrong) = %v, %v, want false, nil", ok, err)
+			}
+		})
+	}
+
+	// Соль входит в хеш: та же строка с другой солью не подходит
+	tagged, err := TagLegacy(AlgorithmMD5, "b17181e8689dbd042709f8e05a32dc0e", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := hasher.Verify("password", tagged); ok {
+		t.Error("md5 hash verified with a wrong salt")
+	}
+}
+
+func TestTagLegacyRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		hash      string
+		salt      string
+	}{
+		{"md5 too short", AlgorithmMD5, "5f4dcc3b5aa765d61d8327deb882cf", ""},
+		{"md5 not hex", AlgorithmMD5, "zz4dcc3b5aa765d61d8327deb882cf99", ""},
+		{"sha1 with md5 length", AlgorithmSHA1, "5f4dcc3b5aa765d61d8327deb882cf99", ""},
+		{"phpass wrong prefix", AlgorithmPhpass, "$X$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", ""},
+		{"phpass short", AlgorithmPhpass, "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L", ""},
+		{"phpass with salt", AlgorithmPhpass, "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", "s4lt"},
+		{"bcrypt wrong prefix", AlgorithmBcrypt, "$1$abc", ""},
+		{"unknown algorithm", "crc32", "cbf43926", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := TagLegacy(tt.algorithm, tt.hash, tt.salt); err == nil {
+				t.Error("TagLegacy accepted a malformed hash")
+			}
+		})
+	}
+}
+
+// Число раундов phpass ограничено и при импорте, и при проверке: иначе одна
+// запись с $P$S... (2^30 раундов) занимала бы воркер на каждом входе
+func TestPhpassIterationCap(t *testing.T) {
+	const vector = "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"
+	withCount := func(countLog2 int) string {
+		return vector[:3] + string(phpassItoa64[countLog2]) + vector[4:]
+	}
+
+	for _, countLog2 := range []int{0, MinPhpassCountLog2 - 1, MaxPhpassCountLog2 + 1, 30} {
+		hash := withCount(countLog2)
+		if _, err := TagLegacy(AlgorithmPhpass, hash, ""); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("TagLegacy with 2^%d rounds = %v, want ErrMalformedHash", countLog2, err)
+		}
+		if _, err := (phpass{}).Verify("test12345", hash); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify with 2^%d rounds = %v, want ErrMalformedHash", countLog2, err)
+		}
+	}
+
+	for _, countLog2 := range []int{MinPhpassCountLog2, MaxPhpassCountLog2} {
+		if _, err := TagLegacy(AlgorithmPhpass, withCount(countLog2), ""); err != nil {
+			t.Errorf("TagLegacy with 2^%d rounds: %v", countLog2, err)
+		}
+	}
+}
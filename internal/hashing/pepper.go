@@ -0,0 +1,68 @@
+package hashing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pepperPrefix отмечает хеш пароля, подписанного перцем:
+// $pepper$<версия>$argon2id$v=19$... - после версии идет обычный хеш алгоритма
+const pepperPrefix = "$pepper$"
+
+// ErrUnknownPepper - хеш создан с перцем, версии которого нет в конфигурации
+var ErrUnknownPepper = errors.New("password hash uses an unknown pepper version")
+
+// Peppers - секреты сервера, которыми пароль подписывается (HMAC-SHA256) перед
+// хешированием. Они хранятся вне базы, поэтому утекшие хеши без них нельзя
+// перебирать. Версия записывается в хеш, чтобы перец можно было сменить:
+// новые хеши создаются с Current, старые проверяются своей версией и
+// пересчитываются при входе.
+type Peppers struct {
+	// Current - версия для новых хешей; пусто - новые хеши без перца
+	Current string
+	Keys    map[string][]byte
+}
+
+// WithPeppers возвращает копию Hasher, который подписывает пароли перцем
+// peppers.Current и проверяет хеши всех версий из peppers.Keys
+func (h *Hasher) WithPeppers(peppers Peppers) (*Hasher, error) {
+	if peppers.Current != "" {
+		if _, ok := peppers.Keys[peppers.Current]; !ok {
+			return nil, fmt.Errorf("current pepper version %q has no key", peppers.Current)
+		}
+	}
+	for version := range peppers.Keys {
+		if version == "" || strings.Contains(version, "$") {
+			return nil, fmt.Errorf("invalid pepper version %q", version)
+		}
+	}
+
+	peppered := *h
+	peppered.peppers = peppers
+	return &peppered, nil
+}
+
+// pepper подписывает пароль; результат в base64 короче 72 байт, поэтому
+// подходит и для bcrypt
+func pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// splitPeppered разделяет "$pepper$2$argon2id$..." на версию "2" и хеш "$argon2id$..."
+func splitPeppered(encoded string) (version, inner string, ok bool) {
+	rest, ok := strings.CutPrefix(encoded, pepperPrefix)
+	if !ok {
+		return "", "", false
+	}
+	i := strings.IndexByte(rest, '$')
+	if i <= 0 {
+		return "", "", false
+	}
+	return rest[:i], rest[i:], true
+}
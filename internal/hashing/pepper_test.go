@@ -0,0 +1,155 @@
+package hashing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testArgon2id - минимальные параметры, чтобы тесты шли быстро
+var testArgon2id = Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func pepperedHasher(t *testing.T, current string, keys map[string][]byte) *Hasher {
+	t.Helper()
+	h, err := NewHasher(testArgon2id).WithPeppers(Peppers{Current: current, Keys: keys})
+	if err != nil {
+		t.Fatalf("WithPeppers: %v", err)
+	}
+	return h
+}
+
+var (
+	pepper1 = []byte(strings.Repeat("1", 32))
+	pepper2 = []byte(strings.Repeat("2", 32))
+)
+
+func TestPepperRoundTrip(t *testing.T) {
+	h := pepperedHasher(t, "1", map[string][]byte{"1": pepper1})
+
+	hash, err := h.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$pepper$1$argon2id$") {
+		t.Fatalf("Hash = %q, want the $pepper$1$ prefix", hash)
+	}
+
+	ok, needsRehash, err := h.Verify("secret", hash)
+	if err != nil || !ok || needsRehash {
+		t.Errorf("Verify(correct) = %v, %v, %v, want true, false, nil", ok, needsRehash, err)
+	}
+	if ok, _, err := h.Verify("wrong", hash); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v, want false, nil", ok, err)
+	}
+
+	// Без перца хеш не проверить: внутри хеш HMAC пароля, а не сам пароль
+	_, inner, _ := splitPeppered(hash)
+	if ok, _, _ := NewHasher(testArgon2id).Verify("secret", inner); ok {
+		t.Error("peppered hash verified without the pepper")
+	}
+	// Та же версия с другим секретом - неверный пароль, а не ошибка
+	replaced := pepperedHasher(t, "1", map[string][]byte{"1": pepper2})
+	if ok, _, err := replaced.Verify("secret", hash); ok || err != nil {
+		t.Errorf("Verify with another secret = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestPepperUnknownVersion(t *testing.T) {
+	hash, err := pepperedHasher(t, "1", map[string][]byte{"1": pepper1}).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, h := range map[string]*Hasher{
+		"version removed": pepperedHasher(t, "2", map[string][]byte{"2": pepper2}),
+		"no peppers":      NewHasher(testArgon2id),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ok, _, err := h.Verify("secret", hash)
+			if ok || !errors.Is(err, ErrUnknownPepper) {
+				t.Errorf("Verify = %v, %v, want ErrUnknownPepper", ok, err)
+			}
+		})
+	}
+}
+
+func TestPepperRotationNeedsRehash(t *testing.T) {
+	keys := map[string][]byte{"1": pepper1, "2": pepper2}
+	old, err := pepperedHasher(t, "1", keys).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := NewHasher(testArgon2id).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := pepperedHasher(t, "2", keys)
+	tests := []struct {
+		name   string
+		hasher *Hasher
+		hash   string
+	}{
+		{"current pepper changed", rotated, old},
+		{"pepper added to unpeppered hashes", rotated, plain},
+		{"pepper turned off", pepperedHasher(t, "", keys), old},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := tt.hasher.Verify("secret", tt.hash)
+			if err != nil || !ok || !needsRehash {
+				t.Errorf("Verify = %v, %v, %v, want true, true, nil", ok, needsRehash, err)
+			}
+		})
+	}
+
+	// Пересчитанный хеш создан с новой версией и больше не требует пересчета
+	fresh, err := rotated.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ := splitPeppered(fresh); version != "2" {
+		t.Errorf("new hash uses pepper %q, want 2", version)
+	}
+	if ok, needsRehash, err := rotated.Verify("secret", fresh); err != nil || !ok || needsRehash {
+		t.Errorf("Verify(fresh) = %v, %v, %v, want true, false, nil", ok, needsRehash, err)
+	}
+}
+
+func TestWithPeppersRejectsBadVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		peppers Peppers
+	}{
+		{"dollar in version", Peppers{Current: "1", Keys: map[string][]byte{"1": pepper1, "a$b": pepper2}}},
+		{"dollar in current", Peppers{Current: "a$b", Keys: map[string][]byte{"a$b": pepper1}}},
+		{"empty version", Peppers{Keys: map[string][]byte{"": pepper1}}},
+		{"current without key", Peppers{Current: "2", Keys: map[string][]byte{"1": pepper1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHasher(testArgon2id).WithPeppers(tt.peppers); err == nil {
+				t.Error("WithPeppers accepted invalid peppers")
+			}
+		})
+	}
+}
+
+func TestSplitPeppered(t *testing.T) {
+	tests := []struct {
+		encoded, version, inner string
+		ok                      bool
+	}{
+		{"$pepper$2$argon2id$v=19$m=64,t=1,p=1$salt$key", "2", "$argon2id$v=19$m=64,t=1,p=1$salt$key", true},
+		{"$pepper$v10$2a$10$abc", "v10", "$2a$10$abc", true},
+		{"$argon2id$v=19$m=64,t=1,p=1$salt$key", "", "", false},
+		{"$pepper$$argon2id$", "", "", false},
+		{"$pepper$1", "", "", false},
+	}
+	for _, tt := range tests {
+		version, inner, ok := splitPeppered(tt.encoded)
+		if version != tt.version || inner != tt.inner || ok != tt.ok {
+			t.Errorf("splitPeppered(%q) = %q, %q, %v, want %q, %q, %v", tt.encoded, version, inner, ok, tt.version, tt.inner, tt.ok)
+		}
+	}
+}
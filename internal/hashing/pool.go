@@ -0,0 +1,244 @@
+// Package hashing выполняет хеширование паролей на ограниченном пуле воркеров,
+// чтобы всплеск входов не занимал все процессоры и не блокировал остальные запросы.
+package hashing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded возвращается сразу, если очередь заполнена: клиенту лучше
+// получить 503 и повторить позже, чем ждать, пока истечет таймаут запроса
+var ErrOverloaded = errors.New("password hashing queue is full")
+
+// ErrClosed возвращается после Close
+var ErrClosed = errors.New("password hashing pool is closed")
+
+type job struct {
+	ctx      context.Context
+	fn       func()
+	done     chan struct{}
+	enqueued time.Time
+	// panicked - fn завершилась паникой; читается после закрытия done
+	panicked error
+}
+
+// Pool - пул воркеров с ограниченной очередью
+type Pool struct {
+	hasher  *Hasher
+	workers int
+	jobs    chan *job
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	// dummy - хеш случайного пароля для VerifyDummy. Считается в NewPool:
+	// иначе первый вход неизвестного пользователя занял бы два хеширования
+	// и отличался по времени от входа с неверным паролем
+	dummy    string
+	dummyErr error
+
+	queued    atomic.Int64
+	inFlight  atomic.Int64
+	completed atomic.Uint64
+	rejected  atomic.Uint64
+	canceled  atomic.Uint64
+	waitNanos atomic.Int64
+	workNanos atomic.Int64
+}
+
+// NewPool запускает workers воркеров, при workers <= 0 - по числу процессоров
+// (GOMAXPROCS); queueSize - сколько задач может ждать свободного воркера,
+// прежде чем новые начнут отклоняться, при queueSize <= 0 - по одной задаче
+// на воркер: без буфера задача отклонялась бы, даже когда воркер свободен,
+// но еще не вернулся к чтению очереди. Хеш для
+// VerifyDummy считается здесь же, до первого запроса.
+func NewPool(hasher *Hasher, workers, queueSize int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if queueSize <= 0 {
+		queueSize = workers
+	}
+	p := &Pool{
+		hasher:  hasher,
+		workers: workers,
+		jobs:    make(chan *job, queueSize),
+	}
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	p.dummy, p.dummyErr = hasher.Hash(hex.EncodeToString(secret))
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for j := range p.jobs {
+		p.queued.Add(-1)
+		p.waitNanos.Add(int64(time.Since(j.enqueued)))
+
+		// Запрос отменен, пока задача стояла в очереди - не тратим на нее CPU
+		if j.ctx.Err() != nil {
+			p.canceled.Add(1)
+			close(j.done)
+			continue
+		}
+
+		p.inFlight.Add(1)
+		start := time.Now()
+		j.panicked = run(j.fn)
+		p.workNanos.Add(int64(time.Since(start)))
+		p.inFlight.Add(-1)
+		p.completed.Add(1)
+		close(j.done)
+	}
+}
+
+// run выполняет fn и превращает панику в ошибку: испорченный хеш одного
+// пользователя не должен останавливать воркер и весь сервер
+func run(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: panic while hashing: %v", ErrMalformedHash, r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// Do выполняет fn на воркере и ждет результата. Если ctx отменен раньше,
+// Do возвращает ctx.Err(); уже начатое вычисление доводится до конца,
+// но его результат отбрасывается.
+func (p *Pool) Do(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	j := &job{ctx: ctx, fn: fn, done: make(chan struct{}), enqueued: time.Now()}
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrClosed
+	}
+	p.queued.Add(1)
+	select {
+	case p.jobs <- j:
+		p.mu.RUnlock()
+	default:
+		p.mu.RUnlock()
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return ErrOverloaded
+	}
+
+	select {
+	case <-j.done:
+		if j.panicked != nil {
+			return j.panicked
+		}
+		// Воркер мог пропустить задачу, если контекст отменили в очереди
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Hash хеширует пароль на воркере пула
+func (p *Pool) Hash(ctx context.Context, password string) (string, error) {
+	var (
+		hash    string
+		hashErr error
+	)
+	if err := p.Do(ctx, func() { hash, hashErr = p.hasher.Hash(password) }); err != nil {
+		return "", err
+	}
+	return hash, hashErr
+}
+
+// Verify проверяет пароль против хеша на воркере пула; needsRehash -
+// см. Hasher.Verify
+func (p *Pool) Verify(ctx context.Context, password, hash string) (ok, needsRehash bool, err error) {
+	// Воркер пишет в локальные переменные: после отмены ctx он может
+	// закончить уже после возврата из Verify
+	var (
+		match, rehash bool
+		verifyErr     error
+	)
+	if err := p.Do(ctx, func() { match, rehash, verifyErr = p.hasher.Verify(password, hash) }); err != nil {
+		return false, false, err
+	}
+	return match, rehash, verifyErr
+}
+
+// VerifyDummy проверяет пароль против хеша случайного пароля с текущими
+// параметрами и перцем. Вход неизвестного пользователя так занимает столько
+// же, сколько вход с неверным паролем, и по времени ответа нельзя узнать,
+// зарегистрирован ли логин. Хеши устаревших алгоритмов проверяются за другое
+// время, пока их не пересчитают при входе. Ошибка - только от пула или ctx.
+func (p *Pool) VerifyDummy(ctx context.Context, password string) error {
+	if p.dummyErr != nil {
+		return p.dummyErr
+	}
+	var verifyErr error
+	err := p.Do(ctx, func() { _, _, verifyErr = p.hasher.Verify(password, p.dummy) })
+	if err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+// Close перестает принимать задачи и дожидается завершения поставленных
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+// Stats - метрики пула для /debug/vars и внутреннего эндпоинта метрик
+type Stats struct {
+	Workers   int    `json:"workers"`
+	QueueSize int    `json:"queue_size"`
+	Queued    int64  `json:"queued"`
+	InFlight  int64  `json:"in_flight"`
+	Completed uint64 `json:"completed"`
+	Rejected  uint64 `json:"rejected"`
+	Canceled  uint64 `json:"canceled"`
+	// Суммарное время ожидания в очереди и работы воркеров
+	WaitSeconds float64 `json:"wait_seconds_total"`
+	WorkSeconds float64 `json:"work_seconds_total"`
+}
+
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:     p.workers,
+		QueueSize:   cap(p.jobs),
+		Queued:      p.queued.Load(),
+		InFlight:    p.inFlight.Load(),
+		Completed:   p.completed.Load(),
+		Rejected:    p.rejected.Load(),
+		Canceled:    p.canceled.Load(),
+		WaitSeconds: time.Duration(p.waitNanos.Load()).Seconds(),
+		WorkSeconds: time.Duration(p.workNanos.Load()).Seconds(),
+	}
+}
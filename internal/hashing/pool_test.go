@@ -0,0 +1,144 @@
+package hashing
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitFor ждет, пока cond станет истинным: воркеры пула работают в своих горутинах
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// busyPool возвращает пул из одного воркера, занятого до вызова release
+func busyPool(t *testing.T, queueSize int) (pool *Pool, release func()) {
+	t.Helper()
+	pool = NewPool(NewHasher(testArgon2id), 1, queueSize)
+	blocked := make(chan struct{})
+	go pool.Do(context.Background(), func() { <-blocked })
+	waitFor(t, "the worker to start", func() bool { return pool.Stats().InFlight == 1 })
+
+	released := false
+	release = func() {
+		if !released {
+			released = true
+			close(blocked)
+		}
+	}
+	t.Cleanup(func() {
+		release()
+		pool.Close()
+	})
+	return pool, release
+}
+
+func TestPoolOverloaded(t *testing.T) {
+	pool, release := busyPool(t, 1)
+
+	queued := make(chan error, 1)
+	go func() { queued <- pool.Do(context.Background(), func() {}) }()
+	waitFor(t, "the job to queue", func() bool { return pool.Stats().Queued == 1 })
+
+	// Очередь заполнена: отказ сразу, без ожидания воркера
+	if err := pool.Do(context.Background(), func() { t.Error("rejected job ran") }); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("Do on a full queue = %v, want ErrOverloaded", err)
+	}
+	if _, err := pool.Hash(context.Background(), "secret"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Hash on a full queue = %v, want ErrOverloaded", err)
+	}
+	if stats := pool.Stats(); stats.Rejected != 2 {
+		t.Errorf("Rejected = %d, want 2", stats.Rejected)
+	}
+
+	release()
+	if err := <-queued; err != nil {
+		t.Errorf("queued job: %v", err)
+	}
+}
+
+func TestPoolCanceledWhileQueued(t *testing.T) {
+	pool, release := busyPool(t, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	result := make(chan error, 1)
+	go func() { result <- pool.Do(ctx, func() { ran <- struct{}{} }) }()
+	waitFor(t, "the job to queue", func() bool { return pool.Stats().Queued == 1 })
+
+	// Do возвращается сразу после отмены, не дожидаясь воркера
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do after cancel = %v, want context.Canceled", err)
+	}
+
+	// Воркер пропускает отмененную задачу и не тратит на нее CPU
+	release()
+	waitFor(t, "the canceled job to be skipped", func() bool { return pool.Stats().Canceled == 1 })
+	select {
+	case <-ran:
+		t.Error("canceled job ran")
+	default:
+	}
+
+	if err := pool.Do(context.Background(), func() {}); err != nil {
+		t.Errorf("Do after a canceled job: %v", err)
+	}
+}
+
+func TestPoolCanceledBeforeDo(t *testing.T) {
+	pool := NewPool(NewHasher(testArgon2id), 1, 1)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Do(ctx, func() { t.Error("job with a canceled context ran") }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewPoolDefaults(t *testing.T) {
+	tests := []struct {
+		name              string
+		workers, queue    int
+		wantWork, wantCap int
+	}{
+		{"zero workers", 0, 64, runtime.GOMAXPROCS(0), 64},
+		{"negative values", -1, -1, runtime.GOMAXPROCS(0), runtime.GOMAXPROCS(0)},
+		{"zero queue", 2, 0, 2, 2},
+		{"explicit", 3, 5, 3, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewPool(NewHasher(testArgon2id), tt.workers, tt.queue)
+			defer pool.Close()
+
+			stats := pool.Stats()
+			if stats.Workers != tt.wantWork || stats.QueueSize != tt.wantCap {
+				t.Errorf("Workers, QueueSize = %d, %d, want %d, %d", stats.Workers, stats.QueueSize, tt.wantWork, tt.wantCap)
+			}
+			if _, err := pool.Hash(context.Background(), "secret"); err != nil {
+				t.Errorf("Hash: %v", err)
+			}
+		})
+	}
+}
+
+func TestPoolClosed(t *testing.T) {
+	pool := NewPool(NewHasher(testArgon2id), 1, 1)
+	pool.Close()
+	pool.Close()
+
+	if err := pool.Do(context.Background(), func() {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Do after Close = %v, want ErrClosed", err)
+	}
+}
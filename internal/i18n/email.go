@@ -0,0 +1,23 @@
+package i18n
+
+import "fmt"
+
+// Email - письмо на языке получателя
+type Email struct {
+	Subject string
+	Body    string
+}
+
+// RenderEmail собирает письмо из шаблонов каталога email.<name>.subject и email.<name>.body
+func RenderEmail(locale, name string, params Params) (Email, error) {
+	subjectKey := "email." + name + ".subject"
+	bodyKey := "email." + name + ".body"
+	if !Has(locale, subjectKey) || !Has(locale, bodyKey) {
+		return Email{}, fmt.Errorf("email template %q not found", name)
+	}
+
+	return Email{
+		Subject: T(locale, subjectKey, params),
+		Body:    T(locale, bodyKey, params),
+	}, nil
+}
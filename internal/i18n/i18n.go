@@ -0,0 +1,170 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Поддерживаемые языки
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Fallback - язык, на котором есть все сообщения; используется, если перевода нет
+const Fallback = English
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// message - строка каталога, либо набор форм множественного числа
+type message struct {
+	text  string
+	forms map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &m.forms); err != nil {
+		return err
+	}
+	if m.forms["other"] == "" {
+		return fmt.Errorf("plural message must have an \"other\" form")
+	}
+	return nil
+}
+
+var catalogs = mustLoadCatalogs()
+
+func mustLoadCatalogs() map[string]map[string]message {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]map[string]message)
+	for _, entry := range entries {
+		content, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		catalog := make(map[string]message)
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: parse %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+
+	return loaded
+}
+
+// Supported сообщает, есть ли каталог для языка
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Locales возвращает отсортированный список поддерживаемых языков
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Params - значения для подстановки в сообщение: {name} заменяется на Params["name"].
+// Params["count"] также выбирает форму множественного числа.
+type Params map[string]interface{}
+
+// Has сообщает, есть ли ключ в каталоге языка или в резервном каталоге
+func Has(locale, key string) bool {
+	if _, ok := catalogs[locale][key]; ok {
+		return true
+	}
+	_, ok := catalogs[Fallback][key]
+	return ok
+}
+
+// T переводит сообщение. Если ключа нет в каталоге языка, используется английский,
+// если нет и там - возвращается сам ключ.
+func T(locale, key string, params Params) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		locale = Fallback
+		if msg, ok = catalogs[Fallback][key]; !ok {
+			return key
+		}
+	}
+
+	text := msg.text
+	if msg.forms != nil {
+		text = msg.forms[pluralCategory(locale, params["count"])]
+		if text == "" {
+			text = msg.forms["other"]
+		}
+	}
+
+	return interpolate(text, params)
+}
+
+func interpolate(text string, params Params) string {
+	if len(params) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	replacements := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(text)
+}
+
+// pluralCategory выбирает форму множественного числа по правилам CLDR
+func pluralCategory(locale string, count interface{}) string {
+	n, ok := toInt(count)
+	if !ok {
+		return "other"
+	}
+	if n < 0 {
+		n = -n
+	}
+
+	switch locale {
+	case Russian:
+		mod10, mod100 := n%10, n%100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == float64(int(v))
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRussianPlurals(t *testing.T) {
+	tests := []struct {
+		count int
+		want  string
+	}{
+		{1, "one"},
+		{2, "few"},
+		{4, "few"},
+		{5, "many"},
+		{11, "many"},
+		{12, "many"},
+		{14, "many"},
+		{21, "one"},
+		{22, "few"},
+		{25, "many"},
+		{101, "one"},
+		{111, "many"},
+		{0, "many"},
+	}
+	for _, tt := range tests {
+		if got := pluralCategory(Russian, tt.count); got != tt.want {
+			t.Errorf("pluralCategory(ru, %d) = %q, want %q", tt.count, got, tt.want)
+		}
+	}
+
+	// Формы из каталога подставляются с числом
+	for count, want := range map[int]string{
+		1:  "через 1 секунду",
+		2:  "через 2 секунды",
+		5:  "через 5 секунд",
+		11: "через 11 секунд",
+		21: "через 21 секунду",
+		22: "через 22 секунды",
+		25: "через 25 секунд",
+	} {
+		got := T(Russian, "error.rate_limited.seconds", Params{"count": count})
+		if !strings.HasSuffix(got, want) {
+			t.Errorf("T(ru, %d) = %q, want suffix %q", count, got, want)
+		}
+	}
+}
+
+func TestEnglishPlurals(t *testing.T) {
+	if got := T(English, "error.rate_limited.seconds", Params{"count": 1}); !strings.HasSuffix(got, "1 second") {
+		t.Errorf("T(en, 1) = %q", got)
+	}
+	if got := T(English, "error.rate_limited.seconds", Params{"count": 21}); !strings.HasSuffix(got, "21 seconds") {
+		t.Errorf("T(en, 21) = %q", got)
+	}
+	// Без числа выбирается форма other
+	if got := pluralCategory(English, "five"); got != "other" {
+		t.Errorf("pluralCategory(en, non-number) = %q, want other", got)
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := T("de", "error.rate_limited", nil); got != T(English, "error.rate_limited", nil) {
+		t.Errorf("T(de) = %q, want the English text", got)
+	}
+	if got := T(Russian, "no.such.key", nil); got != "no.such.key" {
+		t.Errorf("T(missing key) = %q, want the key itself", got)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty header", "", Russian},
+		{"exact", "en", English},
+		{"region", "en-GB", English},
+		{"region in upper case", "RU-ru", Russian},
+		{"order without weights", "en, ru", English},
+		{"weights", "en;q=0.5, ru;q=0.8", Russian},
+		{"browser header", "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", Russian},
+		{"unsupported first", "de-DE, en;q=0.3", English},
+		{"only unsupported", "de, fr;q=0.9", Russian},
+		{"q=0 excludes", "en;q=0, ru;q=0.1", Russian},
+		{"all excluded", "en;q=0", Russian},
+		{"invalid weight skipped", "en;q=abc, ru;q=0.2", Russian},
+		{"wildcard", "*", Russian},
+		{"equal weights keep order", "ru;q=0.5, en;q=0.5", Russian},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.header, Russian); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+
+	if got := Negotiate("de", English); got != English {
+		t.Errorf("Negotiate with default en = %q", got)
+	}
+}
@@ -0,0 +1,144 @@
+// Package mail отправляет письма пользователям: через SMTP-сервер или, при
+// локальной разработке, в лог. Отправка идет в фоне через Queue, чтобы
+// время ответа API не зависело от почтового сервера.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"LOIL-auth-server/pkg/logger"
+)
+
+// Message - письмо одному получателю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письмо
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// errHeaderInjection - перевод строки в адресе или теме добавил бы свои заголовки
+var errHeaderInjection = errors.New("mail header contains a line break")
+
+// SMTPSender отправляет письма через SMTP-сервер. Если сервер поддерживает
+// STARTTLS, соединение шифруется; логин и пароль передаются только по TLS.
+type SMTPSender struct {
+	addr     string
+	from     string
+	username string
+	password string
+}
+
+// NewSMTPSender готовит отправку через сервер addr (host:port) от имени from;
+// username пустой - без аутентификации
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	return &SMTPSender{addr: addr, from: from, username: username, password: password}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	body, err := format(s.from, msg)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(s.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.username != "" {
+		// PlainAuth сам отказывается работать без TLS, кроме localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// format собирает письмо в формате RFC 5322: тема в кодировке RFC 2047,
+// текст в UTF-8 и base64
+func format(from string, msg Message) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes(), nil
+}
+
+// LogSender пишет письма в лог вместо отправки; для локальной разработки
+type LogSender struct {
+	logger *logger.Logger
+}
+
+func NewLogSender(logger *logger.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.Info(fmt.Sprintf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body))
+	return nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"LOIL-auth-server/pkg/logger"
+)
+
+// sendTimeout ограничивает отправку одного письма
+const sendTimeout = 30 * time.Second
+
+// Queue отправляет письма в фоне одним воркером. Если очередь заполнена,
+// письмо отбрасывается с записью в лог: запрос пользователя не должен
+// ждать почтового сервера.
+type Queue struct {
+	sender Sender
+	logger *logger.Logger
+	jobs   chan Message
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue запускает воркер; size - сколько писем может ждать отправки
+func NewQueue(sender Sender, size int, logger *logger.Logger) *Queue {
+	q := &Queue{
+		sender: sender,
+		logger: logger,
+		jobs:   make(chan Message, size),
+	}
+	q.wg.Add(1)
+	go q.work()
+	return q
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		if err := q.sender.Send(ctx, msg); err != nil {
+			q.logger.Error("Mail: sending failed:", err)
+		}
+		cancel()
+	}
+}
+
+// Send ставит письмо в очередь и сразу возвращается; ошибки отправки
+// попадают в лог. Реализует Sender, поэтому очередь можно передать туда же,
+// куда и отправителя.
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.logger.Warn("Mail: queue is closed, message dropped")
+		return nil
+	}
+	select {
+	case q.jobs <- msg:
+	default:
+		q.logger.Warn("Mail: queue is full, message dropped")
+	}
+	return nil
+}
+
+// Close перестает принимать письма и дожидается отправки поставленных
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
@@ -1,34 +1,37 @@
 package middleware
 
 import (
+	"LOIL-auth-server/internal/apierror"
 	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/i18n"
 	"LOIL-auth-server/internal/utils"
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 )
 
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func AuthMiddleware(cfg *config.Manager, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, `{"error": "Authorization header required"}`, http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.ErrUnauthorized)
 			return
 		}
 
 		// Формат: Bearer <token>
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, `{"error": "Invalid authorization format"}`, http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.ErrInvalidAuthFormat)
 			return
 		}
 
 		tokenString := parts[1]
-		cfg, _ := config.Load()
 
-		claims, err := utils.ValidateJWT(tokenString, cfg.JWTSecret)
+		claims, err := utils.ValidateJWT(tokenString, cfg.Get().JWT.VerificationKeys())
 		if err != nil {
-			http.Error(w, `{"error": "Invalid token"}`, http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.ErrInvalidToken.Wrap(err))
 			return
 		}
 
@@ -41,11 +44,21 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func CORS(next http.Handler) http.Handler {
+// CORS разрешает кросс-доменные запросы только для origin из cors.allowed_origins.
+// Список читается при каждом запросе, поэтому меняется через SIGHUP без перезапуска.
+func CORS(cfg *config.Manager, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		origin := r.Header.Get("Origin")
+		if allowed, wildcard := originAllowed(cfg.Get().CORS.AllowedOrigins, origin); allowed {
+			if wildcard {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -55,3 +68,50 @@ func CORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func originAllowed(allowedOrigins []string, origin string) (allowed, wildcard bool) {
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == "*" {
+			return true, true
+		}
+		if origin != "" && strings.EqualFold(allowedOrigin, origin) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// Locale определяет язык ответа по Accept-Language; если подходящего нет,
+// используется i18n.default_locale. Обработчики могут переопределить язык
+// настройкой пользователя.
+func Locale(cfg *config.Manager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"), cfg.Get().I18n.DefaultLocale)
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
+
+// RequireClient пропускает только запросы с клиентским сертификатом, CN или
+// DNS-имя которого есть в allowed. Пустой allowed пропускает любой
+// сертификат, проверенный TLS-листенером.
+func RequireClient(allowed []string, next http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			apierror.Write(w, r, apierror.ErrForbidden)
+			return
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+		if slices.Contains(allowed, cert.Subject.CommonName) || slices.ContainsFunc(cert.DNSNames, func(name string) bool {
+			return slices.Contains(allowed, name)
+		}) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		apierror.Write(w, r, apierror.ErrForbidden.Wrap(fmt.Errorf("client certificate %q is not allowed", cert.Subject.CommonName)))
+	})
+}
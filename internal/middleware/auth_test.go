@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireClient(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	handler := RequireClient([]string{"admin-cli", "ops.example.com"}, ok)
+
+	withCert := func(cn string, dns ...string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/internal/admin/users/import", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}, DNSNames: dns}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"allowed common name", withCert("admin-cli"), http.StatusNoContent},
+		{"allowed DNS name", withCert("node-7", "ops.example.com"), http.StatusNoContent},
+		{"game server", withCert("game-server-1", "gs1.example.com"), http.StatusForbidden},
+		{"no TLS", httptest.NewRequest(http.MethodPost, "/internal/admin/users/import", nil), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// Пустой список пропускает любой проверенный сертификат
+	rec := httptest.NewRecorder()
+	RequireClient(nil, ok).ServeHTTP(rec, withCert("anyone"))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("empty allow list: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"LOIL-auth-server/internal/apierror"
+	"LOIL-auth-server/internal/config"
+)
+
+// RateLimiter ограничивает частоту запросов с одного IP (token bucket).
+// Лимиты читаются из конфигурации при каждом запросе и меняются через SIGHUP.
+// За reverse proxy адрес клиента берется из заголовков proxy, только если
+// запрос пришел с адреса из rate_limit.trusted_proxies.
+type RateLimiter struct {
+	cfg *config.Manager
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Бакеты, к которым не обращались дольше этого времени, удаляются
+const bucketIdleTTL = 10 * time.Minute
+
+func NewRateLimiter(cfg *config.Manager) *RateLimiter {
+	return &RateLimiter{
+		cfg:         cfg,
+		buckets:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Limit оборачивает обработчик ограничением частоты запросов
+func (rl *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limits := rl.cfg.Get().RateLimit
+		if !limits.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, retryAfter := rl.allow(clientIP(r, limits.TrustedProxyPrefixes()), limits, time.Now())
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			apierror.Write(w, r, apierror.ErrRateLimited.WithRetryAfter(seconds))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+func (rl *RateLimiter) allow(key string, limits config.RateLimitConfig, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastCleanup) > bucketIdleTTL {
+		for k, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > bucketIdleTTL {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastCleanup = now
+	}
+
+	perSecond := float64(limits.RequestsPerMinute) / 60
+	burst := float64(limits.Burst)
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, lastSeen: now}
+		rl.buckets[key] = b
+	}
+
+	// Пополняем бакет за прошедшее время; burst мог уменьшиться после перезагрузки
+	b.tokens += now.Sub(b.lastSeen).Seconds() * perSecond
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// clientIP возвращает адрес клиента. Если соединение открыл доверенный proxy,
+// X-Forwarded-For читается справа налево до первого недоверенного адреса:
+// левые значения клиент может подставить сам, а правые дописаны нашими
+// proxy. Без X-Forwarded-For используется X-Real-IP.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(peer, trusted) {
+		return host
+	}
+
+	hops := r.Header.Values("X-Forwarded-For")
+	if len(hops) == 0 {
+		if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return realIP.Unmap().String()
+		}
+		return host
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		list := strings.Split(hops[i], ",")
+		for j := len(list) - 1; j >= 0; j-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(list[j]))
+			if err != nil {
+				// Мусор в заголовке: последний разобранный адрес надежнее
+				return client.Unmap().String()
+			}
+			client = hop
+			if !isTrusted(hop, trusted) {
+				return client.Unmap().String()
+			}
+		}
+	}
+	// Все адреса доверенные: клиент - самый левый
+	return client.Unmap().String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"LOIL-auth-server/internal/config"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := config.RateLimitConfig{TrustedProxies: []string{"10.0.0.0/8", "::1"}}.TrustedProxyPrefixes()
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "", "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:5000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"client prepends fake hop", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:5000", []string{"198.51.100.1, 10.1.1.1", "10.0.0.3"}, "", "198.51.100.1"},
+		{"all hops trusted", "10.0.0.2:5000", []string{"10.0.0.5"}, "", "10.0.0.5"},
+		{"garbage hop", "10.0.0.2:5000", []string{"unknown, 10.0.0.3"}, "", "10.0.0.3"},
+		{"x-real-ip", "10.0.0.2:5000", nil, "198.51.100.1", "198.51.100.1"},
+		{"bad x-real-ip", "10.0.0.2:5000", nil, "nonsense", "10.0.0.2"},
+		{"no headers", "10.0.0.2:5000", nil, "", "10.0.0.2"},
+		{"ipv6 proxy", "[::1]:5000", []string{"2001:db8::1"}, "", "2001:db8::1"},
+		{"ipv4-mapped hop", "10.0.0.2:5000", []string{"::ffff:198.51.100.1"}, "", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r, trusted); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Без trusted_proxies заголовки не читаются
+	r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r, nil); got != "10.0.0.2" {
+		t.Errorf("clientIP without trusted proxies = %q, want the peer address", got)
+	}
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	t.Setenv("RATE_LIMIT_ENABLED", "true")
+	t.Setenv("RATE_LIMIT_REQUESTS_PER_MINUTE", "1")
+	t.Setenv("RATE_LIMIT_BURST", "2")
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "127.0.0.1")
+	cfg, err := config.NewManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewRateLimiter(cfg).Limit(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	request := func(client string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		r.RemoteAddr = "127.0.0.1:40000"
+		r.Header.Set("X-Forwarded-For", client)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		return rec.Code
+	}
+
+	for i := range 2 {
+		if code := request("198.51.100.1"); code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d, want %d", i+1, code, http.StatusNoContent)
+		}
+	}
+	if code := request("198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("over the burst: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	// Другой клиент за тем же proxy не разделяет чужой лимит
+	if code := request("198.51.100.2"); code != http.StatusNoContent {
+		t.Errorf("another client behind the proxy: status = %d, want %d", code, http.StatusNoContent)
+	}
+}
+
+func TestTrustedProxiesValidated(t *testing.T) {
+	t.Setenv("RATE_LIMIT_TRUSTED_PROXIES", "10.0.0.0/8,proxy.local")
+	if _, err := config.NewManager(""); err == nil {
+		t.Error("config with a host name in trusted_proxies accepted")
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Действия правил имен
+const (
+	NameRuleBlock = "block"
+	NameRuleAllow = "allow"
+)
+
+// NameRule - шаблон логина или игровой фамилии, который администратор
+// запретил или разрешил в дополнение к встроенным спискам
+type NameRule struct {
+	ID      int    `json:"id"`
+	Pattern string `json:"pattern"`
+	// Action - block или allow; allow перекрывает любые запреты
+	Action    string    `json:"action"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -3,28 +3,45 @@ package models
 import "time"
 
 type User struct {
-	ID          int       `json:"id"`
-	Login       string    `json:"login"`
-	GameSurname string    `json:"gameSurname"`
-	Email       string    `json:"email"`
-	Password    string    `json:"-"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          int    `json:"id"`
+	Login       string `json:"login"`
+	GameSurname string `json:"gameSurname"`
+	// GameSurnameLatin - фамилия латиницей для клиентов без кириллицы;
+	// заполняет хранилище
+	GameSurnameLatin string    `json:"gameSurnameLatin"`
+	Email            string    `json:"email"`
+	Password         string    `json:"-"`
+	Locale           string    `json:"locale"` // предпочитаемый язык; пусто - по Accept-Language
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+
+	// PasswordBreached - пароль найден в базе утечек при входе, его нужно сменить
+	PasswordBreached bool `json:"passwordBreached"`
 }
 
 type UserResponse struct {
 	ID          int    `json:"id"`
 	Login       string `json:"login"`
 	GameSurname string `json:"gameSurname"`
-	Email       string `json:"email"`
+	// GameSurnameLatin совпадает с GameSurname, если фамилия латиницей
+	GameSurnameLatin string `json:"gameSurnameLatin"`
+	Email            string `json:"email"`
+	Locale           string `json:"locale,omitempty"`
+
+	// PasswordBreached подсказывает клиенту предложить смену пароля
+	PasswordBreached bool `json:"passwordBreached,omitempty"`
 }
 
 // ToResponse преобразует User в UserResponse (без пароля)
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:          u.ID,
-		Login:       u.Login,
-		GameSurname: u.GameSurname,
-		Email:       u.Email,
+		ID:               u.ID,
+		Login:            u.Login,
+		GameSurname:      u.GameSurname,
+		GameSurnameLatin: u.GameSurnameLatin,
+		Email:            u.Email,
+		Locale:           u.Locale,
+
+		PasswordBreached: u.PasswordBreached,
 	}
-}
\ No newline at end of file
+}
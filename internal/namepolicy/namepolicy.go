@@ -0,0 +1,203 @@
+// Package namepolicy запрещает логины и игровые фамилии из списков: служебные
+// имена, под которыми можно выдать себя за администрацию, и брань на русском
+// и английском. Встроенные списки дополняются правилами администраторов из
+// базы: block запрещает шаблон, allow разрешает имя, даже если оно попало в
+// любой из списков.
+package namepolicy
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/validation"
+	"LOIL-auth-server/pkg/logger"
+)
+
+//go:embed lists/*.txt
+var listFS embed.FS
+
+// Причины запрета; передаются клиенту в params.reason ошибки поля
+const (
+	ReasonReserved  = "reserved"
+	ReasonProfanity = "profanity"
+	ReasonBlocked   = "blocked"
+)
+
+type listEntry struct {
+	pattern pattern
+	reason  string
+}
+
+// builtinLists - встроенные списки в порядке проверки: служебные имена
+// первыми, чтобы причина была точнее
+var builtinLists = []struct{ file, reason string }{
+	{"reserved", ReasonReserved},
+	{"profanity_en", ReasonProfanity},
+	{"profanity_ru", ReasonProfanity},
+}
+
+var builtin = mustLoadLists()
+
+func mustLoadLists() []listEntry {
+	var entries []listEntry
+	for _, list := range builtinLists {
+		file, err := listFS.Open("lists/" + list.file + ".txt")
+		if err != nil {
+			panic(err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			p, err := parsePattern(line)
+			if err != nil {
+				panic(fmt.Sprintf("namepolicy: %s list: %q: %v", list.file, line, err))
+			}
+			entries = append(entries, listEntry{pattern: p, reason: list.reason})
+		}
+		if err := scanner.Err(); err != nil {
+			panic(fmt.Sprintf("namepolicy: read %s list: %v", list.file, err))
+		}
+		file.Close()
+	}
+	return entries
+}
+
+// Verdict - итог проверки имени
+type Verdict struct {
+	Allowed bool `json:"allowed"`
+	// Reason - reserved, profanity или blocked; пусто, если имя разрешено
+	Reason string `json:"reason,omitempty"`
+	// Pattern - сработавший шаблон, в том числе разрешающий
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Filter проверяет имена по встроенным спискам и правилам из базы. Правила
+// перечитываются не чаще names.refresh_interval, чтобы изменения на других
+// экземплярах сервера доходили без перезапуска; Invalidate применяет
+// изменения этого экземпляра сразу.
+type Filter struct {
+	store  database.NameRuleStore
+	cfg    *config.Manager
+	logger *logger.Logger
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	// loading - правила читаются из базы; другие проверки не ждут и не
+	// запускают второй запрос
+	loading bool
+	blocked []pattern
+	allowed []pattern
+}
+
+func NewFilter(store database.NameRuleStore, cfg *config.Manager, logger *logger.Logger) *Filter {
+	return &Filter{
+		store:  store,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Check проверяет поле field и возвращает ошибку с кодом CodeNameNotAllowed,
+// если имя запрещено. При выключенной проверке (names.enabled) возвращает nil.
+func (f *Filter) Check(ctx context.Context, field, name string) *validation.FieldError {
+	if !f.cfg.Get().Names.Enabled {
+		return nil
+	}
+	verdict := f.Verdict(ctx, name)
+	if verdict.Allowed {
+		return nil
+	}
+	return validation.NewFieldError(field, validation.CodeNameNotAllowed, map[string]interface{}{"reason": verdict.Reason})
+}
+
+// Verdict проверяет имя независимо от names.enabled; нужен администраторам,
+// чтобы проверить правила
+func (f *Filter) Verdict(ctx context.Context, name string) Verdict {
+	forms := skeletons(name)
+	blocked, allowed := f.rules(ctx)
+
+	for _, p := range allowed {
+		if p.matches(forms) {
+			return Verdict{Allowed: true, Pattern: p.raw}
+		}
+	}
+	for _, p := range blocked {
+		if p.matches(forms) {
+			return Verdict{Reason: ReasonBlocked, Pattern: p.raw}
+		}
+	}
+	for _, entry := range builtin {
+		if entry.pattern.matches(forms) {
+			return Verdict{Reason: entry.reason, Pattern: entry.pattern.raw}
+		}
+	}
+	return Verdict{Allowed: true}
+}
+
+// Invalidate заставляет перечитать правила при следующей проверке
+func (f *Filter) Invalidate() {
+	f.mu.Lock()
+	f.loadedAt = time.Time{}
+	f.mu.Unlock()
+}
+
+// rules возвращает правила администраторов, при необходимости перечитывая их.
+// Запрос к базе идет без блокировки: остальные проверки в это время
+// используют прежние правила, а не ждут базу. Если база недоступна, прежние
+// правила остаются: регистрация не должна останавливаться из-за списка имен.
+func (f *Filter) rules(ctx context.Context) (blocked, allowed []pattern) {
+	f.mu.Lock()
+	blocked, allowed = f.blocked, f.allowed
+	fresh := !f.loadedAt.IsZero() && time.Since(f.loadedAt) < f.cfg.Get().Names.RefreshInterval
+	if fresh || f.loading {
+		f.mu.Unlock()
+		return blocked, allowed
+	}
+	// Следующая попытка - через интервал, даже если эта не удалась
+	f.loading = true
+	f.loadedAt = time.Now()
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.loading = false
+		f.mu.Unlock()
+	}()
+
+	list, err := f.store.ListNameRules(ctx)
+	if err != nil {
+		f.logger.Error("Name rules: loading failed, using previous rules:", err)
+		return blocked, allowed
+	}
+
+	blocked, allowed = nil, nil
+	for _, rule := range list {
+		p, err := parsePattern(rule.Pattern)
+		if err != nil {
+			f.logger.Warn(fmt.Sprintf("Name rules: rule %d %q skipped: %v", rule.ID, rule.Pattern, err))
+			continue
+		}
+		if rule.Action == models.NameRuleAllow {
+			allowed = append(allowed, p)
+		} else {
+			blocked = append(blocked, p)
+		}
+	}
+
+	f.mu.Lock()
+	f.blocked, f.allowed = blocked, allowed
+	f.mu.Unlock()
+	return blocked, allowed
+}
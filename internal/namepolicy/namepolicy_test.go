@@ -0,0 +1,228 @@
+package namepolicy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/validation"
+	"LOIL-auth-server/pkg/logger"
+)
+
+func newFilter(t *testing.T, store database.NameRuleStore) *Filter {
+	t.Helper()
+	cfg, err := config.NewManager("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFilter(store, cfg, logger.NewLogger())
+}
+
+func TestBuiltinBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+	}{
+		{"Admin", ReasonReserved},
+		{"Adm1n", ReasonReserved},
+		{"a.d.m.i.n", ReasonReserved},
+		{"admin123", ReasonReserved},
+		{"TheAdmin", ReasonReserved},
+		{"xXAdminXx", ReasonReserved},
+		{"superadmin", ReasonReserved},
+		{"Moderator", ReasonReserved},
+		{"Администратор", ReasonReserved},
+		{"LoilTeam", ReasonReserved},
+		{"cyka", ReasonProfanity},
+		{"s_u_k_a", ReasonProfanity},
+		{"huy", ReasonProfanity},
+		{"khuy", ReasonProfanity},
+		{"хуй", ReasonProfanity},
+		{"Хуйло", ReasonProfanity},
+		{"hujnya", ReasonProfanity},
+		{"Pizdec", ReasonProfanity},
+		{"eban", ReasonProfanity},
+		{"zaebal", ReasonProfanity},
+		{"pidoras", ReasonProfanity},
+		{"Blyat", ReasonProfanity},
+		{"Fuuuck", ReasonProfanity},
+		{"FuckYou", ReasonProfanity},
+		{"Cunt", ReasonProfanity},
+	}
+
+	f := newFilter(t, database.NewMemoryStore())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := f.Verdict(context.Background(), tt.name)
+			if verdict.Allowed || verdict.Reason != tt.reason {
+				t.Errorf("Verdict(%q) = %+v, want reason %q", tt.name, verdict, tt.reason)
+			}
+		})
+	}
+}
+
+// Обычные фамилии, в которых есть короткие корни из списков
+func TestBuiltinFalsePositives(t *testing.T) {
+	names := []string{
+		"Hues", "Khuev", "Suki", "Ebanov", "Zaebov", "Pidorenko", "Khuilov",
+		"Helperin", "Badminton", "Scunthorpe", "Sporn", "Nebalov", "Chuikov",
+		"Dickens", "Sukachev", "Cockburn", "Hitchcock", "Slutsky", "Fagin",
+		"Ivanov", "Petrov", "Smirnov", "Kuznetsov", "Сукачев", "Команда",
+	}
+
+	f := newFilter(t, database.NewMemoryStore())
+	for _, name := range names {
+		if verdict := f.Verdict(context.Background(), name); !verdict.Allowed {
+			t.Errorf("Verdict(%q) = %+v, want allowed", name, verdict)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Ivanov", []string{"Ivanov"}},
+		{"the_admin", []string{"the", "admin"}},
+		{"TheAdmin", []string{"The", "Admin"}},
+		{"xXAdminXx", []string{"x", "X", "Admin", "Xx"}},
+		{"ADMIN", []string{"ADMIN"}},
+		{"adm1n", []string{"adm1n"}},
+		{"a.b", []string{"a", "b"}},
+		{"__", nil},
+	}
+	for _, tt := range tests {
+		if got := words(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("words(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, raw := range []string{"admin", "admin*", "*admin", "*admin*", "хуй"} {
+		if err := ValidatePattern(raw); err != nil {
+			t.Errorf("ValidatePattern(%q) = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"", "*", "**", "ad*min", "___", "a234567890123456789012345678901234567890123456789012345678901234567890"} {
+		if err := ValidatePattern(raw); err == nil {
+			t.Errorf("ValidatePattern(%q) accepted", raw)
+		}
+	}
+}
+
+func TestAdminRules(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	f := newFilter(t, store)
+
+	if f.Verdict(ctx, "Petrov").Allowed != true {
+		t.Fatal("Petrov blocked without rules")
+	}
+
+	allow := &models.NameRule{Pattern: "hui", Action: models.NameRuleAllow}
+	block := &models.NameRule{Pattern: "*petrov*", Action: models.NameRuleBlock}
+	for _, rule := range []*models.NameRule{allow, block} {
+		if err := store.AddNameRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Invalidate()
+
+	if verdict := f.Verdict(ctx, "P3trova"); verdict.Allowed || verdict.Reason != ReasonBlocked {
+		t.Errorf("Verdict(P3trova) = %+v, want blocked", verdict)
+	}
+	// Разрешающее правило сильнее встроенного списка
+	if verdict := f.Verdict(ctx, "Hui"); !verdict.Allowed || verdict.Pattern != "hui" {
+		t.Errorf("Verdict(Hui) = %+v, want allowed by rule", verdict)
+	}
+
+	fieldErr := f.Check(ctx, "gameSurname", "Petrov")
+	if fieldErr == nil || fieldErr.Code != validation.CodeNameNotAllowed || fieldErr.Params["reason"] != ReasonBlocked {
+		t.Errorf("Check(Petrov) = %+v, want name_not_allowed/blocked", fieldErr)
+	}
+
+	if err := store.DeleteNameRule(ctx, block.ID); err != nil {
+		t.Fatal(err)
+	}
+	f.Invalidate()
+	if verdict := f.Verdict(ctx, "Petrov"); !verdict.Allowed {
+		t.Errorf("Verdict(Petrov) after delete = %+v, want allowed", verdict)
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	t.Setenv("NAMES_ENABLED", "false")
+	f := newFilter(t, database.NewMemoryStore())
+	if fieldErr := f.Check(context.Background(), "login", "admin"); fieldErr != nil {
+		t.Errorf("Check with names.enabled=false = %+v, want nil", fieldErr)
+	}
+}
+
+// slowStore отвечает, только когда тест закроет release
+type slowStore struct {
+	database.NameRuleStore
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (s *slowStore) ListNameRules(ctx context.Context) ([]models.NameRule, error) {
+	close(s.started)
+	<-s.release
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []models.NameRule{{ID: 1, Pattern: "petrov", Action: models.NameRuleBlock}}, nil
+}
+
+func TestRulesLoadDoesNotBlockChecks(t *testing.T) {
+	store := &slowStore{started: make(chan struct{}), release: make(chan struct{})}
+	f := newFilter(t, store)
+
+	loaded := make(chan Verdict)
+	go func() { loaded <- f.Verdict(context.Background(), "Petrov") }()
+	<-store.started
+
+	// Пока база отвечает, проверки идут по прежним (пустым) правилам
+	done := make(chan Verdict)
+	go func() { done <- f.Verdict(context.Background(), "Petrov") }()
+	select {
+	case verdict := <-done:
+		if !verdict.Allowed {
+			t.Errorf("Verdict during load = %+v, want allowed by previous rules", verdict)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Verdict waited for the rules query")
+	}
+
+	close(store.release)
+	if verdict := <-loaded; verdict.Allowed {
+		t.Errorf("Verdict after load = %+v, want blocked", verdict)
+	}
+	if verdict := f.Verdict(context.Background(), "Petrov"); verdict.Allowed {
+		t.Errorf("Verdict with cached rules = %+v, want blocked", verdict)
+	}
+}
+
+func TestRulesLoadFailureKeepsPreviousRules(t *testing.T) {
+	store := &slowStore{started: make(chan struct{}), release: make(chan struct{})}
+	close(store.release)
+	f := newFilter(t, store)
+	if f.Verdict(context.Background(), "Petrov").Allowed {
+		t.Fatal("rule not loaded")
+	}
+
+	store.started = make(chan struct{})
+	store.err = errors.New("database is down")
+	f.Invalidate()
+	if f.Verdict(context.Background(), "Petrov").Allowed {
+		t.Error("failed reload dropped the previous rules")
+	}
+}
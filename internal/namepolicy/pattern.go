@@ -0,0 +1,87 @@
+package namepolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"LOIL-auth-server/internal/canonical"
+)
+
+// MaxPatternLength - предел длины шаблона в символах
+const MaxPatternLength = 64
+
+type matchKind int
+
+const (
+	matchExact matchKind = iota
+	matchPrefix
+	matchSuffix
+	matchContains
+)
+
+// pattern - шаблон из списка. Синтаксис: "слово" - имя целиком или одно из
+// его слов, "слово*" - начало имени или слова, "*слово" - конец, "*слово*" -
+// где угодно. Короткие корни, которые встречаются внутри обычных фамилий,
+// записываются без "*" с обеих сторон.
+type pattern struct {
+	raw  string
+	body string
+	kind matchKind
+}
+
+// ValidatePattern проверяет шаблон, который добавляет администратор
+func ValidatePattern(raw string) error {
+	_, err := parsePattern(raw)
+	return err
+}
+
+func parsePattern(raw string) (pattern, error) {
+	if utf8.RuneCountInString(raw) > MaxPatternLength {
+		return pattern{}, fmt.Errorf("pattern is longer than %d characters", MaxPatternLength)
+	}
+
+	p := pattern{raw: raw}
+	body := strings.TrimSpace(raw)
+	leading, trailing := strings.HasPrefix(body, "*"), strings.HasSuffix(body, "*")
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "*"), "*")
+	switch {
+	case leading && trailing:
+		p.kind = matchContains
+	case leading:
+		p.kind = matchSuffix
+	case trailing:
+		p.kind = matchPrefix
+	}
+	if strings.Contains(body, "*") {
+		return pattern{}, errors.New("* is allowed only at the start or the end of a pattern")
+	}
+
+	p.body = skeleton(canonical.Login(body), latinDigits, false)
+	if p.body == "" {
+		return pattern{}, errors.New("pattern must contain letters")
+	}
+	return p, nil
+}
+
+// matches сообщает, что под шаблон подходит хотя бы один скелет имени
+func (p pattern) matches(forms []string) bool {
+	for _, form := range forms {
+		var ok bool
+		switch p.kind {
+		case matchExact:
+			ok = form == p.body
+		case matchPrefix:
+			ok = strings.HasPrefix(form, p.body)
+		case matchSuffix:
+			ok = strings.HasSuffix(form, p.body)
+		case matchContains:
+			ok = strings.Contains(form, p.body)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
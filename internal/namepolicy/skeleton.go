@@ -0,0 +1,157 @@
+package namepolicy
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"LOIL-auth-server/internal/canonical"
+)
+
+// Имена и шаблоны сравниваются в виде скелета: кириллицей, без регистра,
+// разделителей и повторов букв. Латиница переводится в кириллицу, поэтому
+// "huy", "khui" и "хуй" дают один скелет, а русские слова в списках
+// находят и латинское написание. Английские слова в списках переводятся так
+// же и сравниваются с именами в том же виде.
+
+// digraphs - сочетания латинских букв, которые читаются одной русской буквой;
+// проверяются раньше одиночных букв, длинные раньше коротких
+var digraphs = []struct{ latin, cyrillic string }{
+	{"shch", "щ"}, {"sch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"tz", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"ya", "я"}, {"ia", "я"}, {"yu", "ю"}, {"iu", "ю"}, {"yo", "е"}, {"ye", "е"},
+}
+
+var latinLetters = map[rune]rune{
+	'a': 'а', 'b': 'б', 'c': 'ц', 'd': 'д', 'e': 'е', 'f': 'ф', 'g': 'г',
+	'h': 'х', 'i': 'и', 'j': 'и', 'k': 'к', 'l': 'л', 'm': 'м', 'n': 'н',
+	'o': 'о', 'p': 'п', 'q': 'к', 'r': 'р', 's': 'с', 't': 'т', 'u': 'у',
+	'v': 'в', 'w': 'в', 'x': 'х', 'y': 'и', 'z': 'з',
+}
+
+// lookalikes - латинские буквы, которыми подменяют похожие русские:
+// "cyka" читается как "сука", а не "цика"
+var lookalikes = map[rune]rune{
+	'a': 'а', 'b': 'в', 'c': 'с', 'e': 'е', 'h': 'н', 'k': 'к', 'm': 'м',
+	'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у',
+}
+
+// Цифры вместо букв: латинский вариант (4dm1n) и русский (3алупа, 4мо)
+var (
+	latinDigits   = map[rune]rune{'0': 'о', '1': 'и', '3': 'е', '4': 'а', '5': 'с', '6': 'б', '7': 'т', '8': 'в'}
+	russianDigits = map[rune]rune{'0': 'о', '1': 'и', '3': 'з', '4': 'ч', '5': 'с', '6': 'б', '7': 'т', '8': 'в'}
+)
+
+// cyrillicFold - различия, которые не меняют слово: ё и е, й и и, ы и и
+// (в транслите обе - y), твердый и мягкий знаки пропускаются
+var cyrillicFold = map[rune]rune{
+	'ё': 'е', 'э': 'е', 'й': 'и', 'ы': 'и', 'і': 'и', 'ъ': -1, 'ь': -1,
+}
+
+// skeletons возвращает варианты скелета имени целиком и каждого его слова:
+// цифры читаются как латинские или русские буквы либо отбрасываются
+// (admin123), латинские буквы - по звучанию или по виду. Имя запрещено, если
+// под шаблон подходит любой вариант.
+func skeletons(name string) []string {
+	parts := words(name)
+	if len(parts) > 1 {
+		parts = append([]string{name}, parts...)
+	}
+
+	var forms []string
+	for _, part := range parts {
+		folded := canonical.Login(part)
+		for _, digits := range []map[rune]rune{latinDigits, russianDigits, nil} {
+			for _, byLook := range []bool{false, true} {
+				form := skeleton(folded, digits, byLook)
+				if form != "" && !slices.Contains(forms, form) {
+					forms = append(forms, form)
+				}
+			}
+		}
+	}
+	return forms
+}
+
+// words делит имя на слова по разделителям и по смене регистра:
+// "the_admin" и "TheAdmin" - это "the" и "admin", "XAdmin" - "X" и "Admin".
+// Цифры слово не разделяют, чтобы "adm1n" осталось одним словом.
+func words(name string) []string {
+	runes := []rune(name)
+
+	var result []string
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				result = append(result, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || (unicode.IsUpper(prev) && nextLower) {
+				result = append(result, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		result = append(result, string(runes[start:]))
+	}
+	return result
+}
+
+// skeleton переводит строку в скелет. digits nil - цифры отбрасываются;
+// byLook - латинские буквы, похожие на русские, заменяются ими.
+// s уже в нижнем регистре.
+func skeleton(s string, digits map[rune]rune, byLook bool) string {
+	// Оставляем буквы и цифры: "ad_min" и "a.d.m.i.n" - это admin
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			if digits == nil {
+				return -1
+			}
+			if letter, ok := digits[r]; ok {
+				return letter
+			}
+			return -1
+		}
+		if !unicode.IsLetter(r) {
+			return -1
+		}
+		if byLook {
+			if cyrillic, ok := lookalikes[r]; ok {
+				return cyrillic
+			}
+		}
+		return r
+	}, s)
+
+	for _, d := range digraphs {
+		s = strings.ReplaceAll(s, d.latin, d.cyrillic)
+	}
+
+	var b strings.Builder
+	var last rune
+	for _, r := range s {
+		if cyrillic, ok := latinLetters[r]; ok {
+			r = cyrillic
+		}
+		if folded, ok := cyrillicFold[r]; ok {
+			r = folded
+		}
+		// Повторы схлопываются: "fuuuck" и "fuck", "ssuka" и "suka"
+		if r < 0 || r == last {
+			continue
+		}
+		b.WriteRune(r)
+		last = r
+	}
+	return b.String()
+}
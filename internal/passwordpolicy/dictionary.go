@@ -0,0 +1,100 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Словари упорядочены по частоте: чем выше слово, тем раньше его пробуют при подборе
+//
+//go:embed dict/*.txt
+var dictFS embed.FS
+
+// Имена словарей; по ним выбирается предупреждение для пользователя
+const (
+	dictPasswords  = "passwords"
+	dictEnglish    = "english"
+	dictNames      = "names"
+	dictRussian    = "russian"
+	dictUserInputs = "user_inputs"
+)
+
+// rankedDictionary - слово в нижнем регистре -> номер по частоте, начиная с 1
+type rankedDictionary map[string]int
+
+type namedDictionary struct {
+	name  string
+	words rankedDictionary
+}
+
+var (
+	dictionaries  = mustLoadDictionaries(dictPasswords, dictEnglish, dictNames, dictRussian)
+	maxWordLength = longestWord(dictionaries)
+)
+
+func mustLoadDictionaries(names ...string) []namedDictionary {
+	loaded := make([]namedDictionary, 0, len(names))
+	for _, name := range names {
+		file, err := dictFS.Open("dict/" + name + ".txt")
+		if err != nil {
+			panic(err)
+		}
+
+		words := make(rankedDictionary)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			if word == "" || strings.HasPrefix(word, "#") {
+				continue
+			}
+			if _, ok := words[word]; !ok {
+				words[word] = len(words) + 1
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			panic(fmt.Sprintf("passwordpolicy: read %s dictionary: %v", name, err))
+		}
+		file.Close()
+
+		loaded = append(loaded, namedDictionary{name: name, words: words})
+	}
+	return loaded
+}
+
+func longestWord(dicts []namedDictionary) int {
+	longest := 0
+	for _, dict := range dicts {
+		for word := range dict.words {
+			longest = max(longest, utf8.RuneCountInString(word))
+		}
+	}
+	return longest
+}
+
+// userInputsDictionary собирает словарь из данных пользователя: логина, частей
+// email, игровой фамилии. Их подбирают первыми, поэтому ранги минимальные.
+func userInputsDictionary(inputs []string) namedDictionary {
+	words := make(rankedDictionary)
+	add := func(word string) {
+		word = strings.ToLower(word)
+		if utf8.RuneCountInString(word) < 3 {
+			return
+		}
+		if _, ok := words[word]; !ok {
+			words[word] = len(words) + 1
+		}
+	}
+
+	for _, input := range inputs {
+		add(input)
+		for _, part := range strings.FieldsFunc(input, func(r rune) bool {
+			return strings.ContainsRune("@._-+ ", r) || (r >= '0' && r <= '9')
+		}) {
+			add(part)
+		}
+	}
+	return namedDictionary{name: dictUserInputs, words: words}
+}
@@ -0,0 +1,128 @@
+package passwordpolicy
+
+import "unicode"
+
+// keyboard - раскладка как граф соседних клавиш для поиска узоров вроде "qwerty" и "zxcvbn"
+type keyboard struct {
+	name string
+	keys map[rune]keyPosition
+	// directions - смещения к соседним клавишам; порядок задает номер направления
+	directions [][2]int
+	// startingPositions и averageDegree нужны для оценки числа узоров
+	startingPositions float64
+	averageDegree     float64
+}
+
+type keyPosition struct {
+	x, y    int
+	shifted bool
+}
+
+var (
+	// На обычной клавиатуре ряды сдвинуты, поэтому у клавиши шесть соседей
+	slantedDirections = [][2]int{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+	// На цифровом блоке ряды ровные: восемь соседей
+	alignedDirections = [][2]int{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+)
+
+var keyboards = []*keyboard{
+	newKeyboard("qwerty", slantedDirections,
+		[]string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+		[]string{"~!@#$%^&*()_+", "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"}),
+	newKeyboard("jcuken", slantedDirections,
+		[]string{"ё1234567890-=", "йцукенгшщзхъ\\", "фывапролджэ", "ячсмитьбю."},
+		[]string{"Ё!\"№;%:?*()_+", "ЙЦУКЕНГШЩЗХЪ/", "ФЫВАПРОЛДЖЭ", "ЯЧСМИТЬБЮ,"}),
+	newKeyboard("keypad", alignedDirections,
+		[]string{"789", "456", "123", "0"},
+		nil),
+}
+
+// newKeyboard строит граф по рядам клавиш. Первый ряд начинается с нулевой
+// колонки, остальные смещены на одну: так "q" оказывается между "1" и "2".
+func newKeyboard(name string, directions [][2]int, rows, shiftedRows []string) *keyboard {
+	k := &keyboard{name: name, keys: make(map[rune]keyPosition), directions: directions}
+	slanted := len(directions) == len(slantedDirections)
+
+	place := func(rows []string, shifted bool) {
+		for y, row := range rows {
+			offset := 0
+			if slanted && y > 0 {
+				offset = 1
+			}
+			for x, key := range []rune(row) {
+				k.keys[key] = keyPosition{x: x + offset, y: y, shifted: shifted}
+			}
+		}
+	}
+	place(rows, false)
+	place(shiftedRows, true)
+
+	occupied := make(map[[2]int]bool)
+	for _, pos := range k.keys {
+		if !pos.shifted {
+			occupied[[2]int{pos.x, pos.y}] = true
+		}
+	}
+	degrees := 0
+	for cell := range occupied {
+		for _, d := range directions {
+			if occupied[[2]int{cell[0] + d[0], cell[1] + d[1]}] {
+				degrees++
+			}
+		}
+	}
+	k.startingPositions = float64(len(occupied))
+	k.averageDegree = float64(degrees) / float64(len(occupied))
+
+	return k
+}
+
+// direction возвращает номер направления от клавиши from к соседней to или -1
+func (k *keyboard) direction(from, to rune) int {
+	a, ok := k.keys[from]
+	if !ok {
+		return -1
+	}
+	b, ok := k.keys[to]
+	if !ok {
+		return -1
+	}
+	for i, d := range k.directions {
+		if b.x-a.x == d[0] && b.y-a.y == d[1] {
+			return i
+		}
+	}
+	return -1
+}
+
+func (k *keyboard) shifted(key rune) bool {
+	return k.keys[key].shifted
+}
+
+// layoutSwap переводит символы, набранные в одной раскладке, в символы той же
+// клавиши в другой: "ghbdtn" -> "привет", "рщьу" -> "home"
+var layoutSwap = buildLayoutSwap(keyboards[0], keyboards[1])
+
+func buildLayoutSwap(a, b *keyboard) map[rune]rune {
+	byPosition := func(k *keyboard) map[keyPosition]rune {
+		positions := make(map[keyPosition]rune)
+		for key, pos := range k.keys {
+			positions[pos] = key
+		}
+		return positions
+	}
+	aKeys, bKeys := byPosition(a), byPosition(b)
+
+	swap := make(map[rune]rune)
+	for pos, aKey := range aKeys {
+		// Меняем только клавиши с буквами второй раскладки: знаки препинания
+		// на разных местах дали бы неоднозначную замену
+		bKey, ok := bKeys[pos]
+		if !ok || !unicode.IsLetter(bKey) {
+			continue
+		}
+		swap[aKey] = bKey
+		swap[bKey] = aKey
+	}
+	return swap
+}
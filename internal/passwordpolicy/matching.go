@@ -0,0 +1,607 @@
+package passwordpolicy
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Шаблоны, которыми можно объяснить часть пароля
+const (
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternYear       = "year"
+	patternDate       = "date"
+	patternBruteforce = "bruteforce"
+)
+
+// match - фрагмент пароля [i, j] (индексы символов включительно), найденный одним из шаблонов
+type match struct {
+	pattern string
+	i, j    int
+	token   []rune
+	guesses float64
+
+	// dictionary
+	dictName string
+	word     string
+	rank     int
+	reversed bool
+	l33t     bool
+	// sub - замены l33t в этом фрагменте: символ пароля -> буква словаря
+	sub map[rune]rune
+	// layout - слово набрано в другой раскладке
+	layout bool
+
+	// spatial
+	keyboard *keyboard
+	turns    int
+	shifted  int
+
+	// repeat
+	baseToken   []rune
+	baseGuesses float64
+	repeatCount int
+
+	// sequence
+	sequenceSpace int
+	ascending     bool
+
+	// year, date
+	year      int
+	separator bool
+}
+
+func (m *match) length() int {
+	return m.j - m.i + 1
+}
+
+// omnimatch находит все известные шаблоны в пароле
+func omnimatch(password []rune, userInputs namedDictionary) []*match {
+	dicts := dictionaries
+	if len(userInputs.words) > 0 {
+		dicts = append([]namedDictionary{userInputs}, dictionaries...)
+	}
+
+	var matches []*match
+	matches = append(matches, dictionaryMatch(password, dicts)...)
+	matches = append(matches, reverseDictionaryMatch(password, dicts)...)
+	matches = append(matches, l33tMatch(password, dicts)...)
+	matches = append(matches, layoutMatch(password, dicts)...)
+	matches = append(matches, spatialMatch(password)...)
+	matches = append(matches, repeatMatch(password)...)
+	matches = append(matches, sequenceMatch(password)...)
+	matches = append(matches, yearMatch(password)...)
+	matches = append(matches, dateMatch(password)...)
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].i != matches[b].i {
+			return matches[a].i < matches[b].i
+		}
+		return matches[a].j < matches[b].j
+	})
+	return matches
+}
+
+func lowerRunes(password []rune) []rune {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// dictionaryMatch ищет все фрагменты пароля, которые есть в словарях
+func dictionaryMatch(password []rune, dicts []namedDictionary) []*match {
+	longest := maxWordLength
+	for _, dict := range dicts {
+		if dict.name == dictUserInputs {
+			for word := range dict.words {
+				longest = max(longest, utf8.RuneCountInString(word))
+			}
+		}
+	}
+
+	lower := lowerRunes(password)
+	var matches []*match
+	for i := range lower {
+		for j := i; j < len(lower) && j-i < longest; j++ {
+			word := string(lower[i : j+1])
+			for _, dict := range dicts {
+				if rank, ok := dict.words[word]; ok {
+					matches = append(matches, &match{
+						pattern:  patternDictionary,
+						i:        i,
+						j:        j,
+						token:    password[i : j+1],
+						dictName: dict.name,
+						word:     word,
+						rank:     rank,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// reverseDictionaryMatch находит слова, записанные задом наперед
+func reverseDictionaryMatch(password []rune, dicts []namedDictionary) []*match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, r := range password {
+		reversed[n-1-i] = r
+	}
+
+	var matches []*match
+	for _, m := range dictionaryMatch(reversed, dicts) {
+		// Однобуквенные и симметричные слова уже найдены прямым поиском
+		if m.length() < 2 || string(m.token) == string(reverseRunes(m.token)) {
+			continue
+		}
+		m.i, m.j = n-1-m.j, n-1-m.i
+		m.token = password[m.i : m.j+1]
+		m.reversed = true
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+func reverseRunes(s []rune) []rune {
+	reversed := make([]rune, len(s))
+	for i, r := range s {
+		reversed[len(s)-1-i] = r
+	}
+	return reversed
+}
+
+// l33tTable - буква и символы, которыми ее обычно заменяют
+var l33tTable = map[rune][]rune{
+	'a': {'4', '@'},
+	'b': {'8'},
+	'c': {'(', '{', '[', '<'},
+	'e': {'3'},
+	'g': {'6', '9'},
+	'i': {'1', '!', '|'},
+	'l': {'1', '|', '7'},
+	'o': {'0'},
+	's': {'$', '5'},
+	't': {'+', '7'},
+	'x': {'%'},
+	'z': {'2'},
+}
+
+// maxL33tSubs ограничивает перебор неоднозначных замен ("1" - это i или l)
+const maxL33tSubs = 32
+
+// l33tMatch находит слова с заменами вроде "p@ssw0rd"
+func l33tMatch(password []rune, dicts []namedDictionary) []*match {
+	// Для каждого символа пароля - буквы, которые он может заменять
+	candidates := make(map[rune][]rune)
+	for letter, subs := range l33tTable {
+		for _, sub := range subs {
+			for _, r := range password {
+				if r == sub {
+					candidates[sub] = append(candidates[sub], letter)
+					break
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var chars []rune
+	for sub := range candidates {
+		chars = append(chars, sub)
+		sort.Slice(candidates[sub], func(a, b int) bool { return candidates[sub][a] < candidates[sub][b] })
+	}
+	sort.Slice(chars, func(a, b int) bool { return chars[a] < chars[b] })
+
+	// Перебираем сочетания замен: каждому символу - одна буква
+	subs := []map[rune]rune{{}}
+	for _, sub := range chars {
+		var next []map[rune]rune
+		for _, base := range subs {
+			for _, letter := range candidates[sub] {
+				if len(next) == maxL33tSubs {
+					break
+				}
+				extended := make(map[rune]rune, len(base)+1)
+				for k, v := range base {
+					extended[k] = v
+				}
+				extended[sub] = letter
+				next = append(next, extended)
+			}
+		}
+		subs = next
+	}
+
+	var matches []*match
+	for _, sub := range subs {
+		translated := make([]rune, len(password))
+		for i, r := range password {
+			if letter, ok := sub[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+
+		for _, m := range dictionaryMatch(translated, dicts) {
+			token := password[m.i : m.j+1]
+			used := make(map[rune]rune)
+			for _, r := range token {
+				if letter, ok := sub[r]; ok {
+					used[r] = letter
+				}
+			}
+			// Без замен это обычное слово; одиночный символ - не слово
+			if len(used) == 0 || len(token) < 2 {
+				continue
+			}
+			m.token = token
+			m.l33t = true
+			m.sub = used
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// layoutMatch находит слова, набранные в другой раскладке: "ghbdtn" вместо "привет"
+func layoutMatch(password []rune, dicts []namedDictionary) []*match {
+	translated := make([]rune, len(password))
+	swapped := false
+	for i, r := range password {
+		if other, ok := layoutSwap[r]; ok {
+			translated[i] = other
+			swapped = true
+		} else {
+			translated[i] = r
+		}
+	}
+	if !swapped {
+		return nil
+	}
+
+	var matches []*match
+	for _, m := range dictionaryMatch(translated, dicts) {
+		token := password[m.i : m.j+1]
+		if string(lowerRunes(token)) == m.word {
+			continue
+		}
+		m.token = token
+		m.layout = true
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// spatialMatch находит цепочки соседних клавиш: "qwerty", "zaq12wsx", "йцукен", "789456"
+func spatialMatch(password []rune) []*match {
+	var matches []*match
+	for _, kb := range keyboards {
+		i := 0
+		for i < len(password)-1 {
+			j := i + 1
+			lastDirection := -1
+			turns := 0
+			shifted := 0
+			if kb.shifted(password[i]) {
+				shifted++
+			}
+
+			for ; j < len(password); j++ {
+				direction := kb.direction(password[j-1], password[j])
+				if direction < 0 {
+					break
+				}
+				if kb.shifted(password[j]) {
+					shifted++
+				}
+				if direction != lastDirection {
+					turns++
+					lastDirection = direction
+				}
+			}
+
+			// Узором считаем не меньше трех клавиш
+			if j-i > 2 {
+				matches = append(matches, &match{
+					pattern:  patternSpatial,
+					i:        i,
+					j:        j - 1,
+					token:    password[i:j],
+					keyboard: kb,
+					turns:    turns,
+					shifted:  shifted,
+				})
+			}
+			i = j
+		}
+	}
+	return matches
+}
+
+// repeatMatch находит повторы символа или фрагмента: "aaaa", "abcabcabc"
+func repeatMatch(password []rune) []*match {
+	var matches []*match
+	n := len(password)
+	for i := 0; i < n-1; {
+		bestUnit, bestCount := 0, 0
+		for unit := 1; i+2*unit <= n; unit++ {
+			count := 1
+			for i+(count+1)*unit <= n && string(password[i+count*unit:i+(count+1)*unit]) == string(password[i:i+unit]) {
+				count++
+			}
+			if count > 1 && unit*count > bestUnit*bestCount {
+				bestUnit, bestCount = unit, count
+			}
+		}
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestUnit]
+		matches = append(matches, &match{
+			pattern:     patternRepeat,
+			i:           i,
+			j:           i + bestUnit*bestCount - 1,
+			token:       password[i : i+bestUnit*bestCount],
+			baseToken:   base,
+			baseGuesses: mostGuessable(base, omnimatch(base, namedDictionary{})).guesses,
+			repeatCount: bestCount,
+		})
+		i += bestUnit * bestCount
+	}
+	return matches
+}
+
+// maxSequenceDelta - наибольший шаг, при котором символы еще считаются последовательностью
+const maxSequenceDelta = 5
+
+// sequenceMatch находит последовательности с постоянным шагом: "abcd", "13579", "9876"
+func sequenceMatch(password []rune) []*match {
+	if len(password) < 2 {
+		return nil
+	}
+
+	var matches []*match
+	add := func(i, j, delta int) {
+		if j-i > 1 || abs(delta) == 1 {
+			if abs(delta) > 0 && abs(delta) <= maxSequenceDelta {
+				token := password[i : j+1]
+				space := 26
+				if allRunes(token, unicode.IsDigit) {
+					space = 10
+				}
+				matches = append(matches, &match{
+					pattern:       patternSequence,
+					i:             i,
+					j:             j,
+					token:         token,
+					sequenceSpace: space,
+					ascending:     delta > 0,
+				})
+			}
+		}
+	}
+
+	i := 0
+	lastDelta := int(password[1]) - int(password[0])
+	for k := 2; k < len(password); k++ {
+		delta := int(password[k]) - int(password[k-1])
+		if delta == lastDelta {
+			continue
+		}
+		add(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	add(i, len(password)-1, lastDelta)
+
+	return matches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func allRunes(s []rune, f func(rune) bool) bool {
+	for _, r := range s {
+		if !f(r) {
+			return false
+		}
+	}
+	return true
+}
+
+var yearPattern = regexp.MustCompile(`19\d\d|20[0-4]\d`)
+
+// yearMatch находит годы: их часто добавляют к словам
+func yearMatch(password []rune) []*match {
+	var matches []*match
+	for _, m := range findAll(yearPattern, password) {
+		year, _ := strconv.Atoi(string(password[m[0] : m[1]+1]))
+		matches = append(matches, &match{
+			pattern: patternYear,
+			i:       m[0],
+			j:       m[1],
+			token:   password[m[0] : m[1]+1],
+			year:    year,
+		})
+	}
+	return matches
+}
+
+// findAll возвращает совпадения регулярного выражения в индексах символов [i, j]
+func findAll(re *regexp.Regexp, password []rune) [][2]int {
+	s := string(password)
+	var found [][2]int
+	for _, loc := range re.FindAllStringIndex(s, -1) {
+		i := utf8.RuneCountInString(s[:loc[0]])
+		found = append(found, [2]int{i, i + utf8.RuneCountInString(s[loc[0]:loc[1]]) - 1})
+	}
+	return found
+}
+
+var (
+	// dateSplits - где разрезать дату без разделителей по ее длине
+	dateSplits = map[int][][2]int{
+		4: {{1, 2}, {2, 3}},
+		5: {{1, 3}, {2, 3}},
+		6: {{1, 2}, {2, 4}, {4, 5}},
+		7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+		8: {{2, 4}, {4, 6}},
+	}
+	dateWithSeparator = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+)
+
+const (
+	minDateYear = 1000
+	maxDateYear = 2050
+)
+
+// dateMatch находит даты вроде "12.05.1990", "120590", "1990-5-12"
+func dateMatch(password []rune) []*match {
+	var matches []*match
+	n := len(password)
+
+	for i := 0; i+3 < n; i++ {
+		for j := i + 3; j < i+8 && j < n; j++ {
+			token := password[i : j+1]
+			if !allRunes(token, unicode.IsDigit) {
+				continue
+			}
+
+			var best *dmy
+			for _, split := range dateSplits[len(token)] {
+				candidate := mapIntsToDMY(
+					atoi(token[:split[0]]),
+					atoi(token[split[0]:split[1]]),
+					atoi(token[split[1]:]),
+				)
+				if candidate != nil && (best == nil || abs(candidate.year-referenceYear()) < abs(best.year-referenceYear())) {
+					best = candidate
+				}
+			}
+			if best != nil {
+				matches = append(matches, &match{pattern: patternDate, i: i, j: j, token: token, year: best.year})
+			}
+		}
+	}
+
+	for i := 0; i+5 < n; i++ {
+		for j := i + 5; j < i+10 && j < n; j++ {
+			token := password[i : j+1]
+			parts := dateWithSeparator.FindStringSubmatch(string(token))
+			if parts == nil || parts[2] != parts[4] {
+				continue
+			}
+			if date := mapIntsToDMY(atoi([]rune(parts[1])), atoi([]rune(parts[3])), atoi([]rune(parts[5]))); date != nil {
+				matches = append(matches, &match{pattern: patternDate, i: i, j: j, token: token, year: date.year, separator: true})
+			}
+		}
+	}
+
+	// Дата внутри другой даты ("1990" в "12051990") ничего не добавляет
+	var filtered []*match
+	for _, m := range matches {
+		contained := false
+		for _, other := range matches {
+			if m != other && other.i <= m.i && other.j >= m.j {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+type dmy struct {
+	day, month, year int
+}
+
+func atoi(digits []rune) int {
+	n, _ := strconv.Atoi(string(digits))
+	return n
+}
+
+// mapIntsToDMY пробует прочитать три числа как день, месяц и год в любом
+// порядке, где год стоит первым или последним
+func mapIntsToDMY(a, b, c int) *dmy {
+	ints := [3]int{a, b, c}
+	if b > 31 || b <= 0 {
+		return nil
+	}
+
+	over12, over31, under1 := 0, 0, 0
+	for _, n := range ints {
+		if (n > 99 && n < minDateYear) || n > maxDateYear {
+			return nil
+		}
+		if n > 31 {
+			over31++
+		}
+		if n > 12 {
+			over12++
+		}
+		if n <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return nil
+	}
+
+	splits := [][3]int{{c, a, b}, {a, b, c}}
+	for _, s := range splits {
+		if s[0] >= minDateYear && s[0] <= maxDateYear {
+			if day, month, ok := mapIntsToDM(s[1], s[2]); ok {
+				return &dmy{day: day, month: month, year: s[0]}
+			}
+			// Четырехзначный год есть, но остальное не день и месяц
+			return nil
+		}
+	}
+	for _, s := range splits {
+		if day, month, ok := mapIntsToDM(s[1], s[2]); ok {
+			return &dmy{day: day, month: month, year: twoToFourDigitYear(s[0])}
+		}
+	}
+	return nil
+}
+
+func mapIntsToDM(a, b int) (day, month int, ok bool) {
+	for _, dm := range [][2]int{{a, b}, {b, a}} {
+		if dm[0] >= 1 && dm[0] <= 31 && dm[1] >= 1 && dm[1] <= 12 {
+			return dm[0], dm[1], true
+		}
+	}
+	return 0, 0, false
+}
+
+func twoToFourDigitYear(year int) int {
+	switch {
+	case year > 99:
+		return year
+	case year > 50:
+		return 1900 + year
+	default:
+		return 2000 + year
+	}
+}
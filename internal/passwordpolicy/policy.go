@@ -0,0 +1,177 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"LOIL-auth-server/internal/config"
+	"LOIL-auth-server/internal/validation"
+)
+
+// Policy - требования к новому паролю. Те же значения отдаются клиентам
+// через GET /api/auth/validation-rules.
+type Policy struct {
+	MinLength int `json:"minLength"`
+	MaxBytes  int `json:"maxBytes"`
+	// MinClasses - сколько видов символов из четырех нужно: строчные, заглавные, цифры, прочие
+	MinClasses int `json:"minClasses"`
+	// MaxRepeat - сколько раз подряд может повториться символ или фрагмент; 0 - без ограничения
+	MaxRepeat int `json:"maxRepeat"`
+	// DisallowUserInputs запрещает логин, email и игровую фамилию внутри пароля
+	DisallowUserInputs bool `json:"disallowUserInputs"`
+	// MinScore - минимальная оценка стойкости от 0 до 4
+	MinScore int `json:"minScore"`
+	// RejectBreached - пароли из базы утечек запрещены
+	RejectBreached bool `json:"rejectBreached"`
+
+	breaches BreachList
+}
+
+// BreachList - база паролей из известных утечек
+type BreachList interface {
+	Contains(password string) bool
+}
+
+// WithBreaches включает проверку по базе утечек; nil оставляет ее выключенной
+func (p Policy) WithBreaches(breaches BreachList) Policy {
+	p.breaches = breaches
+	p.RejectBreached = breaches != nil
+	return p
+}
+
+// PolicyFrom переводит настройки из конфигурации в политику
+func PolicyFrom(cfg config.PasswordConfig) Policy {
+	return Policy{
+		MinLength:          cfg.MinLength,
+		MaxBytes:           cfg.MaxBytes,
+		MinClasses:         cfg.MinClasses,
+		MaxRepeat:          cfg.MaxRepeat,
+		DisallowUserInputs: cfg.DisallowUserInputs,
+		MinScore:           cfg.MinScore,
+	}
+}
+
+// Rule - ограничения длины как правило поля для форм клиентов
+func (p Policy) Rule() validation.Rule {
+	return validation.Rule{
+		Required:  true,
+		MinLength: p.MinLength,
+		MaxBytes:  p.MaxBytes,
+	}
+}
+
+// UserInputs - данные пользователя, которые не должны попадать в пароль
+type UserInputs struct {
+	Login       string
+	Email       string
+	GameSurname string
+}
+
+func (u UserInputs) list() []string {
+	inputs := []string{u.Login, u.Email, u.GameSurname}
+	if local, _, ok := strings.Cut(u.Email, "@"); ok {
+		inputs = append(inputs, local)
+	}
+	return inputs
+}
+
+// maxRepeatUnit - самый длинный фрагмент, повторы которого ограничивает MaxRepeat
+const maxRepeatUnit = 4
+
+// minUserInputLength - более короткие части логина и email совпадают с паролем случайно
+const minUserInputLength = 3
+
+// Check проверяет пароль по политике и оценивает его стойкость. Возвращает
+// первую нарушенную проверку поля field или nil, как validation.Rule.Check.
+func (p Policy) Check(field, password string, inputs UserInputs) (Strength, *validation.FieldError) {
+	// Оценка нужна и для короткого пароля: форма показывает подсказки по мере ввода
+	strength := Estimate(password, inputs.list()...)
+	breached := p.breaches != nil && p.breaches.Contains(password)
+	if breached {
+		strength = strength.breached()
+	}
+
+	if fieldErr := p.Rule().Check(field, password); fieldErr != nil {
+		return strength, fieldErr
+	}
+
+	if breached {
+		return strength, validation.NewFieldError(field, validation.CodeBreached, nil)
+	}
+
+	if p.MinClasses > 0 && characterClasses(password) < p.MinClasses {
+		return strength, validation.NewFieldError(field, validation.CodeTooSimple, map[string]interface{}{"min": p.MinClasses})
+	}
+	if p.MaxRepeat > 0 && longestRepeat(password) > p.MaxRepeat {
+		return strength, validation.NewFieldError(field, validation.CodeRepeated, map[string]interface{}{"max": p.MaxRepeat})
+	}
+	if p.DisallowUserInputs && containsUserInput(password, inputs.list()) {
+		return strength, validation.NewFieldError(field, validation.CodeContainsUserInfo, nil)
+	}
+	if strength.Score < p.MinScore {
+		return strength, validation.NewFieldError(field, validation.CodeTooWeak, map[string]interface{}{
+			"score":       strength.Score,
+			"minScore":    p.MinScore,
+			"warning":     strength.Feedback.WarningCode,
+			"suggestions": strength.Feedback.SuggestionCodes,
+		})
+	}
+
+	return strength, nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
+
+// longestRepeat - наибольшее число повторов подряд символа или короткого
+// фрагмента: 4 для "aaaa" и для "abababab"
+func longestRepeat(password string) int {
+	runes := []rune(password)
+	longest := 1
+	for unit := 1; unit <= maxRepeatUnit; unit++ {
+		// streak - сколько символов подряд совпадают с символом на unit позиций раньше
+		streak := 0
+		for k := unit; k < len(runes); k++ {
+			if runes[k] == runes[k-unit] {
+				streak++
+			} else {
+				streak = 0
+			}
+			longest = max(longest, (streak+unit)/unit)
+		}
+	}
+	return longest
+}
+
+func containsUserInput(password string, inputs []string) bool {
+	lower := strings.ToLower(password)
+	for _, input := range inputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if utf8.RuneCountInString(input) >= minUserInputLength && strings.Contains(lower, input) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"LOIL-auth-server/internal/validation"
+)
+
+type breachSet map[string]bool
+
+func (b breachSet) Contains(password string) bool { return b[password] }
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{
+		MinLength:          8,
+		MaxBytes:           64,
+		MinClasses:         3,
+		MaxRepeat:          3,
+		DisallowUserInputs: true,
+		MinScore:           2,
+	}.WithBreaches(breachSet{"Breached#2024x": true})
+	inputs := UserInputs{Login: "ivanpetrov", Email: "ivan.p@example.com", GameSurname: "Sidorov"}
+
+	tests := []struct {
+		password string
+		code     string
+	}{
+		{"", validation.CodeRequired},
+		{"Vx9#kq", validation.CodeTooShort},
+		{"Vx9#kq2Lm!pz" + strings.Repeat("x", 60), validation.CodeTooLong},
+		{"Breached#2024x", validation.CodeBreached},
+		{"vx9kq2lmpzqt", validation.CodeTooSimple},
+		{"Vx9#kqqqqLm!", validation.CodeRepeated},
+		{"Vx9#Sidorov!", validation.CodeContainsUserInfo},
+		{"Ivan.p#1", validation.CodeContainsUserInfo},
+		{"Password1!", validation.CodeTooWeak},
+		{"Vx9#kq2Lm!pz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			_, fieldErr := policy.Check("password", tt.password, inputs)
+			got := ""
+			if fieldErr != nil {
+				got = fieldErr.Code
+			}
+			if got != tt.code {
+				t.Errorf("Check = %q, want %q", got, tt.code)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckBreachedStrength(t *testing.T) {
+	policy := Policy{MinLength: 8, MaxBytes: 64}.WithBreaches(breachSet{"Vx9#kq2Lm!pz": true})
+	strength, _ := policy.Check("password", "Vx9#kq2Lm!pz", UserInputs{})
+	if strength.Score != 0 || strength.Feedback.WarningCode != warningBreached {
+		t.Errorf("strength of a breached password = %+v, want score 0 and the breached warning", strength)
+	}
+}
+
+func TestLongestRepeat(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"a", 1},
+		{"abc", 1},
+		{"aab", 2},
+		{"aaaa", 4},
+		{"abababab", 4},
+		{"abcabcabc", 3},
+		{"abcdabcd", 2},
+		// Фрагменты длиннее maxRepeatUnit не считаются
+		{"abcdeabcdeabcde", 1},
+	}
+	for _, tt := range tests {
+		if got := longestRepeat(tt.password); got != tt.want {
+			t.Errorf("longestRepeat(%q) = %d, want %d", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestCharacterClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"abc", 1},
+		{"abcABC", 2},
+		{"abcABC123", 3},
+		{"abcABC123!", 4},
+		{"пароль", 1},
+		{"ПарольX", 2},
+	}
+	for _, tt := range tests {
+		if got := characterClasses(tt.password); got != tt.want {
+			t.Errorf("characterClasses(%q) = %d, want %d", tt.password, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+// Package passwordpolicy проверяет новые пароли: требования из конфигурации
+// и оценка стойкости в духе zxcvbn. Пароль разбирается на известные шаблоны
+// (словарные слова, узоры на клавиатуре, повторы, последовательности, даты),
+// и стойкость считается по числу попыток, за которое его подберет атакующий,
+// перебирающий эти шаблоны.
+package passwordpolicy
+
+import (
+	"math"
+	"time"
+	"unicode"
+
+	"LOIL-auth-server/internal/i18n"
+)
+
+// maxEstimateLength - длиннее оценивать незачем: такой пароль и так стойкий,
+// а время разбора растет квадратично
+const maxEstimateLength = 100
+
+const (
+	// minGuessesBeforeGrowingSequence - штраф за каждый следующий шаблон:
+	// атакующему неизвестно, из скольких частей состоит пароль
+	minGuessesBeforeGrowingSequence = 10000
+	minSubmatchGuessesSingleChar    = 10
+	minSubmatchGuessesMultiChar     = 50
+	minYearSpace                    = 20
+	bruteforceCardinality           = 10
+)
+
+// Strength - оценка стойкости пароля
+type Strength struct {
+	// Score от 0 (подбирается мгновенно) до 4 (очень стойкий)
+	Score int `json:"score"`
+	// GuessesLog10 - десятичный логарифм числа попыток для подбора
+	GuessesLog10 float64  `json:"guessesLog10"`
+	Feedback     Feedback `json:"feedback"`
+}
+
+// Feedback - почему пароль слабый и как его улучшить. Коды стабильны,
+// тексты переведены на язык запроса.
+type Feedback struct {
+	WarningCode     string   `json:"warningCode,omitempty"`
+	Warning         string   `json:"warning,omitempty"`
+	SuggestionCodes []string `json:"suggestionCodes,omitempty"`
+	Suggestions     []string `json:"suggestions,omitempty"`
+}
+
+// Localized возвращает оценку с подсказками на указанном языке
+func (s Strength) Localized(locale string) Strength {
+	s.Feedback.Warning = ""
+	if s.Feedback.WarningCode != "" {
+		s.Feedback.Warning = i18n.T(locale, "password.warning."+s.Feedback.WarningCode, nil)
+	}
+	s.Feedback.Suggestions = nil
+	for _, code := range s.Feedback.SuggestionCodes {
+		s.Feedback.Suggestions = append(s.Feedback.Suggestions, i18n.T(locale, "password.suggestion."+code, nil))
+	}
+	return s
+}
+
+// Estimate оценивает стойкость пароля. userInputs - логин, email и другие
+// данные пользователя: их атакующий попробует первыми.
+func Estimate(password string, userInputs ...string) Strength {
+	runes := []rune(password)
+	if len(runes) > maxEstimateLength {
+		runes = runes[:maxEstimateLength]
+	}
+
+	result := mostGuessable(runes, omnimatch(runes, userInputsDictionary(userInputs)))
+	score := guessesToScore(result.guesses)
+
+	return Strength{
+		Score:        score,
+		GuessesLog10: math.Round(math.Log10(result.guesses)*100) / 100,
+		Feedback:     feedback(score, result.sequence),
+	}.Localized(i18n.Fallback)
+}
+
+func guessesToScore(guesses float64) int {
+	// Запас в 5 попыток, чтобы граничные значения не прыгали между оценками
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+type guessResult struct {
+	guesses  float64
+	sequence []*match
+}
+
+// mostGuessable находит разбиение пароля на шаблоны, которое атакующий
+// переберет быстрее всего. Непокрытые шаблонами участки считаются перебором.
+func mostGuessable(password []rune, matches []*match) guessResult {
+	n := len(password)
+	if n == 0 {
+		return guessResult{guesses: 1}
+	}
+
+	byEnd := make([][]*match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// Для каждой позиции k и длины разбиения l - лучший последний шаблон,
+	// произведение попыток по шаблонам (pi) и итоговая оценка (g)
+	best := make([]map[int]*match, n)
+	pi := make([]map[int]float64, n)
+	g := make([]map[int]float64, n)
+	for k := range best {
+		best[k] = make(map[int]*match)
+		pi[k] = make(map[int]float64)
+		g[k] = make(map[int]float64)
+	}
+
+	update := func(m *match, l int) {
+		k := m.j
+		product := estimateGuesses(m, n)
+		if l > 1 {
+			product *= pi[m.i-1][l-1]
+		}
+		total := factorial(l)*product + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+
+		// Разбиение длиннее и не лучше уже найденного не нужно
+		for otherL, otherG := range g[k] {
+			if otherL <= l && otherG <= total {
+				return
+			}
+		}
+		best[k][l] = m
+		pi[k][l] = product
+		g[k][l] = total
+	}
+
+	bruteforceUpdate := func(k int) {
+		update(bruteforceMatch(password, 0, k), 1)
+		for i := 1; i <= k; i++ {
+			m := bruteforceMatch(password, i, k)
+			for l, last := range best[i-1] {
+				// Два перебора подряд - это один перебор
+				if last.pattern == patternBruteforce {
+					continue
+				}
+				update(m, l+1)
+			}
+		}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i > 0 {
+				for l := range best[m.i-1] {
+					update(m, l+1)
+				}
+			} else {
+				update(m, 1)
+			}
+		}
+		bruteforceUpdate(k)
+	}
+
+	optimalL, guesses := 0, math.Inf(1)
+	for l, total := range g[n-1] {
+		if total < guesses || (total == guesses && l < optimalL) {
+			optimalL, guesses = l, total
+		}
+	}
+
+	sequence := make([]*match, optimalL)
+	for k, l := n-1, optimalL; k >= 0; l-- {
+		m := best[k][l]
+		sequence[l-1] = m
+		k = m.i - 1
+	}
+
+	return guessResult{guesses: guesses, sequence: sequence}
+}
+
+func bruteforceMatch(password []rune, i, j int) *match {
+	return &match{pattern: patternBruteforce, i: i, j: j, token: password[i : j+1]}
+}
+
+// estimateGuesses - сколько попыток нужно, чтобы угадать фрагмент, зная его шаблон
+func estimateGuesses(m *match, passwordLength int) float64 {
+	if m.guesses > 0 {
+		return m.guesses
+	}
+
+	// Фрагмент короче пароля не может быть проще минимального перебора
+	minGuesses := 1.0
+	if m.length() < passwordLength {
+		minGuesses = minSubmatchGuessesMultiChar
+		if m.length() == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+	}
+
+	var guesses float64
+	switch m.pattern {
+	case patternBruteforce:
+		guesses = math.Pow(bruteforceCardinality, float64(m.length()))
+		// Перебор должен быть хуже любого шаблона той же длины
+		if m.length() == 1 {
+			guesses = max(guesses, minSubmatchGuessesSingleChar+1)
+		} else {
+			guesses = max(guesses, minSubmatchGuessesMultiChar+1)
+		}
+	case patternDictionary:
+		guesses = float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(m)
+		if m.reversed || m.layout {
+			guesses *= 2
+		}
+	case patternSpatial:
+		guesses = spatialGuesses(m)
+	case patternRepeat:
+		guesses = m.baseGuesses * float64(m.repeatCount)
+	case patternSequence:
+		base := float64(m.sequenceSpace)
+		switch m.token[0] {
+		case 'a', 'A', 'z', 'Z', '0', '1', '9':
+			// Очевидное начало
+			base = 4
+		}
+		if !m.ascending {
+			base *= 2
+		}
+		guesses = base * float64(m.length())
+	case patternYear:
+		guesses = float64(max(abs(m.year-referenceYear()), minYearSpace))
+	case patternDate:
+		guesses = float64(max(abs(m.year-referenceYear()), minYearSpace)) * 365
+		if m.separator {
+			guesses *= 4
+		}
+	}
+
+	m.guesses = max(guesses, minGuesses)
+	return m.guesses
+}
+
+func referenceYear() int {
+	return time.Now().Year()
+}
+
+// uppercaseVariations - во сколько раз заглавные буквы усложняют слово.
+// Заглавная первая или последняя буква, или все заглавные - всего вдвое.
+func uppercaseVariations(token []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 {
+		return 2
+	}
+	first, last := token[0], token[len(token)-1]
+	if upper == 1 && (unicode.IsUpper(first) || unicode.IsUpper(last)) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations - во сколько раз замены вроде "@" вместо "a" усложняют слово
+func l33tVariations(m *match) float64 {
+	if !m.l33t {
+		return 1
+	}
+
+	variations := 1.0
+	lower := lowerRunes(m.token)
+	for subbed, letter := range m.sub {
+		s, u := 0, 0
+		for _, r := range lower {
+			switch r {
+			case subbed:
+				s++
+			case letter:
+				u++
+			}
+		}
+		if s == 0 || u == 0 {
+			// Заменены все вхождения буквы: атакующему достаточно попробовать оба варианта
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= min(s, u); i++ {
+			possibilities += binomial(s+u, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// spatialGuesses - число узоров такой же длины и с тем же числом поворотов
+func spatialGuesses(m *match) float64 {
+	kb := m.keyboard
+	length := m.length()
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(m.turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * kb.startingPositions * math.Pow(kb.averageDegree, float64(j))
+		}
+	}
+
+	if m.shifted > 0 {
+		s, u := m.shifted, length-m.shifted
+		if u == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= min(s, u); i++ {
+				variations += binomial(s+u, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	result := 1.0
+	for d := 1; d <= k; d++ {
+		result *= float64(n - k + d)
+		result /= float64(d)
+	}
+	return result
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
+
+// Коды предупреждений и советов; тексты - в каталогах i18n (password.warning.*, password.suggestion.*)
+const (
+	warningTop10             = "top10"
+	warningTop100            = "top100"
+	warningCommon            = "common"
+	warningSimilarToCommon   = "similar_to_common"
+	warningWordByItself      = "word_by_itself"
+	warningNamesByThemselves = "names_by_themselves"
+	warningCommonNames       = "common_names"
+	warningUserInputs        = "user_inputs"
+	warningStraightRows      = "straight_rows"
+	warningKeyboardPatterns  = "keyboard_patterns"
+	warningRepeatedChars     = "repeated_chars"
+	warningRepeatedPattern   = "repeated_pattern"
+	warningSequences         = "sequences"
+	warningRecentYears       = "recent_years"
+	warningDates             = "dates"
+	warningBreached          = "breached"
+
+	suggestionUseFewWords          = "use_few_words"
+	suggestionNoNeedForSymbols     = "no_need_for_symbols"
+	suggestionAddAnotherWord       = "add_another_word"
+	suggestionCapitalization       = "capitalization"
+	suggestionAllUppercase         = "all_uppercase"
+	suggestionReversedWords        = "reversed_words"
+	suggestionL33t                 = "l33t"
+	suggestionOtherLayout          = "other_layout"
+	suggestionLongerKeyboard       = "longer_keyboard_patterns"
+	suggestionAvoidRepeats         = "avoid_repeats"
+	suggestionAvoidSequences       = "avoid_sequences"
+	suggestionAvoidRecentYears     = "avoid_recent_years"
+	suggestionAvoidAssociatedYears = "avoid_associated_years"
+	suggestionAvoidAssociatedDates = "avoid_associated_dates"
+	suggestionUniquePassword       = "unique_password"
+)
+
+// breached - оценка пароля из базы утечек: его перебирают первым, сколько бы
+// попыток ни насчитал Estimate
+func (s Strength) breached() Strength {
+	s.Score = 0
+	s.Feedback = Feedback{
+		WarningCode:     warningBreached,
+		SuggestionCodes: []string{suggestionUniquePassword},
+	}
+	return s
+}
+
+// feedback объясняет слабый пароль по самому длинному найденному шаблону
+func feedback(score int, sequence []*match) Feedback {
+	if len(sequence) == 0 {
+		return Feedback{SuggestionCodes: []string{suggestionUseFewWords, suggestionNoNeedForSymbols}}
+	}
+	if score > 2 {
+		return Feedback{}
+	}
+
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if m.length() > longest.length() {
+			longest = m
+		}
+	}
+
+	fb := matchFeedback(longest, len(sequence) == 1)
+	fb.SuggestionCodes = append([]string{suggestionAddAnotherWord}, fb.SuggestionCodes...)
+	return fb
+}
+
+func matchFeedback(m *match, soleMatch bool) Feedback {
+	switch m.pattern {
+	case patternDictionary:
+		return dictionaryFeedback(m, soleMatch)
+	case patternSpatial:
+		warning := warningKeyboardPatterns
+		if m.turns == 1 {
+			warning = warningStraightRows
+		}
+		return Feedback{WarningCode: warning, SuggestionCodes: []string{suggestionLongerKeyboard}}
+	case patternRepeat:
+		warning := warningRepeatedPattern
+		if len(m.baseToken) == 1 {
+			warning = warningRepeatedChars
+		}
+		return Feedback{WarningCode: warning, SuggestionCodes: []string{suggestionAvoidRepeats}}
+	case patternSequence:
+		return Feedback{WarningCode: warningSequences, SuggestionCodes: []string{suggestionAvoidSequences}}
+	case patternYear:
+		return Feedback{WarningCode: warningRecentYears, SuggestionCodes: []string{suggestionAvoidRecentYears, suggestionAvoidAssociatedYears}}
+	case patternDate:
+		return Feedback{WarningCode: warningDates, SuggestionCodes: []string{suggestionAvoidAssociatedDates}}
+	}
+	return Feedback{}
+}
+
+func dictionaryFeedback(m *match, soleMatch bool) Feedback {
+	var fb Feedback
+	plain := !m.l33t && !m.reversed && !m.layout
+
+	switch m.dictName {
+	case dictPasswords:
+		switch {
+		case soleMatch && plain && m.rank <= 10:
+			fb.WarningCode = warningTop10
+		case soleMatch && plain && m.rank <= 100:
+			fb.WarningCode = warningTop100
+		case soleMatch && plain:
+			fb.WarningCode = warningCommon
+		case m.guesses <= 1e4:
+			fb.WarningCode = warningSimilarToCommon
+		}
+	case dictEnglish, dictRussian:
+		if soleMatch {
+			fb.WarningCode = warningWordByItself
+		}
+	case dictNames:
+		if soleMatch {
+			fb.WarningCode = warningNamesByThemselves
+		} else {
+			fb.WarningCode = warningCommonNames
+		}
+	case dictUserInputs:
+		fb.WarningCode = warningUserInputs
+	}
+
+	word := m.token
+	switch {
+	case uppercaseVariations(word) == 2 && unicode.IsUpper(word[0]) && !allRunes(word, isUpperOrNotLetter):
+		fb.SuggestionCodes = append(fb.SuggestionCodes, suggestionCapitalization)
+	case allRunes(word, isUpperOrNotLetter) && !allRunes(word, isLowerOrNotLetter):
+		fb.SuggestionCodes = append(fb.SuggestionCodes, suggestionAllUppercase)
+	}
+	if m.reversed && m.length() >= 4 {
+		fb.SuggestionCodes = append(fb.SuggestionCodes, suggestionReversedWords)
+	}
+	if m.l33t {
+		fb.SuggestionCodes = append(fb.SuggestionCodes, suggestionL33t)
+	}
+	if m.layout {
+		fb.SuggestionCodes = append(fb.SuggestionCodes, suggestionOtherLayout)
+	}
+	return fb
+}
+
+func isUpperOrNotLetter(r rune) bool {
+	return !unicode.IsLetter(r) || unicode.IsUpper(r)
+}
+
+func isLowerOrNotLetter(r rune) bool {
+	return !unicode.IsLetter(r) || unicode.IsLower(r)
+}
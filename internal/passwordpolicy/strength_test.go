@@ -0,0 +1,123 @@
+package passwordpolicy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateWeakPasswords(t *testing.T) {
+	tests := []struct {
+		password string
+		warning  string
+	}{
+		{"password", warningTop10},
+		{"123456", warningTop10},
+		{"qwerty", warningTop10},
+		{"iloveyou", warningTop100},
+		{"1qaz2wsx", warningTop100},
+		{"P@ssw0rd", warningSimilarToCommon},
+		{"aaaaaaaa", warningRepeatedChars},
+		{"abcabcabcabc", warningRepeatedPattern},
+		{"abcdefgh", warningSequences},
+		{"asdfghjkl;", warningStraightRows},
+		{"фывапролдж", warningStraightRows},
+		// Словарное слово задом наперед
+		{"poiuytrewq", warningSimilarToCommon},
+		{"15.06.1990", warningDates},
+		// "привет" в английской раскладке
+		{"ghbdtn", warningWordByItself},
+		{"ivan.petrov", warningUserInputs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			s := Estimate(tt.password, "ivan.petrov", "ivan.petrov@example.com")
+			if s.Score > 1 {
+				t.Errorf("Score = %d, want at most 1", s.Score)
+			}
+			if s.Feedback.WarningCode != tt.warning {
+				t.Errorf("WarningCode = %q, want %q", s.Feedback.WarningCode, tt.warning)
+			}
+			if s.Feedback.Warning == "" || len(s.Feedback.Suggestions) != len(s.Feedback.SuggestionCodes) {
+				t.Errorf("feedback is not localized: %+v", s.Feedback)
+			}
+		})
+	}
+}
+
+func TestEstimateStrongPasswords(t *testing.T) {
+	for _, password := range []string{
+		"correcthorsebatterystaple",
+		"kitten-mango-river",
+		"Vx9#kq2Lm!pz",
+		"tR7$wq!Lz0#mKe",
+	} {
+		t.Run(password, func(t *testing.T) {
+			s := Estimate(password)
+			if s.Score != 4 {
+				t.Errorf("Score = %d (log10 guesses %.2f), want 4", s.Score, s.GuessesLog10)
+			}
+			if s.Feedback.WarningCode != "" {
+				t.Errorf("WarningCode = %q for a strong password", s.Feedback.WarningCode)
+			}
+		})
+	}
+}
+
+// Оценка монотонна: добавление случайных символов не делает пароль слабее
+func TestEstimateGrowsWithLength(t *testing.T) {
+	previous := -1.0
+	for _, password := range []string{"kq", "kq2L", "kq2Lm!", "kq2Lm!pz", "kq2Lm!pzVx9#"} {
+		s := Estimate(password)
+		if s.GuessesLog10 < previous {
+			t.Errorf("Estimate(%q) = %.2f, less than for a shorter prefix (%.2f)", password, s.GuessesLog10, previous)
+		}
+		previous = s.GuessesLog10
+	}
+}
+
+func TestGuessesToScoreBoundaries(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		score   int
+	}{
+		{1, 0},
+		{1e3 + 4, 0},
+		{1e3 + 5, 1},
+		{1e6 + 4, 1},
+		{1e6 + 5, 2},
+		{1e8 + 4, 2},
+		{1e8 + 5, 3},
+		{1e10 + 4, 3},
+		{1e10 + 5, 4},
+		{1e20, 4},
+	}
+	for _, tt := range tests {
+		if got := guessesToScore(tt.guesses); got != tt.score {
+			t.Errorf("guessesToScore(%g) = %d, want %d", tt.guesses, got, tt.score)
+		}
+	}
+}
+
+// Длинные пароли оцениваются по первым maxEstimateLength символам, иначе
+// разбор растет квадратично и длинный ввод занимает процессор
+func TestEstimateTruncatesLongPasswords(t *testing.T) {
+	prefix := strings.Repeat("Vx9#kq2Lm!pz", maxEstimateLength/12+1)[:maxEstimateLength]
+	long := prefix + strings.Repeat("x", 100000)
+
+	start := time.Now()
+	got := Estimate(long)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Estimate of a %d-character password took %v", len(long), elapsed)
+	}
+	if want := Estimate(prefix); !reflect.DeepEqual(got, want) {
+		t.Errorf("Estimate(long) = %+v, want the estimate of its first %d characters %+v", got, maxEstimateLength, want)
+	}
+}
+
+func TestEstimateEmpty(t *testing.T) {
+	if s := Estimate(""); s.Score != 0 || s.GuessesLog10 != 0 {
+		t.Errorf("Estimate(\"\") = %+v, want score 0", s)
+	}
+}
@@ -0,0 +1,45 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Префиксы ссылок на секреты в конфигурации
+const (
+	// file:///run/secrets/jwt_secret - значение читается из файла (Docker secrets)
+	FilePrefix = "file://"
+	// secret://jwt_secret - значение берется из зашифрованного файла секретов
+	StorePrefix = "secret://"
+)
+
+// IsReference сообщает, что значение является ссылкой, а не самим секретом
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, FilePrefix) || strings.HasPrefix(value, StorePrefix)
+}
+
+// Resolve возвращает значение секрета. Ссылки file:// читаются с диска при каждом
+// вызове, поэтому повторная загрузка конфигурации подхватывает ротированные файлы.
+// Значения без префикса возвращаются как есть.
+func Resolve(value string, store *Store) (string, error) {
+	switch {
+	case strings.HasPrefix(value, FilePrefix):
+		path := strings.TrimPrefix(value, FilePrefix)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		// Завершающий перевод строки почти всегда добавлен редактором, а не частью секрета
+		return strings.TrimRight(string(content), "\r\n"), nil
+
+	case strings.HasPrefix(value, StorePrefix):
+		name := strings.TrimPrefix(value, StorePrefix)
+		if store == nil {
+			return "", fmt.Errorf("secret %q referenced but secrets.file is not configured", name)
+		}
+		return store.Get(name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return FilePrefix + path
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no newline", "s3cr3t", "s3cr3t"},
+		{"trailing newline", "s3cr3t\n", "s3cr3t"},
+		{"windows newline", "s3cr3t\r\n", "s3cr3t"},
+		{"several newlines", "s3cr3t\n\n", "s3cr3t"},
+		// Пробелы могут быть частью секрета и не обрезаются
+		{"trailing spaces", "s3cr3t  \n", "s3cr3t  "},
+		{"inner newline", "line1\nline2\n", "line1\nline2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(write(strings.ReplaceAll(tt.name, " ", "_"), tt.content), nil)
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// Ротированный файл читается заново при каждом вызове
+	ref := write("rotated", "old\n")
+	write("rotated", "new\n")
+	if got, err := Resolve(ref, nil); err != nil || got != "new" {
+		t.Errorf("Resolve after rotation = %q, %v, want new", got, err)
+	}
+
+	if _, err := Resolve(FilePrefix+filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("Resolve of a missing file succeeded")
+	}
+}
+
+func TestResolveStore(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "secrets.enc"), testMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("jwt_secret", "s3cr3t-jwt")
+
+	if got, err := Resolve(StorePrefix+"jwt_secret", store); err != nil || got != "s3cr3t-jwt" {
+		t.Errorf("Resolve = %q, %v", got, err)
+	}
+	if _, err := Resolve(StorePrefix+"missing", store); err == nil {
+		t.Error("Resolve of a missing secret succeeded")
+	}
+	if _, err := Resolve(StorePrefix+"jwt_secret", nil); err == nil {
+		t.Error("Resolve without a store succeeded")
+	}
+	if got, err := Resolve("plain-value", nil); err != nil || got != "plain-value" {
+		t.Errorf("Resolve(plain) = %q, %v", got, err)
+	}
+	if IsReference("plain-value") || !IsReference(FilePrefix+"/x") || !IsReference(StorePrefix+"x") {
+		t.Error("IsReference misclassifies values")
+	}
+}
@@ -0,0 +1,171 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Формат зашифрованного файла секретов. Содержимое (JSON-словарь имя -> значение)
+// шифруется AES-256-GCM ключом, выведенным из мастер-ключа через argon2id.
+const storeVersion = 1
+
+var storeAAD = []byte("loil-secrets-v1")
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+type storeFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Store - расшифрованное в памяти хранилище секретов
+type Store struct {
+	path      string
+	masterKey []byte
+	values    map[string]string
+}
+
+// OpenStore открывает зашифрованный файл секретов. Если файла нет, возвращается
+// пустое хранилище, которое будет создано при первом Save.
+func OpenStore(path string, masterKey []byte) (*Store, error) {
+	if len(masterKey) == 0 {
+		return nil, fmt.Errorf("secrets store %s: master key is empty", path)
+	}
+
+	s := &Store{
+		path:      path,
+		masterKey: masterKey,
+		values:    make(map[string]string),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secrets store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("parse secrets store %s: %w", path, err)
+	}
+	if file.Version != storeVersion {
+		return nil, fmt.Errorf("secrets store %s: unsupported version %d", path, file.Version)
+	}
+
+	aead, err := newAEAD(masterKey, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, storeAAD)
+	if err != nil {
+		return nil, fmt.Errorf("secrets store %s: wrong master key or corrupted file", path)
+	}
+
+	if err := json.Unmarshal(plaintext, &s.values); err != nil {
+		return nil, fmt.Errorf("decode secrets store %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Get возвращает значение секрета по имени
+func (s *Store) Get(name string) (string, error) {
+	value, ok := s.values[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// Set добавляет или заменяет секрет. Изменения сохраняются вызовом Save.
+func (s *Store) Set(name, value string) {
+	s.values[name] = value
+}
+
+// Delete удаляет секрет. Изменения сохраняются вызовом Save.
+func (s *Store) Delete(name string) {
+	delete(s.values, name)
+}
+
+// Names возвращает отсортированный список имен секретов
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save шифрует хранилище с новой солью и атомарно перезаписывает файл
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := newAEAD(s.masterKey, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(storeFile{
+		Version:    storeVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, storeAAD),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".secrets-*")
+	if err != nil {
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write secrets store: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newAEAD(masterKey, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey(masterKey, salt, 1, 64*1024, 4, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,163 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testMasterKey = []byte("test-master-key")
+
+// savedStore создает файл секретов с двумя значениями и возвращает путь к нему
+func savedStore(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	store, err := OpenStore(path, testMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("jwt_secret", "s3cr3t-jwt")
+	store.Set("smtp_password", "p@ss\nword")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := savedStore(t)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "s3cr3t-jwt") {
+		t.Fatal("secret stored in plain text")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	store, err := OpenStore(path, testMasterKey)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if names := store.Names(); !reflect.DeepEqual(names, []string{"jwt_secret", "smtp_password"}) {
+		t.Errorf("Names = %q", names)
+	}
+	if value, err := store.Get("smtp_password"); err != nil || value != "p@ss\nword" {
+		t.Errorf("Get = %q, %v", value, err)
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrSecretNotFound", err)
+	}
+
+	// Удаление переживает повторное сохранение, соль при этом новая
+	store.Delete("jwt_secret")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	resaved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saltOf(t, resaved) == saltOf(t, content) {
+		t.Error("Save reused the salt")
+	}
+	reopened, err := OpenStore(path, testMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := reopened.Names(); !reflect.DeepEqual(names, []string{"smtp_password"}) {
+		t.Errorf("Names after Delete = %q", names)
+	}
+}
+
+func saltOf(t *testing.T, content []byte) string {
+	t.Helper()
+	var file storeFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		t.Fatal(err)
+	}
+	return string(file.Salt)
+}
+
+func TestOpenStoreMissingFile(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "absent.enc"), testMasterKey)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	if len(store.Names()) != 0 {
+		t.Errorf("Names = %q, want empty", store.Names())
+	}
+}
+
+func TestOpenStoreRejects(t *testing.T) {
+	path := savedStore(t)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rewrite сохраняет копию файла с измененным содержимым
+	rewrite := func(t *testing.T, change func(*storeFile)) string {
+		t.Helper()
+		var file storeFile
+		if err := json.Unmarshal(content, &file); err != nil {
+			t.Fatal(err)
+		}
+		change(&file)
+		changed, err := json.Marshal(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := filepath.Join(t.TempDir(), "secrets.enc")
+		if err := os.WriteFile(tampered, changed, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return tampered
+	}
+
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		key  []byte
+	}{
+		{"wrong master key", func(*testing.T) string { return path }, []byte("other-master-key")},
+		{"empty master key", func(*testing.T) string { return path }, nil},
+		{"tampered ciphertext", func(t *testing.T) string {
+			return rewrite(t, func(f *storeFile) { f.Ciphertext[0] ^= 1 })
+		}, testMasterKey},
+		{"tampered salt", func(t *testing.T) string {
+			return rewrite(t, func(f *storeFile) { f.Salt[0] ^= 1 })
+		}, testMasterKey},
+		{"tampered nonce", func(t *testing.T) string {
+			return rewrite(t, func(f *storeFile) { f.Nonce[0] ^= 1 })
+		}, testMasterKey},
+		{"unknown version", func(t *testing.T) string {
+			return rewrite(t, func(f *storeFile) { f.Version = storeVersion + 1 })
+		}, testMasterKey},
+		{"not json", func(t *testing.T) string {
+			broken := filepath.Join(t.TempDir(), "secrets.enc")
+			if err := os.WriteFile(broken, content[:len(content)/2], 0o600); err != nil {
+				t.Fatal(err)
+			}
+			return broken
+		}, testMasterKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := OpenStore(tt.path(t), tt.key)
+			if err == nil {
+				t.Fatalf("OpenStore succeeded with %d secrets", len(store.Names()))
+			}
+			if strings.Contains(err.Error(), "s3cr3t-jwt") {
+				t.Errorf("error leaks the secret: %v", err)
+			}
+		})
+	}
+}
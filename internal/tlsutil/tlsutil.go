@@ -0,0 +1,196 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"LOIL-auth-server/pkg/logger"
+)
+
+// CertReloader отдает текущий сертификат сервера и перечитывает его,
+// когда файлы сертификата или ключа меняются на диске (например, после certbot renew)
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *logger.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	certMod  time.Time
+	keyMod   time.Time
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func NewCertReloader(certFile, keyFile string, logger *logger.Logger) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate подходит для tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch проверяет время изменения файлов с заданным интервалом до вызова Stop
+func (r *CertReloader) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				changed, err := r.changed()
+				if err != nil {
+					r.logger.Error("TLS: stat certificate failed:", err)
+					continue
+				}
+				if !changed {
+					continue
+				}
+				// Ошибка не заменяет рабочий сертификат: certbot мог записать только один из файлов
+				if err := r.reload(); err != nil {
+					r.logger.Error("TLS: certificate reload failed, keeping current certificate:", err)
+					continue
+				}
+				r.logger.Info("TLS: certificate reloaded from " + r.certFile)
+			}
+		}
+	}()
+}
+
+func (r *CertReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+func (r *CertReloader) changed() (bool, error) {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod), nil
+}
+
+func (r *CertReloader) reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// ServerConfig собирает tls.Config по настройкам: минимальная версия и набор шифров
+func ServerConfig(minVersionName string, cipherSuiteNames []string, reloader *CertReloader) (*tls.Config, error) {
+	minVersion, err := ParseVersion(minVersionName)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := ParseCipherSuites(cipherSuiteNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}, nil
+}
+
+// MutualConfig дополняет серверную конфигурацию обязательной проверкой клиентских
+// сертификатов, подписанных CA из clientCAFile
+func MutualConfig(base *tls.Config, clientCAFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client CA %s: no certificates found", clientCAFile)
+	}
+
+	mutual := base.Clone()
+	mutual.ClientAuth = tls.RequireAndVerifyClientCert
+	mutual.ClientCAs = pool
+	return mutual, nil
+}
+
+// ParseVersion разбирает минимальную версию TLS: "1.2" или "1.3"
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q (use 1.2 or 1.3)", version)
+}
+
+// ParseCipherSuites переводит имена шифров (как в crypto/tls) в идентификаторы.
+// Пустой список означает безопасный набор Go по умолчанию. Небезопасные шифры
+// не принимаются. Для TLS 1.3 набор шифров не настраивается.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,197 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"LOIL-auth-server/pkg/logger"
+)
+
+// writeCert создает самоподписанный сертификат с заданным серийным номером
+// и возвращает PEM сертификата и ключа
+func writeCert(t *testing.T, serial int64) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "auth.test"},
+		DNSNames:     []string{"auth.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// replaceFile перезаписывает файл и сдвигает время изменения: на быстрой
+// файловой системе оно могло бы совпасть с прежним
+func replaceFile(t *testing.T, path string, content []byte, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func servedSerial(t *testing.T, r *CertReloader) int64 {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber.Int64()
+}
+
+func waitForSerial(t *testing.T, r *CertReloader, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for servedSerial(t, r) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("served serial %d, want %d", servedSerial(t, r), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	mod := time.Now().Add(-time.Hour)
+
+	cert1, key1 := writeCert(t, 1)
+	replaceFile(t, certFile, cert1, mod)
+	replaceFile(t, keyFile, key1, mod)
+
+	r, err := NewCertReloader(certFile, keyFile, logger.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	if serial := servedSerial(t, r); serial != 1 {
+		t.Fatalf("served serial %d, want 1", serial)
+	}
+	r.Watch(5 * time.Millisecond)
+
+	// Новая пара подхватывается без перезапуска
+	cert2, key2 := writeCert(t, 2)
+	mod = mod.Add(time.Minute)
+	replaceFile(t, certFile, cert2, mod)
+	replaceFile(t, keyFile, key2, mod)
+	waitForSerial(t, r, 2)
+
+	// Сертификат без подходящего ключа (certbot записал только один файл):
+	// остается прежняя пара
+	cert3, _ := writeCert(t, 3)
+	mod = mod.Add(time.Minute)
+	replaceFile(t, certFile, cert3, mod)
+	time.Sleep(50 * time.Millisecond)
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Fatalf("served serial %d after an invalid pair, want 2", serial)
+	}
+
+	// Битый файл тоже не заменяет рабочий сертификат
+	replaceFile(t, certFile, []byte("not a certificate"), mod.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if serial := servedSerial(t, r); serial != 2 {
+		t.Fatalf("served serial %d after a corrupt file, want 2", serial)
+	}
+
+	// Когда пара снова согласована, она подхватывается
+	cert4, key4 := writeCert(t, 4)
+	mod = mod.Add(2 * time.Minute)
+	replaceFile(t, certFile, cert4, mod)
+	replaceFile(t, keyFile, key4, mod)
+	waitForSerial(t, r, 4)
+}
+
+func TestNewCertReloaderRejectsInvalidPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	cert1, _ := writeCert(t, 1)
+	_, key2 := writeCert(t, 2)
+	replaceFile(t, certFile, cert1, time.Now())
+	replaceFile(t, keyFile, key2, time.Now())
+
+	if _, err := NewCertReloader(certFile, keyFile, logger.NewLogger()); err == nil {
+		t.Error("NewCertReloader accepted a certificate with a foreign key")
+	}
+	if _, err := NewCertReloader(filepath.Join(dir, "missing.pem"), keyFile, logger.NewLogger()); err == nil {
+		t.Error("NewCertReloader accepted a missing certificate")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    uint16
+		wantErr bool
+	}{
+		{"", tls.VersionTLS12, false},
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.1", 0, true},
+		{"1.0", 0, true},
+		{"TLS1.3", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %x, %v, want %x, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	if ids, err := ParseCipherSuites(nil); err != nil || ids != nil {
+		t.Errorf("ParseCipherSuites(nil) = %v, %v, want Go defaults (nil)", ids, err)
+	}
+
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || ids[1] != tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 {
+		t.Errorf("ParseCipherSuites = %x", ids)
+	}
+
+	for _, name := range []string{
+		"TLS_FAKE_WITH_NOTHING",
+		"tls_ecdhe_ecdsa_with_aes_128_gcm_sha256",
+		// Небезопасные шифры crypto/tls знает, но не принимаются
+		"TLS_RSA_WITH_RC4_128_SHA",
+		"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	} {
+		if _, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", name}); err == nil {
+			t.Errorf("ParseCipherSuites accepted %q", name)
+		}
+	}
+}
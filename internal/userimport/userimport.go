@@ -0,0 +1,253 @@
+// Package userimport переносит пользователей из старого форума и прежнего игрового
+// сервера. Хеши паролей сохраняются как есть, с пометкой алгоритма, и пересчитываются
+// текущим алгоритмом при первом успешном входе.
+package userimport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/hashing"
+	"LOIL-auth-server/internal/i18n"
+	"LOIL-auth-server/internal/models"
+	"LOIL-auth-server/internal/utils"
+	"LOIL-auth-server/internal/validation"
+)
+
+// Record - пользователь из файла импорта. В CSV те же имена в строке заголовка.
+type Record struct {
+	// ID - прежний ID; 0 или пусто - назначит хранилище
+	ID            int    `json:"id"`
+	Login         string `json:"login"`
+	GameSurname   string `json:"game_surname"`
+	Email         string `json:"email"`
+	PasswordHash  string `json:"password_hash"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	// Salt - отдельная соль для md5, md5_double и sha1
+	Salt   string `json:"salt,omitempty"`
+	Locale string `json:"locale,omitempty"`
+
+	// line - номер строки CSV или индекс в JSON для отчета
+	line int
+}
+
+// Options управляет импортом
+type Options struct {
+	// MapIDs - не сохранять прежние ID, а назначать новые и вернуть соответствие
+	MapIDs bool
+	// DryRun - только проверить записи, ничего не сохраняя
+	DryRun bool
+	// GameSurnames - допустимые игровые фамилии, как при регистрации
+	GameSurnames gamesurname.Policy
+}
+
+// RecordError - причина, по которой запись не импортирована
+type RecordError struct {
+	Line    int    `json:"line"`
+	ID      int    `json:"id,omitempty"`
+	Login   string `json:"login"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Report - итог импорта
+type Report struct {
+	Total    int `json:"total"`
+	Imported int `json:"imported"`
+	// IDMap - прежний ID -> новый, если включен MapIDs
+	IDMap  map[int]int   `json:"id_map,omitempty"`
+	Errors []RecordError `json:"errors,omitempty"`
+}
+
+// Коды ошибок записей, кроме кодов validation
+const (
+	CodeInvalidHash  = "invalid_hash"
+	CodeLoginTaken   = "login_taken"
+	CodeSurnameTaken = "game_surname_taken"
+	CodeEmailTaken   = "email_taken"
+	CodeIDTaken      = "id_taken"
+	CodeStoreFailed  = "store_failed"
+)
+
+var csvColumns = []string{"id", "login", "game_surname", "email", "password_hash", "hash_algorithm", "salt", "locale"}
+
+// ParseCSV читает записи из CSV со строкой заголовка. Обязательны login,
+// game_surname, email, password_hash и hash_algorithm; порядок столбцов любой.
+func ParseCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"login", "game_surname", "email", "password_hash", "hash_algorithm"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("csv header: column %q is required (known columns: %s)", required, strings.Join(csvColumns, ", "))
+		}
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := index[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv: %w", err)
+		}
+
+		record := Record{
+			Login:         field(row, "login"),
+			GameSurname:   field(row, "game_surname"),
+			Email:         field(row, "email"),
+			PasswordHash:  field(row, "password_hash"),
+			HashAlgorithm: field(row, "hash_algorithm"),
+			Salt:          field(row, "salt"),
+			Locale:        field(row, "locale"),
+			line:          line,
+		}
+		if id := field(row, "id"); id != "" {
+			if record.ID, err = strconv.Atoi(id); err != nil || record.ID < 0 {
+				return nil, fmt.Errorf("csv line %d: invalid id %q", line, id)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ParseJSON читает JSON-массив записей
+func ParseJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&records); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+	for i := range records {
+		records[i].line = i + 1
+	}
+	return records, nil
+}
+
+// Import проверяет и сохраняет записи по одной. Ошибочные записи попадают в отчет
+// и не мешают остальным; ошибка возвращается, только если прервался сам импорт.
+func Import(ctx context.Context, store database.UserImporter, records []Record, opts Options) (Report, error) {
+	report := Report{Total: len(records)}
+	if opts.MapIDs && !opts.DryRun {
+		report.IDMap = make(map[int]int)
+	}
+
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		user, err := record.toUser(opts)
+		if err != nil {
+			report.Errors = append(report.Errors, record.fail(err))
+			continue
+		}
+		if opts.DryRun {
+			report.Imported++
+			continue
+		}
+
+		if err := store.ImportUser(ctx, user); err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			report.Errors = append(report.Errors, record.fail(err))
+			continue
+		}
+
+		report.Imported++
+		if opts.MapIDs && record.ID != 0 {
+			report.IDMap[record.ID] = user.ID
+		}
+	}
+
+	return report, nil
+}
+
+// toUser проверяет запись теми же правилами, что и регистрацию
+func (r Record) toUser(opts Options) (*models.User, error) {
+	var v validation.Validator
+	v.Check("login", r.Login, validation.Login)
+	v.Add(opts.GameSurnames.Check("gameSurname", r.GameSurname))
+	v.Check("email", r.Email, validation.Email)
+	if r.Locale != "" {
+		v.OneOf("locale", r.Locale, i18n.Locales())
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashing.TagLegacy(strings.ToLower(r.HashAlgorithm), r.PasswordHash, r.Salt)
+	if err != nil {
+		return nil, &hashError{err}
+	}
+
+	user := &models.User{
+		ID:          r.ID,
+		Login:       r.Login,
+		GameSurname: utils.NormalizeGameSurname(r.GameSurname),
+		Email:       r.Email,
+		Password:    hash,
+		Locale:      r.Locale,
+	}
+	if opts.MapIDs {
+		user.ID = 0
+	}
+	return user, nil
+}
+
+type hashError struct{ err error }
+
+func (e *hashError) Error() string { return e.err.Error() }
+
+func (r Record) fail(err error) RecordError {
+	recordErr := RecordError{Line: r.line, ID: r.ID, Login: r.Login, Message: err.Error()}
+
+	var fieldErrs validation.Errors
+	var hashErr *hashError
+	switch {
+	case errors.As(err, &fieldErrs):
+		recordErr.Code = fieldErrs[0].Code
+		recordErr.Message = fieldErrs.Error()
+	case errors.As(err, &hashErr):
+		recordErr.Code = CodeInvalidHash
+	case errors.Is(err, database.ErrLoginTaken):
+		recordErr.Code = CodeLoginTaken
+	case errors.Is(err, database.ErrGameSurnameTaken):
+		recordErr.Code = CodeSurnameTaken
+	case errors.Is(err, database.ErrEmailTaken):
+		recordErr.Code = CodeEmailTaken
+	case errors.Is(err, database.ErrUserIDTaken):
+		recordErr.Code = CodeIDTaken
+	default:
+		recordErr.Code = CodeStoreFailed
+	}
+	return recordErr
+}
@@ -0,0 +1,37 @@
+package userimport
+
+import (
+	"context"
+	"testing"
+
+	"LOIL-auth-server/internal/database"
+	"LOIL-auth-server/internal/gamesurname"
+)
+
+func TestImportRejectsDangerousHashParameters(t *testing.T) {
+	const tail = "$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	records := []Record{
+		{Login: "plr1", GameSurname: "Ivanov", Email: "plr1@example.com", HashAlgorithm: "argon2id", PasswordHash: "$argon2id$v=19$m=16,t=1,p=0" + tail},
+		{Login: "plr2", GameSurname: "Petrov", Email: "plr2@example.com", HashAlgorithm: "argon2id", PasswordHash: "$argon2id$v=19$m=4294967295,t=1,p=1" + tail},
+		{Login: "plr3", GameSurname: "Sidorov", Email: "plr3@example.com", HashAlgorithm: "argon2id", PasswordHash: "$argon2id$v=19$m=65536,t=3,p=2" + tail},
+		// 2^30 раундов phpass
+		{Login: "plr4", GameSurname: "Smirnov", Email: "plr4@example.com", HashAlgorithm: "phpass", PasswordHash: "$P$SIQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"},
+	}
+
+	store := database.NewMemoryStore()
+	report, err := Import(context.Background(), store, records, Options{GameSurnames: gamesurname.Policy{Alphabets: []string{"latin"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 1 || len(report.Errors) != 3 {
+		t.Fatalf("report = %+v, want 1 imported and 3 errors", report)
+	}
+	for _, recordErr := range report.Errors {
+		if recordErr.Code != CodeInvalidHash {
+			t.Errorf("%s: code = %q, want %q", recordErr.Login, recordErr.Code, CodeInvalidHash)
+		}
+	}
+	if exists, _ := store.UserExists(context.Background(), "plr1"); exists {
+		t.Error("record with p=0 was imported")
+	}
+}
@@ -1,49 +1,70 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
-	
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Claims struct {
-	UserID     int    `json:"userId"`
-	Login      string `json:"login"`
+	UserID      int    `json:"userId"`
+	Login       string `json:"login"`
 	GameSurname string `json:"gameSurname"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID int, login, gameSurname, secret string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	
+func GenerateJWT(userID int, login, gameSurname, secret string, ttl time.Duration) (string, error) {
+	expirationTime := time.Now().Add(ttl)
+
 	claims := &Claims{
-		UserID:     userID,
-		Login:      login,
+		UserID:      userID,
+		Login:       login,
 		GameSurname: gameSurname,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = KeyID(secret)
 	return token.SignedString([]byte(secret))
 }
 
-func ValidateJWT(tokenString, secret string) (*Claims, error) {
-	claims := &Claims{}
-	
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
-	
-	if err != nil {
-		return nil, err
-	}
-	
-	if !token.Valid {
-		return nil, jwt.ErrSignatureInvalid
+// ValidateJWT проверяет токен любым из секретов. Во время ротации передаются
+// текущий и предыдущие секреты; нужный выбирается по заголовку kid.
+func ValidateJWT(tokenString string, secrets []string) (*Claims, error) {
+	var lastErr error = jwt.ErrSignatureInvalid
+
+	for _, secret := range secrets {
+		claims := &Claims{}
+
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			// Токены без kid выпущены до ротации - проверяем их всеми секретами
+			if kid, ok := token.Header["kid"].(string); ok && kid != KeyID(secret) {
+				return nil, errKeyMismatch
+			}
+			return []byte(secret), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+		if err == nil && token.Valid {
+			return claims, nil
+		}
+		if err != nil && !errors.Is(err, errKeyMismatch) {
+			lastErr = err
+		}
 	}
-	
-	return claims, nil
-}
\ No newline at end of file
+
+	return nil, lastErr
+}
+
+var errKeyMismatch = errors.New("token signed with a different key")
+
+// KeyID возвращает несекретный идентификатор ключа для заголовка kid
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
@@ -1,34 +1,40 @@
 package utils
 
 import (
-	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"LOIL-auth-server/internal/gamesurname"
+	"LOIL-auth-server/internal/validation"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // ValidateLogin проверяет формат логина: буквы, цифры, подчеркивание
 func ValidateLogin(login string) bool {
-	matched, _ := regexp.MatchString(`^[a-zA-Z0-9_]{3,20}$`, login)
-	return matched
+	return validation.Login.Check("login", login) == nil
 }
 
-// ValidateGameSurname проверяет игровую фамилию: только латинские буквы
-func ValidateGameSurname(surname string) bool {
-	matched, _ := regexp.MatchString(`^[a-zA-Z]{2,20}$`, surname)
-	return matched
+// ValidateGameSurname проверяет игровую фамилию: буквы одного из алфавитов политики
+func ValidateGameSurname(surname string, policy gamesurname.Policy) bool {
+	return policy.Check("gameSurname", surname) == nil
 }
 
-// NormalizeGameSurname нормализует фамилию: Первая заглавная, остальные строчные
+// NormalizeGameSurname нормализует фамилию: Первая заглавная, остальные строчные.
+// Составные символы собираются (NFC), чтобы "й" из двух кодовых точек
+// совпадала с обычной.
 func NormalizeGameSurname(surname string) string {
-	if len(surname) == 0 {
+	surname = norm.NFC.String(surname)
+	first, size := utf8.DecodeRuneInString(surname)
+	if size == 0 {
 		return surname
 	}
 
-	return strings.ToUpper(surname[:1]) + strings.ToLower(surname[1:])
+	return string(unicode.ToUpper(first)) + strings.ToLower(surname[size:])
 }
 
 // ValidateEmail проверяет формат email
 func ValidateEmail(email string) bool {
-	emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	matched, _ := regexp.MatchString(emailRegex, email)
-	return matched
+	return validation.Email.Check("email", email) == nil
 }
@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// Rule описывает ограничения одного поля. Те же правила отдаются клиентам через
+// GET /api/auth/validation-rules, поэтому Pattern должен быть совместим с JavaScript RegExp.
+type Rule struct {
+	Required  bool `json:"required"`
+	MinLength int  `json:"minLength,omitempty"`
+	MaxLength int  `json:"maxLength,omitempty"`
+	// MaxBytes ограничивает длину в байтах UTF-8
+	MaxBytes int    `json:"maxBytes,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// NewRule готовит правило к проверке: компилирует Pattern
+func NewRule(rule Rule) Rule {
+	if rule.Pattern != "" {
+		rule.re = regexp.MustCompile(rule.Pattern)
+	}
+	return rule
+}
+
+// Коды ошибок полей
+const (
+	CodeRequired      = "required"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeInvalidFormat = "invalid_format"
+	CodeMismatch      = "mismatch"
+	CodeUnsupported   = "unsupported"
+	CodeIncorrect     = "incorrect"
+
+	// Коды политики паролей
+	CodeTooSimple        = "too_simple"
+	CodeRepeated         = "repeated"
+	CodeContainsUserInfo = "contains_user_info"
+	CodeTooWeak          = "too_weak"
+	CodeBreached         = "breached"
+
+	// Код игровых фамилий: в одной фамилии буквы разных алфавитов
+	CodeMixedAlphabets = "mixed_alphabets"
+	// Код фильтра имен: служебное имя, брань или правило администратора
+	CodeNameNotAllowed = "name_not_allowed"
+)
+
+// Правила полей регистрации и профиля
+var (
+	Login = NewRule(Rule{
+		Required:  true,
+		MinLength: 3,
+		MaxLength: 20,
+		Pattern:   `^[a-zA-Z0-9_]+$`,
+	})
+	Email = NewRule(Rule{
+		Required:  true,
+		MaxLength: 254,
+		Pattern:   `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`,
+	})
+)
+
+// Rules возвращает правила по именам полей в JSON-запросах. Правила пароля и
+// игровой фамилии задаются конфигурацией (пакеты passwordpolicy и gamesurname).
+func Rules() map[string]Rule {
+	return map[string]Rule{
+		"login": Login,
+		"email": Email,
+	}
+}
+
+// Check проверяет значение и возвращает первую нарушенную проверку поля или nil
+func (r Rule) Check(field, value string) *FieldError {
+	if value == "" {
+		if r.Required {
+			return NewFieldError(field, CodeRequired, nil)
+		}
+		return nil
+	}
+
+	length := utf8.RuneCountInString(value)
+	if r.MinLength > 0 && length < r.MinLength {
+		return NewFieldError(field, CodeTooShort, map[string]interface{}{"min": r.MinLength})
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		return NewFieldError(field, CodeTooLong, map[string]interface{}{"max": r.MaxLength})
+	}
+	if r.MaxBytes > 0 && len(value) > r.MaxBytes {
+		return NewFieldError(field, CodeTooLong, map[string]interface{}{"maxBytes": r.MaxBytes})
+	}
+	if r.re != nil && !r.re.MatchString(value) {
+		return NewFieldError(field, CodeInvalidFormat, nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"strings"
+
+	"LOIL-auth-server/internal/i18n"
+)
+
+// FieldError - ошибка одного поля формы
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// Errors - все ошибки формы сразу, чтобы клиент подсветил каждое поле
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Validator накапливает ошибки полей
+type Validator struct {
+	errors Errors
+}
+
+// Check проверяет поле по правилу
+func (v *Validator) Check(field, value string, rule Rule) {
+	if fieldErr := rule.Check(field, value); fieldErr != nil {
+		v.errors = append(v.errors, *fieldErr)
+	}
+}
+
+// Add добавляет ошибку, найденную отдельной проверкой; nil игнорируется
+func (v *Validator) Add(fieldErr *FieldError) {
+	if fieldErr != nil {
+		v.errors = append(v.errors, *fieldErr)
+	}
+}
+
+// Match проверяет, что подтверждение совпадает с исходным значением
+func (v *Validator) Match(field, value, original string) {
+	if value != original {
+		v.errors = append(v.errors, *NewFieldError(field, CodeMismatch, nil))
+	}
+}
+
+// OneOf проверяет, что значение входит в список допустимых
+func (v *Validator) OneOf(field, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.errors = append(v.errors, *NewFieldError(field, CodeUnsupported, map[string]interface{}{"allowed": allowed}))
+}
+
+// Err возвращает Errors, если найдена хотя бы одна ошибка, иначе nil
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// NewFieldError создает ошибку поля с сообщением на резервном языке
+func NewFieldError(field, code string, params map[string]interface{}) *FieldError {
+	fieldErr := &FieldError{
+		Field:  field,
+		Code:   code,
+		Params: params,
+	}
+	fieldErr.Message = fieldErr.Localized(i18n.Fallback)
+	return fieldErr
+}
+
+// Localized возвращает сообщение об ошибке на указанном языке
+func (e FieldError) Localized(locale string) string {
+	params := i18n.Params{"field": i18n.T(locale, "field."+e.Field, nil)}
+
+	key := "validation." + e.Code
+	switch e.Code {
+	case CodeTooShort:
+		params["count"] = e.Params["min"]
+	case CodeTooLong:
+		if maxBytes, ok := e.Params["maxBytes"]; ok {
+			key = "validation.too_long_bytes"
+			params["count"] = maxBytes
+		} else {
+			params["count"] = e.Params["max"]
+		}
+	case CodeInvalidFormat, CodeIncorrect:
+		// Для некоторых полей есть более понятное сообщение
+		if i18n.Has(locale, key+"."+e.Field) {
+			key += "." + e.Field
+		}
+	case CodeTooSimple:
+		params["count"] = e.Params["min"]
+	case CodeRepeated:
+		params["count"] = e.Params["max"]
+	case CodeTooWeak:
+		// Главная причина слабости пароля - из оценки стойкости
+		if warning, _ := e.Params["warning"].(string); warning != "" {
+			key = "validation.too_weak.warning"
+			params["warning"] = i18n.T(locale, "password.warning."+warning, nil)
+		}
+	case CodeNameNotAllowed:
+		if reason, _ := e.Params["reason"].(string); reason != "" && i18n.Has(locale, key+"."+reason) {
+			key += "." + reason
+		}
+	}
+
+	return i18n.T(locale, key, params)
+}
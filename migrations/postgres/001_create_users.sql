@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    login TEXT NOT NULL,
+    game_surname TEXT NOT NULL,
+    email TEXT NOT NULL,
+    password TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT users_login_key UNIQUE (login),
+    CONSTRAINT users_game_surname_key UNIQUE (game_surname),
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+-- +migrate Down
+DROP TABLE users;
@@ -0,0 +1,8 @@
+-- +migrate Up
+-- Email хранится зашифрованным; поиск и уникальность - по слепому индексу
+ALTER TABLE users ADD COLUMN email_index TEXT;
+ALTER TABLE users ADD CONSTRAINT users_email_index_key UNIQUE (email_index);
+
+-- +migrate Down
+ALTER TABLE users DROP CONSTRAINT users_email_index_key;
+ALTER TABLE users DROP COLUMN email_index;
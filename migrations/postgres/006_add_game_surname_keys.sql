@@ -0,0 +1,12 @@
+-- +migrate Up
+-- Каноническая фамилия (без регистра, похожие буквы кириллицы заменены
+-- латинскими) для поиска и уникальности и фамилия латиницей для игровых
+-- клиентов. Их вычисляет сервер при старте, как канонические логины.
+ALTER TABLE users ADD COLUMN game_surname_canonical TEXT;
+ALTER TABLE users ADD COLUMN game_surname_latin TEXT;
+ALTER TABLE users ADD CONSTRAINT users_game_surname_canonical_key UNIQUE (game_surname_canonical);
+
+-- +migrate Down
+ALTER TABLE users DROP CONSTRAINT users_game_surname_canonical_key;
+ALTER TABLE users DROP COLUMN game_surname_latin;
+ALTER TABLE users DROP COLUMN game_surname_canonical;
@@ -0,0 +1,13 @@
+-- +migrate Up
+-- Запрещенные и разрешенные администраторами шаблоны логинов и фамилий
+CREATE TABLE name_rules (
+    id SERIAL PRIMARY KEY,
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('block', 'allow')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT name_rules_pattern_action_key UNIQUE (pattern, action)
+);
+
+-- +migrate Down
+DROP TABLE name_rules;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- Email хранится зашифрованным; поиск и уникальность - по слепому индексу
+ALTER TABLE users ADD COLUMN email_index TEXT;
+CREATE UNIQUE INDEX idx_email_index ON users(email_index);
+DROP INDEX idx_email;
+
+-- +migrate Down
+CREATE INDEX idx_email ON users(email);
+DROP INDEX idx_email_index;
+ALTER TABLE users DROP COLUMN email_index;
@@ -0,0 +1,16 @@
+-- +migrate Up
+-- Канонические логин и email для поиска и уникальности без учета регистра.
+-- Их вычисляет сервер при старте (SQL не умеет NFKC), а строки, совпавшие
+-- с другими, остаются с NULL и выводятся в лог, пока их не разберут вручную.
+ALTER TABLE users ADD COLUMN login_canonical TEXT;
+ALTER TABLE users ADD COLUMN email_canonical TEXT;
+CREATE UNIQUE INDEX idx_login_canonical ON users(login_canonical);
+CREATE UNIQUE INDEX idx_email_canonical ON users(email_canonical);
+-- Слепой индекс теперь строится по каноническому email и тоже пересчитывается
+UPDATE users SET email_index = NULL;
+
+-- +migrate Down
+DROP INDEX idx_email_canonical;
+DROP INDEX idx_login_canonical;
+ALTER TABLE users DROP COLUMN email_canonical;
+ALTER TABLE users DROP COLUMN login_canonical;
@@ -0,0 +1,12 @@
+-- +migrate Up
+-- Каноническая фамилия (без регистра, похожие буквы кириллицы заменены
+-- латинскими) для поиска и уникальности и фамилия латиницей для игровых
+-- клиентов. Их вычисляет сервер при старте, как канонические логины.
+ALTER TABLE users ADD COLUMN game_surname_canonical TEXT;
+ALTER TABLE users ADD COLUMN game_surname_latin TEXT;
+CREATE UNIQUE INDEX idx_game_surname_canonical ON users(game_surname_canonical);
+
+-- +migrate Down
+DROP INDEX idx_game_surname_canonical;
+ALTER TABLE users DROP COLUMN game_surname_latin;
+ALTER TABLE users DROP COLUMN game_surname_canonical;
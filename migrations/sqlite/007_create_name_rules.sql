@@ -0,0 +1,13 @@
+-- +migrate Up
+-- Запрещенные и разрешенные администраторами шаблоны логинов и фамилий
+CREATE TABLE name_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('block', 'allow')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pattern, action)
+);
+
+-- +migrate Down
+DROP TABLE name_rules;
@@ -1,22 +1,75 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
 )
 
 type Logger struct {
 	*log.Logger
+	level atomic.Int32
 }
 
 func NewLogger() *Logger {
-	return &Logger{
+	l := &Logger{
 		Logger: log.New(os.Stdout, "[AUTH] ", log.Ldate|log.Ltime|log.Lshortfile),
 	}
+	l.level.Store(int32(LevelInfo))
+	return l
+}
+
+// ParseLevel разбирает уровень логирования: debug, info, warn, error
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// SetLevel меняет уровень логирования, безопасно вызывать из разных горутин
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+func (l *Logger) enabled(level Level) bool {
+	return Level(l.level.Load()) <= level
+}
+
+func (l *Logger) Debug(v ...interface{}) {
+	if l.enabled(LevelDebug) {
+		l.Println("[DEBUG]", v)
+	}
 }
 
 func (l *Logger) Info(v ...interface{}) {
-	l.Println("[INFO]", v)
+	if l.enabled(LevelInfo) {
+		l.Println("[INFO]", v)
+	}
+}
+
+func (l *Logger) Warn(v ...interface{}) {
+	if l.enabled(LevelWarn) {
+		l.Println("[WARN]", v)
+	}
 }
 
 func (l *Logger) Error(v ...interface{}) {
@@ -26,4 +79,4 @@ func (l *Logger) Error(v ...interface{}) {
 func (l *Logger) Fatal(v ...interface{}) {
 	l.Println("[FATAL]", v)
 	os.Exit(1)
-}
\ No newline at end of file
+}